@@ -3,12 +3,14 @@ package main
 import (
 	"net/http"
 	"os"
+	_ "time/tzdata" // Workout and user timezones must load on hosts without a zoneinfo database
 
-	"repup/internal/auth" // Add this import
 	"repup/internal/data"
 	"repup/internal/handlers"
 	"repup/internal/logger"
 	customMiddleware "repup/internal/middleware"
+	"repup/internal/openapi"
+	"repup/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -47,46 +49,54 @@ func main() {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 
+	// Initialize the blob store for exercise media
+	publicURL := os.Getenv("PUBLIC_URL")
+	storageConfig := storage.Config{
+		Driver:            os.Getenv("STORAGE_DRIVER"),
+		LocalDir:          os.Getenv("STORAGE_LOCAL_DIR"),
+		BaseURL:           publicURL + "/files",
+		Secret:            os.Getenv("STORAGE_SIGNING_SECRET"),
+		S3Endpoint:        os.Getenv("S3_ENDPOINT"),
+		S3Region:          os.Getenv("S3_REGION"),
+		S3Bucket:          os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	if storageConfig.LocalDir == "" {
+		storageConfig.LocalDir = "./uploads"
+	}
+
+	// Media endpoints answer 503 without a store rather than stopping the server
+	store, err := storage.New(storageConfig)
+	if err != nil {
+		logger.Error().Err(err).Msg("Media storage is not configured")
+	}
+
 	// Initialize handlers with database connection
 	db := data.GetDB()
-	mainHandlers := handlers.NewHandlers(db)
+	mainHandlers := handlers.NewHandlers(db, store)
+
+	// A local store serves the links it signs; the signature is the
+	// authorization, so this sits outside the protected routes
+	if fileServer, ok := store.(http.Handler); ok {
+		r.Handle("/files/*", http.StripPrefix("/files/", fileServer))
+	}
+
+	// Requests are checked against the OpenAPI document when enabled, and
+	// responses too under test, where buffering them costs nothing
+	var apiMiddlewares []func(http.Handler) http.Handler
+	if os.Getenv("OPENAPI_VALIDATE") == "true" {
+		spec, err := openapi.Load()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("OpenAPI document could not be loaded")
+		}
+		apiMiddlewares = append(apiMiddlewares, spec.Middleware(openapi.Options{
+			ValidateResponses: os.Getenv("ENV") == "test",
+		}))
+	}
 
 	// Routes
-	r.Route("/api", func(r chi.Router) {
-		// Public routes
-		//r.Post("/auth/google", handlers.GoogleAuth)
-		//r.Get("/auth/google/callback", handlers.GoogleCallback)
-
-		// Protected routes
-		r.Group(func(r chi.Router) {
-			r.Use(auth.RequireAuth)
-
-			// Body Parts
-			r.Route("/body-parts", func(r chi.Router) {
-				r.Get("/", mainHandlers.ListBodyParts)
-				r.Post("/", mainHandlers.CreateBodyPart)
-				r.Get("/{id}", mainHandlers.GetBodyPart)
-				r.Put("/{id}", mainHandlers.UpdateBodyPart)
-				r.Delete("/{id}", mainHandlers.DeleteBodyPart)
-			})
-
-			r.Route("/exercises", func(r chi.Router) {
-				r.Get("/", mainHandlers.ListExercises)
-				r.Post("/", mainHandlers.CreateExercise)
-				r.Get("/{id}", mainHandlers.GetExercise)
-				r.Put("/{id}", mainHandlers.UpdateExercise)
-				r.Delete("/{id}", mainHandlers.DeleteExercise)
-			})
-
-			r.Route("/workouts", func(r chi.Router) {
-				r.Get("/", mainHandlers.ListWorkouts)
-				r.Post("/", mainHandlers.CreateWorkout)
-				r.Get("/{id}", mainHandlers.GetWorkout)
-				r.Put("/{id}", mainHandlers.UpdateWorkout)
-				r.Delete("/{id}", mainHandlers.DeleteWorkout)
-			})
-		})
-	})
+	mainHandlers.RegisterRoutes(r, apiMiddlewares...)
 
 	// Debug routes - only in development
 	if os.Getenv("ENV") != "production" {
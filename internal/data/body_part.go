@@ -2,17 +2,30 @@ package data
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 )
 
 // BodyPart represents a body part record from the database
 type BodyPart struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	ParentID  *int64      `json:"parent_id"` // Nil for top-level body parts
+	Children  []*BodyPart `json:"children,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
 
+// bodyPartSubtree selects the IDs of a body part and all its descendants, for
+// use in an IN clause. It takes the body part's ID as its placeholder.
+const bodyPartSubtree = `
+	WITH RECURSIVE subtree(id) AS (
+		SELECT ?
+		UNION
+		SELECT bp.id FROM body_parts bp JOIN subtree s ON bp.parent_id = s.id
+	)
+	SELECT id FROM subtree`
+
 // BodyPartModel wraps the database connection pool
 type BodyPartModel struct {
 	DB *sql.DB
@@ -27,12 +40,13 @@ func (m BodyPartModel) GetByID(id int64) (*BodyPart, error) {
 	bodyPart := &BodyPart{}
 
 	err := m.DB.QueryRow(`
-		SELECT id, name, created_at, updated_at
+		SELECT id, name, parent_id, created_at, updated_at
 		FROM body_parts
 		WHERE id = ?`, id,
 	).Scan(
 		&bodyPart.ID,
 		&bodyPart.Name,
+		&bodyPart.ParentID,
 		&bodyPart.CreatedAt,
 		&bodyPart.UpdatedAt,
 	)
@@ -50,7 +64,7 @@ func (m BodyPartModel) GetByID(id int64) (*BodyPart, error) {
 // GetAll retrieves all body parts from the database
 func (m BodyPartModel) GetAll() ([]*BodyPart, error) {
 	rows, err := m.DB.Query(`
-		SELECT id, name, created_at, updated_at
+		SELECT id, name, parent_id, created_at, updated_at
 		FROM body_parts
 		ORDER BY name`)
 	if err != nil {
@@ -65,6 +79,7 @@ func (m BodyPartModel) GetAll() ([]*BodyPart, error) {
 		err := rows.Scan(
 			&bodyPart.ID,
 			&bodyPart.Name,
+			&bodyPart.ParentID,
 			&bodyPart.CreatedAt,
 			&bodyPart.UpdatedAt,
 		)
@@ -81,6 +96,156 @@ func (m BodyPartModel) GetAll() ([]*BodyPart, error) {
 	return bodyParts, nil
 }
 
+// BodyPartFilter narrows the body parts listed. Every set condition must hold.
+type BodyPartFilter struct {
+	ParentID int64  // Direct children of the body part
+	TopLevel bool   // Body parts without a parent
+	Search   string // Case-insensitive substring of the name
+}
+
+// bodyPartList sorts body parts by name (the default) or by ID
+var bodyPartList = listSpec[*BodyPart]{
+	sorts: map[string]sortKey[*BodyPart]{
+		"name": {"name", func(b *BodyPart) interface{} { return b.Name }},
+		"id":   {"id", func(b *BodyPart) interface{} { return b.ID }},
+	},
+	defaultSort: "name",
+	idColumn:    "id",
+	id:          func(b *BodyPart) int64 { return b.ID },
+}
+
+// List retrieves one page of the body parts matching filter
+func (m BodyPartModel) List(filter BodyPartFilter, list ListQuery) ([]*BodyPart, *Page, error) {
+	clauses, err := bodyPartList.clauses(list)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if filter.ParentID > 0 {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, filter.ParentID)
+	}
+	if filter.TopLevel {
+		conditions = append(conditions, "parent_id IS NULL")
+	}
+	if filter.Search != "" {
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+	}
+	if clauses.where != "" {
+		conditions = append(conditions, clauses.where)
+		args = append(args, clauses.args...)
+	}
+
+	query := `
+		SELECT id, name, parent_id, created_at, updated_at
+		FROM body_parts`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, clauses.limitArgs()...)
+
+	rows, err := m.DB.Query(query+clauses.orderBy, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var bodyParts []*BodyPart
+
+	for rows.Next() {
+		bodyPart := &BodyPart{}
+		err := rows.Scan(
+			&bodyPart.ID,
+			&bodyPart.Name,
+			&bodyPart.ParentID,
+			&bodyPart.CreatedAt,
+			&bodyPart.UpdatedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		bodyParts = append(bodyParts, bodyPart)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	bodyParts, page := bodyPartList.page(clauses, bodyParts)
+	return bodyParts, page, nil
+}
+
+// Tree retrieves all body parts nested under their parents, each level
+// ordered by name
+func (m BodyPartModel) Tree() ([]*BodyPart, error) {
+	bodyParts, err := m.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int64]*BodyPart{}
+	for _, bodyPart := range bodyParts {
+		byID[bodyPart.ID] = bodyPart
+	}
+
+	roots := []*BodyPart{}
+	for _, bodyPart := range bodyParts {
+		if bodyPart.ParentID != nil {
+			if parent, ok := byID[*bodyPart.ParentID]; ok {
+				parent.Children = append(parent.Children, bodyPart)
+				continue
+			}
+		}
+		roots = append(roots, bodyPart)
+	}
+
+	return roots, nil
+}
+
+// Descendants returns the IDs of a body part and every body part below it
+func (m BodyPartModel) Descendants(id int64) ([]int64, error) {
+	return queryIDs(m.DB, bodyPartSubtree, id)
+}
+
+// ValidateParent checks that parentID exists and that nesting the body part
+// id under it would not place the body part under itself. id is 0 for a body
+// part not yet created.
+func (m BodyPartModel) ValidateParent(id, parentID int64) error {
+	return validateBodyPartParent(m.DB, id, parentID)
+}
+
+func validateBodyPartParent(q dbtx, id, parentID int64) error {
+	// Walk up from the new parent; meeting the body part means a cycle
+	seen := map[int64]bool{}
+	for current := parentID; ; {
+		if current == id {
+			return ErrBodyPartCycle
+		}
+		if seen[current] {
+			// An existing cycle above; refuse to extend it
+			return ErrBodyPartCycle
+		}
+		seen[current] = true
+
+		var next sql.NullInt64
+		err := q.QueryRow("SELECT parent_id FROM body_parts WHERE id = ?", current).Scan(&next)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidInput
+			}
+			return err
+		}
+		if !next.Valid {
+			return nil
+		}
+		current = next.Int64
+	}
+}
+
 // Create inserts a new body part into the database
 func (m BodyPartModel) Create(bodyPart *BodyPart) error {
 	if bodyPart.Name == "" {
@@ -101,10 +266,16 @@ func (m BodyPartModel) Create(bodyPart *BodyPart) error {
 		return ErrDuplicateRecord
 	}
 
+	if bodyPart.ParentID != nil {
+		if err := validateBodyPartParent(m.DB, 0, *bodyPart.ParentID); err != nil {
+			return err
+		}
+	}
+
 	result, err := m.DB.Exec(`
-		INSERT INTO body_parts (name)
-		VALUES (?)`,
-		bodyPart.Name,
+		INSERT INTO body_parts (name, parent_id)
+		VALUES (?, ?)`,
+		bodyPart.Name, bodyPart.ParentID,
 	)
 	if err != nil {
 		return err
@@ -119,7 +290,8 @@ func (m BodyPartModel) Create(bodyPart *BodyPart) error {
 	return nil
 }
 
-// Update modifies an existing body part in the database
+// Update modifies an existing body part in the database. It refuses to nest
+// a body part under itself or one of its descendants.
 func (m BodyPartModel) Update(bodyPart *BodyPart) error {
 	if bodyPart.ID < 1 || bodyPart.Name == "" {
 		return ErrInvalidInput
@@ -140,11 +312,17 @@ func (m BodyPartModel) Update(bodyPart *BodyPart) error {
 		return ErrDuplicateRecord
 	}
 
+	if bodyPart.ParentID != nil {
+		if err := validateBodyPartParent(m.DB, bodyPart.ID, *bodyPart.ParentID); err != nil {
+			return err
+		}
+	}
+
 	result, err := m.DB.Exec(`
 		UPDATE body_parts 
-		SET name = ?, updated_at = CURRENT_TIMESTAMP
+		SET name = ?, parent_id = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?`,
-		bodyPart.Name, bodyPart.ID,
+		bodyPart.Name, bodyPart.ParentID, bodyPart.ID,
 	)
 	if err != nil {
 		return err
@@ -173,12 +351,14 @@ func (m BodyPartModel) Delete(id int64) error {
 	}
 	defer tx.Rollback()
 
-	// Check if the body part is referenced by any exercises
+	// Check if the body part is referenced by any exercises or sub-groups
 	var exists bool
 	err = tx.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM exercises WHERE body_part_id = ?
-		)`, id,
+		) OR EXISTS(
+			SELECT 1 FROM body_parts WHERE parent_id = ?
+		)`, id, id,
 	).Scan(&exists)
 	if err != nil {
 		return err
@@ -0,0 +1,268 @@
+package data
+
+import (
+	"sort"
+	"time"
+)
+
+// Calendar day statuses
+const (
+	DayTrained = "trained" // At least one completed workout
+	DayMissed  = "missed"  // A planned workout in the past with nothing completed
+	DayPlanned = "planned" // A planned workout today or later
+	DayRest    = "rest"    // Nothing logged or planned
+)
+
+// CalendarWorkout is a workout listed on a calendar day
+type CalendarWorkout struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// CalendarDay summarises the workouts of one day
+type CalendarDay struct {
+	Date      string            `json:"date"`
+	Status    string            `json:"status"`
+	Workouts  []CalendarWorkout `json:"workouts"`
+	BodyParts []string          `json:"body_parts"`
+	Volume    VolumeTotals      `json:"volume"`
+}
+
+// Streaks counts consecutive weeks in which a user met their weekly target
+type Streaks struct {
+	WeeklyTarget int `json:"weekly_target"`
+	Current      int `json:"current_weeks"`
+	Longest      int `json:"longest_weeks"`
+}
+
+// WeekAdherence compares the workouts of one week with the weekly target
+type WeekAdherence struct {
+	Start    string `json:"start"`
+	Workouts int    `json:"workouts"`
+	Met      bool   `json:"met"`
+	Complete bool   `json:"complete"` // False for the week in progress
+}
+
+// Adherence reports how often a user met their weekly target during a month
+type Adherence struct {
+	WeeklyTarget   int             `json:"weekly_target"`
+	Weeks          []WeekAdherence `json:"weeks"`
+	WeeksMet       int             `json:"weeks_met"`
+	WeeksEvaluated int             `json:"weeks_evaluated"`
+	Rate           float64         `json:"rate"`
+	Planned        int             `json:"planned_workouts"`
+	Missed         int             `json:"missed_workouts"`
+}
+
+// Calendar is a month of training days with streak and adherence reporting
+type Calendar struct {
+	Month     string        `json:"month"`
+	Days      []CalendarDay `json:"days"`
+	Streaks   Streaks       `json:"streaks"`
+	Adherence *Adherence    `json:"adherence,omitempty"` // Only when a weekly target is set
+}
+
+// Calendar builds the calendar of the month containing month. Today decides
+// which planned workouts were missed and which week is in progress. Streaks
+// count weeks meeting weeklyTarget, or with any workout when it is nil.
+func (m StatsModel) Calendar(userID int64, month, today time.Time, weeklyTarget *int) (*Calendar, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	first := BucketStart(month, BucketMonth)
+	last := first.AddDate(0, 1, -1)
+	today = CivilDate(today)
+
+	calendar := &Calendar{Month: first.Format("2006-01")}
+	index := map[string]int{}
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		index[day.Format(DateFormat)] = len(calendar.Days)
+		calendar.Days = append(calendar.Days, CalendarDay{
+			Date:      day.Format(DateFormat),
+			Status:    DayRest,
+			Workouts:  []CalendarWorkout{},
+			BodyParts: []string{},
+		})
+	}
+
+	// Workouts of the month, completed and planned
+	rows, err := m.DB.Query(`
+		SELECT id, name, date, status
+		FROM workouts
+		WHERE user_id = ? AND date(date) BETWEEN ? AND ?
+		ORDER BY date, id`,
+		userID, first.Format(DateFormat), last.Format(DateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workout CalendarWorkout
+		var date time.Time
+		if err := rows.Scan(&workout.ID, &workout.Name, &date, &workout.Status); err != nil {
+			return nil, err
+		}
+		day := &calendar.Days[index[CivilDate(date).Format(DateFormat)]]
+		day.Workouts = append(day.Workouts, workout)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// A planned workout only counts as missed when nothing was completed that
+	// day, so swapping in a different session is not penalised
+	var planned, missed int
+	for i := range calendar.Days {
+		day := &calendar.Days[i]
+		plannedToday := 0
+		for _, workout := range day.Workouts {
+			if workout.Status == WorkoutCompleted {
+				day.Status = DayTrained
+			} else {
+				plannedToday++
+			}
+		}
+		planned += plannedToday
+
+		if day.Status == DayTrained || plannedToday == 0 {
+			continue
+		}
+		if date, _ := time.Parse(DateFormat, day.Date); date.Before(today) {
+			day.Status = DayMissed
+			missed += plannedToday
+		} else {
+			day.Status = DayPlanned
+		}
+	}
+
+	// Body parts and volume of completed workouts
+	volume, err := m.volumeRows(StatsQuery{UserID: userID, From: first, To: last})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range volume {
+		day := &calendar.Days[index[row.date.Format(DateFormat)]]
+		day.Volume.add(row.sets, row.reps, row.weight)
+		if !containsString(day.BodyParts, row.bodyPartName) {
+			day.BodyParts = append(day.BodyParts, row.bodyPartName)
+		}
+	}
+	for i := range calendar.Days {
+		sort.Strings(calendar.Days[i].BodyParts)
+	}
+
+	// Weekly counts over the whole history for streaks
+	weekly, err := m.weeklyWorkouts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := 1
+	if weeklyTarget != nil {
+		target = *weeklyTarget
+	}
+	calendar.Streaks = weeklyStreaks(weekly, target, today)
+
+	if weeklyTarget != nil {
+		adherence := &Adherence{WeeklyTarget: target, Weeks: []WeekAdherence{}, Planned: planned, Missed: missed}
+		for _, start := range BucketStarts(first, last, BucketWeek) {
+			if start.After(today) {
+				break
+			}
+			week := WeekAdherence{
+				Start:    start.Format(DateFormat),
+				Workouts: weekly[start.Format(DateFormat)],
+				Complete: !start.AddDate(0, 0, 7).After(today),
+			}
+			week.Met = week.Workouts >= target
+			if week.Complete || week.Met {
+				adherence.WeeksEvaluated++
+				if week.Met {
+					adherence.WeeksMet++
+				}
+			}
+			adherence.Weeks = append(adherence.Weeks, week)
+		}
+		if adherence.WeeksEvaluated > 0 {
+			adherence.Rate = float64(adherence.WeeksMet) / float64(adherence.WeeksEvaluated)
+		}
+		calendar.Adherence = adherence
+	}
+
+	return calendar, nil
+}
+
+// weeklyWorkouts counts the completed workouts of a user per week, keyed by
+// the Monday starting the week
+func (m StatsModel) weeklyWorkouts(userID int64) (map[string]int, error) {
+	rows, err := m.DB.Query(`
+		SELECT date
+		FROM workouts
+		WHERE user_id = ? AND status = 'completed'`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weekly := map[string]int{}
+	for rows.Next() {
+		var date time.Time
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		weekly[BucketStart(date, BucketWeek).Format(DateFormat)]++
+	}
+
+	return weekly, rows.Err()
+}
+
+// weeklyStreaks finds the current and longest runs of consecutive weeks with
+// at least target workouts. The week in progress extends the current streak
+// once it meets the target but does not break it before then.
+func weeklyStreaks(weekly map[string]int, target int, today time.Time) Streaks {
+	streaks := Streaks{WeeklyTarget: target}
+
+	var met []time.Time
+	for start, count := range weekly {
+		if count >= target {
+			date, _ := time.Parse(DateFormat, start)
+			met = append(met, date)
+		}
+	}
+	sort.Slice(met, func(i, j int) bool { return met[i].Before(met[j]) })
+
+	run := 0
+	for i, start := range met {
+		if i > 0 && start.Equal(met[i-1].AddDate(0, 0, 7)) {
+			run++
+		} else {
+			run = 1
+		}
+		streaks.Longest = max(streaks.Longest, run)
+	}
+
+	week := BucketStart(today, BucketWeek)
+	if weekly[week.Format(DateFormat)] < target {
+		week = week.AddDate(0, 0, -7)
+	}
+	for weekly[week.Format(DateFormat)] >= target {
+		streaks.Current++
+		week = week.AddDate(0, 0, -7)
+	}
+
+	return streaks
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,14 @@ var (
 	once sync.Once
 )
 
+// dbtx is satisfied by both *sql.DB and *sql.Tx so helpers can run either
+// on their own or as part of a caller's transaction
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // Config holds database configuration
 type Config struct {
 	URL   string
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Equipment represents a piece of equipment from the catalog
+type Equipment struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EquipmentModel wraps the database connection pool
+type EquipmentModel struct {
+	DB *sql.DB
+}
+
+// GetAll retrieves the equipment catalog
+func (m EquipmentModel) GetAll() ([]*Equipment, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, name, created_at
+		FROM equipment
+		ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catalog []*Equipment
+
+	for rows.Next() {
+		equipment := &Equipment{}
+		if err := rows.Scan(&equipment.ID, &equipment.Name, &equipment.CreatedAt); err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, equipment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// ForExercises retrieves the equipment needed by each of the given exercises
+func (m EquipmentModel) ForExercises(exerciseIDs []int64) (map[int64][]Equipment, error) {
+	return exerciseEquipment(m.DB, exerciseIDs)
+}
+
+// SetForExercise replaces the equipment needed by an exercise within tx
+func (m EquipmentModel) SetForExercise(tx *sql.Tx, exerciseID int64, equipmentIDs []int64) error {
+	return setExerciseEquipment(tx, exerciseID, equipmentIDs)
+}
+
+func exerciseEquipment(q dbtx, exerciseIDs []int64) (map[int64][]Equipment, error) {
+	result := map[int64][]Equipment{}
+	if len(exerciseIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inClause(exerciseIDs)
+	rows, err := q.Query(`
+		SELECT ee.exercise_id, eq.id, eq.name, eq.created_at
+		FROM exercise_equipment ee
+		JOIN equipment eq ON eq.id = ee.equipment_id
+		WHERE ee.exercise_id IN (`+placeholders+`)
+		ORDER BY ee.exercise_id, eq.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exerciseID int64
+		var equipment Equipment
+		if err := rows.Scan(&exerciseID, &equipment.ID, &equipment.Name, &equipment.CreatedAt); err != nil {
+			return nil, err
+		}
+		result[exerciseID] = append(result[exerciseID], equipment)
+	}
+
+	return result, rows.Err()
+}
+
+func setExerciseEquipment(q dbtx, exerciseID int64, equipmentIDs []int64) error {
+	if err := validateEquipmentIDs(q, equipmentIDs); err != nil {
+		return err
+	}
+
+	_, err := q.Exec("DELETE FROM exercise_equipment WHERE exercise_id = ?", exerciseID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range equipmentIDs {
+		_, err := q.Exec("INSERT INTO exercise_equipment (exercise_id, equipment_id) VALUES (?, ?)", exerciseID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateEquipmentIDs checks that each ID names catalog equipment and
+// appears once
+func validateEquipmentIDs(q dbtx, equipmentIDs []int64) error {
+	seen := map[int64]bool{}
+	for _, id := range equipmentIDs {
+		if id < 1 || seen[id] {
+			return ErrInvalidInput
+		}
+		seen[id] = true
+
+		var exists bool
+		err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM equipment WHERE id = ?)", id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrInvalidInput
+		}
+	}
+	return nil
+}
+
+func equipmentIDs(equipment []Equipment) []int64 {
+	ids := make([]int64, len(equipment))
+	for i, e := range equipment {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// inClause returns the placeholders and arguments of an SQL IN list
+func inClause(ids []int64) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
@@ -7,4 +7,8 @@ var (
 	ErrInvalidInput         = errors.New("data: invalid input")
 	ErrReferentialIntegrity = errors.New("data: cannot delete record due to referential integrity constraint")
 	ErrDuplicateRecord      = errors.New("data: duplicate record")
+	ErrInvalidGrouping      = errors.New("data: invalid exercise grouping")
+	ErrBodyPartCycle        = errors.New("data: body part cannot be nested under itself")
+	ErrInvalidSort          = errors.New("data: invalid sort")
+	ErrInvalidCursor        = errors.New("data: invalid cursor")
 )
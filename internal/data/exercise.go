@@ -2,17 +2,58 @@ package data
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 )
 
+// Exercise visibilities
+const (
+	VisibilityGlobal  = "global"  // Seen by everyone
+	VisibilityTeam    = "team"    // Seen by the owner and everyone sharing a team with them
+	VisibilityPrivate = "private" // Seen only by the owner
+)
+
+// ValidVisibility reports whether visibility is a known exercise visibility
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityGlobal, VisibilityTeam, VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
 // Exercise represents an exercise record from the database
 type Exercise struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	BodyPartID  int64     `json:"body_part_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	BodyPartID      int64     `json:"body_part_id"`
+	TrackingType    string    `json:"tracking_type"`
+	MovementPattern string    `json:"movement_pattern,omitempty"`
+	VariationOf     *int64    `json:"variation_of,omitempty"`  // Exercise this one is a variation of
+	OwnerUserID     *int64    `json:"owner_user_id,omitempty"` // Nil for the built-in catalog
+	Visibility      string    `json:"visibility"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	BodyPartName string           `json:"-"`                   // Set when reading
+	Muscles      []ExerciseMuscle `json:"muscles,omitempty"`   // Muscles worked, primary first
+	Equipment    []Equipment      `json:"equipment,omitempty"` // Equipment needed; only IDs are read on write
+	Aliases      []string         `json:"aliases,omitempty"`   // Other names; kept on update when nil
+}
+
+// OwnedBy reports whether a user owns, and so may change, the exercise
+func (e *Exercise) OwnedBy(userID int64) bool {
+	return e.OwnerUserID != nil && *e.OwnerUserID == userID
+}
+
+// ExerciseFilter narrows the exercises listed. Every set condition must hold.
+type ExerciseFilter struct {
+	UserID      int64  // Exercises visible to the user; 0 lists every exercise
+	Search      string // Case-insensitive substring of the name or an alias
+	BodyPartID  int64  // Exercises for the body part or any body part below it
+	VariationOf int64  // Direct variations of the exercise
+	GymID       int64  // Exercises performable with the gym's equipment
 }
 
 // ExerciseModel wraps the database connection pool
@@ -20,27 +61,69 @@ type ExerciseModel struct {
 	DB *sql.DB
 }
 
-// GetByID retrieves a single exercise by its ID
-func (m ExerciseModel) GetByID(id int64) (*Exercise, error) {
-	if id < 1 {
-		return nil, ErrInvalidInput
-	}
-
+// exerciseColumns are the columns scanned by scanExercise, from exercises
+// aliased e joined to body_parts aliased b
+const exerciseColumns = `
+		e.id, e.name, e.description, e.body_part_id, e.tracking_type,
+		e.movement_pattern, e.variation_of, e.owner_user_id, e.visibility,
+		e.created_at, e.updated_at, b.name`
+
+// visibleToUser is the SQL condition on exercises aliased e that holds when
+// the user bound to its placeholder can see the exercise: it is global, the
+// user owns it, or it is shared with a team the user and its owner are both in
+const visibleToUser = `(e.visibility = 'global' OR EXISTS (
+		SELECT 1 FROM (SELECT ? AS id) viewer
+		WHERE e.owner_user_id = viewer.id OR (e.visibility = 'team' AND EXISTS (
+			SELECT 1
+			FROM team_members mine
+			JOIN team_members theirs ON theirs.team_id = mine.team_id
+			WHERE mine.user_id = viewer.id AND theirs.user_id = e.owner_user_id))))`
+
+func scanExercise(row interface{ Scan(...interface{}) error }) (*Exercise, error) {
 	exercise := &Exercise{}
+	var pattern sql.NullString
+	var variationOf, ownerUserID sql.NullInt64
 
-	err := m.DB.QueryRow(`
-		SELECT id, name, description, body_part_id, created_at, updated_at
-		FROM exercises
-		WHERE id = ?`, id,
-	).Scan(
+	err := row.Scan(
 		&exercise.ID,
 		&exercise.Name,
 		&exercise.Description,
 		&exercise.BodyPartID,
+		&exercise.TrackingType,
+		&pattern,
+		&variationOf,
+		&ownerUserID,
+		&exercise.Visibility,
 		&exercise.CreatedAt,
 		&exercise.UpdatedAt,
+		&exercise.BodyPartName,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	exercise.MovementPattern = pattern.String
+	if variationOf.Valid {
+		exercise.VariationOf = &variationOf.Int64
+	}
+	if ownerUserID.Valid {
+		exercise.OwnerUserID = &ownerUserID.Int64
+	}
+	return exercise, nil
+}
+
+// GetByID retrieves a single exercise by its ID
+func (m ExerciseModel) GetByID(id int64) (*Exercise, error) {
+	if id < 1 {
+		return nil, ErrInvalidInput
+	}
 
+	exercise, err := scanExercise(m.DB.QueryRow(`
+		SELECT`+exerciseColumns+`
+		FROM exercises e
+		JOIN body_parts b ON b.id = e.body_part_id
+		WHERE e.id = ?`, id,
+	))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrRecordNotFound
@@ -48,97 +131,156 @@ func (m ExerciseModel) GetByID(id int64) (*Exercise, error) {
 		return nil, err
 	}
 
+	if err := attachExerciseDetails(m.DB, []*Exercise{exercise}); err != nil {
+		return nil, err
+	}
 	return exercise, nil
 }
 
+// VisibleTo reports whether a user can see an exercise
+func (m ExerciseModel) VisibleTo(id, userID int64) (bool, error) {
+	var visible bool
+	err := m.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM exercises e WHERE e.id = ? AND "+visibleToUser+")", id, userID,
+	).Scan(&visible)
+	return visible, err
+}
+
 // GetByBodyPart retrieves all exercises for a specific body part
 func (m ExerciseModel) GetByBodyPart(bodyPartID int64) ([]*Exercise, error) {
 	if bodyPartID < 1 {
 		return nil, ErrInvalidInput
 	}
+	exercises, _, err := m.List(ExerciseFilter{BodyPartID: bodyPartID}, ListQuery{})
+	return exercises, err
+}
 
-	rows, err := m.DB.Query(`
-		SELECT id, name, description, body_part_id, created_at, updated_at
-		FROM exercises
-		WHERE body_part_id = ?
-		ORDER BY name`, bodyPartID,
-	)
+// GetAll retrieves all exercises from the database
+func (m ExerciseModel) GetAll() ([]*Exercise, error) {
+	exercises, _, err := m.List(ExerciseFilter{}, ListQuery{})
+	return exercises, err
+}
+
+// exerciseList sorts exercises by name (the default) or by ID
+var exerciseList = listSpec[*Exercise]{
+	sorts: map[string]sortKey[*Exercise]{
+		"name": {"e.name", func(e *Exercise) interface{} { return e.Name }},
+		"id":   {"e.id", func(e *Exercise) interface{} { return e.ID }},
+	},
+	defaultSort: "name",
+	idColumn:    "e.id",
+	id:          func(e *Exercise) int64 { return e.ID },
+}
+
+// List retrieves one page of the exercises matching filter, ordered by name
+// unless list sorts them otherwise, with their muscles, equipment and aliases
+func (m ExerciseModel) List(filter ExerciseFilter, list ListQuery) ([]*Exercise, *Page, error) {
+	clauses, err := exerciseList.clauses(list)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer rows.Close()
 
-	var exercises []*Exercise
+	var conditions []string
+	var args []interface{}
 
-	for rows.Next() {
-		exercise := &Exercise{}
-		err := rows.Scan(
-			&exercise.ID,
-			&exercise.Name,
-			&exercise.Description,
-			&exercise.BodyPartID,
-			&exercise.CreatedAt,
-			&exercise.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		exercises = append(exercises, exercise)
+	if filter.UserID > 0 {
+		conditions = append(conditions, visibleToUser)
+		args = append(args, filter.UserID)
 	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		conditions = append(conditions, `(e.name LIKE ? ESCAPE '\' OR EXISTS (
+			SELECT 1 FROM exercise_aliases a
+			WHERE a.exercise_id = e.id AND a.alias LIKE ? ESCAPE '\'))`)
+		args = append(args, pattern, pattern)
+	}
+	if filter.BodyPartID > 0 {
+		conditions = append(conditions, "e.body_part_id IN ("+bodyPartSubtree+")")
+		args = append(args, filter.BodyPartID)
+	}
+	if filter.VariationOf > 0 {
+		conditions = append(conditions, "e.variation_of = ?")
+		args = append(args, filter.VariationOf)
+	}
+	if filter.GymID > 0 {
+		conditions = append(conditions, performableInGym)
+		args = append(args, filter.GymID)
 	}
 
-	return exercises, nil
-}
+	query := `
+		SELECT` + exerciseColumns + `
+		FROM exercises e
+		JOIN body_parts b ON b.id = e.body_part_id`
+	if clauses.where != "" {
+		conditions = append(conditions, clauses.where)
+		args = append(args, clauses.args...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, clauses.limitArgs()...)
 
-// GetAll retrieves all exercises from the database
-func (m ExerciseModel) GetAll() ([]*Exercise, error) {
-	rows, err := m.DB.Query(`
-		SELECT id, name, description, body_part_id, created_at, updated_at
-		FROM exercises
-		ORDER BY name`)
+	rows, err := m.DB.Query(query+clauses.orderBy, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	var exercises []*Exercise
 
 	for rows.Next() {
-		exercise := &Exercise{}
-		err := rows.Scan(
-			&exercise.ID,
-			&exercise.Name,
-			&exercise.Description,
-			&exercise.BodyPartID,
-			&exercise.CreatedAt,
-			&exercise.UpdatedAt,
-		)
+		exercise, err := scanExercise(rows)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		exercises = append(exercises, exercise)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	rows.Close()
 
-	return exercises, nil
+	exercises, page := exerciseList.page(clauses, exercises)
+	if err := attachExerciseDetails(m.DB, exercises); err != nil {
+		return nil, nil, err
+	}
+	return exercises, page, nil
 }
 
 // Create inserts a new exercise into the database
 func (m ExerciseModel) Create(exercise *Exercise) error {
-	if exercise.Name == "" || exercise.BodyPartID < 1 {
+	if exercise.TrackingType == "" {
+		exercise.TrackingType = TrackingWeightReps
+	}
+	if exercise.Visibility == "" {
+		exercise.Visibility = VisibilityPrivate
+	}
+	if exercise.Name == "" || exercise.BodyPartID < 1 || !ValidTrackingType(exercise.TrackingType) ||
+		(exercise.MovementPattern != "" && !ValidMovementPattern(exercise.MovementPattern)) ||
+		!ValidVisibility(exercise.Visibility) {
 		return ErrInvalidInput
 	}
+	if exercise.VariationOf != nil {
+		if err := validateVariation(m.DB, 0, *exercise.VariationOf, exercise.OwnerUserID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := m.DB.Exec(`
-		INSERT INTO exercises (name, description, body_part_id)
-		VALUES (?, ?, ?)`,
-		exercise.Name, exercise.Description, exercise.BodyPartID,
+	result, err := tx.Exec(`
+		INSERT INTO exercises (
+			name, description, body_part_id, tracking_type, movement_pattern, variation_of,
+			owner_user_id, visibility
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		exercise.Name, exercise.Description, exercise.BodyPartID, exercise.TrackingType,
+		nullString(exercise.MovementPattern), exercise.VariationOf, exercise.OwnerUserID, exercise.Visibility,
 	)
 	if err != nil {
 		return err
@@ -148,22 +290,49 @@ func (m ExerciseModel) Create(exercise *Exercise) error {
 	if err != nil {
 		return err
 	}
-
 	exercise.ID = id
-	return nil
+
+	if err := setExerciseMuscles(tx, exercise.ID, exercise.BodyPartID, exercise.Muscles); err != nil {
+		return err
+	}
+	if err := setExerciseEquipment(tx, exercise.ID, equipmentIDs(exercise.Equipment)); err != nil {
+		return err
+	}
+	if err := setExerciseAliases(tx, exercise.ID, exercise.Aliases); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Update modifies an existing exercise in the database
+// Update modifies an existing exercise in the database. An empty tracking
+// type keeps the stored one, since changing it would misread logged sets.
 func (m ExerciseModel) Update(exercise *Exercise) error {
-	if exercise.ID < 1 || exercise.Name == "" || exercise.BodyPartID < 1 {
+	if exercise.ID < 1 || exercise.Name == "" || exercise.BodyPartID < 1 ||
+		(exercise.TrackingType != "" && !ValidTrackingType(exercise.TrackingType)) ||
+		(exercise.MovementPattern != "" && !ValidMovementPattern(exercise.MovementPattern)) ||
+		!ValidVisibility(exercise.Visibility) {
 		return ErrInvalidInput
 	}
+	if exercise.VariationOf != nil {
+		if err := validateVariation(m.DB, exercise.ID, *exercise.VariationOf, exercise.OwnerUserID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := m.DB.Exec(`
+	result, err := tx.Exec(`
 		UPDATE exercises 
-		SET name = ?, description = ?, body_part_id = ?, updated_at = CURRENT_TIMESTAMP
+		SET name = ?, description = ?, body_part_id = ?, tracking_type = COALESCE(NULLIF(?, ''), tracking_type),
+		    movement_pattern = ?, variation_of = ?, visibility = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?`,
-		exercise.Name, exercise.Description, exercise.BodyPartID, exercise.ID,
+		exercise.Name, exercise.Description, exercise.BodyPartID, exercise.TrackingType,
+		nullString(exercise.MovementPattern), exercise.VariationOf, exercise.Visibility, exercise.ID,
 	)
 	if err != nil {
 		return err
@@ -176,11 +345,34 @@ func (m ExerciseModel) Update(exercise *Exercise) error {
 	if rowsAffected == 0 {
 		return ErrRecordNotFound
 	}
+	if exercise.TrackingType == "" {
+		if err := tx.QueryRow("SELECT tracking_type FROM exercises WHERE id = ?", exercise.ID).Scan(&exercise.TrackingType); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	// Muscles, equipment and aliases are only replaced when given
+	if exercise.Muscles != nil {
+		if err := setExerciseMuscles(tx, exercise.ID, exercise.BodyPartID, exercise.Muscles); err != nil {
+			return err
+		}
+	}
+	if exercise.Equipment != nil {
+		if err := setExerciseEquipment(tx, exercise.ID, equipmentIDs(exercise.Equipment)); err != nil {
+			return err
+		}
+	}
+	if exercise.Aliases != nil {
+		if err := setExerciseAliases(tx, exercise.ID, exercise.Aliases); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// Delete removes an exercise from the database
+// Delete removes an exercise from the database. Its variations become
+// standalone exercises.
 func (m ExerciseModel) Delete(id int64) error {
 	if id < 1 {
 		return ErrInvalidInput
@@ -207,6 +399,9 @@ func (m ExerciseModel) Delete(id int64) error {
 	}
 
 	// If not used in any workouts, proceed with deletion
+	if err := deleteExerciseDetails(tx, id); err != nil {
+		return err
+	}
 	result, err := tx.Exec("DELETE FROM exercises WHERE id = ?", id)
 	if err != nil {
 		return err
@@ -222,3 +417,53 @@ func (m ExerciseModel) Delete(id int64) error {
 
 	return tx.Commit()
 }
+
+// DeleteDetails removes the muscles, equipment, aliases and media records of
+// an exercise within tx and detaches its variations, ahead of deleting it
+func (m ExerciseModel) DeleteDetails(tx *sql.Tx, id int64) error {
+	return deleteExerciseDetails(tx, id)
+}
+
+func deleteExerciseDetails(q dbtx, id int64) error {
+	for _, query := range []string{
+		"DELETE FROM exercise_muscles WHERE exercise_id = ?",
+		"DELETE FROM exercise_equipment WHERE exercise_id = ?",
+		"DELETE FROM exercise_aliases WHERE exercise_id = ?",
+		"DELETE FROM exercise_media WHERE exercise_id = ?",
+		"UPDATE exercises SET variation_of = NULL WHERE variation_of = ?",
+	} {
+		if _, err := q.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachExerciseDetails loads the muscles, equipment and aliases of each
+// exercise
+func attachExerciseDetails(q dbtx, exercises []*Exercise) error {
+	ids := make([]int64, len(exercises))
+	for i, exercise := range exercises {
+		ids[i] = exercise.ID
+	}
+
+	muscles, err := exerciseMuscles(q, ids)
+	if err != nil {
+		return err
+	}
+	equipment, err := exerciseEquipment(q, ids)
+	if err != nil {
+		return err
+	}
+	aliases, err := exerciseAliases(q, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, exercise := range exercises {
+		exercise.Muscles = muscles[exercise.ID]
+		exercise.Equipment = equipment[exercise.ID]
+		exercise.Aliases = aliases[exercise.ID]
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Movement patterns group exercises that train the same movement
+const (
+	PatternHorizontalPush = "horizontal_push"
+	PatternVerticalPush   = "vertical_push"
+	PatternHorizontalPull = "horizontal_pull"
+	PatternVerticalPull   = "vertical_pull"
+	PatternSquat          = "squat"
+	PatternHinge          = "hinge"
+	PatternLunge          = "lunge"
+	PatternCarry          = "carry"
+	PatternCore           = "core"
+	PatternIsolation      = "isolation" // Single-joint work such as curls
+	PatternLocomotion     = "locomotion"
+)
+
+// maxAliasLength bounds a single exercise alias, in characters
+const maxAliasLength = 100
+
+// ValidMovementPattern reports whether pattern is a known movement pattern
+func ValidMovementPattern(pattern string) bool {
+	switch pattern {
+	case PatternHorizontalPush, PatternVerticalPush, PatternHorizontalPull, PatternVerticalPull,
+		PatternSquat, PatternHinge, PatternLunge, PatternCarry, PatternCore, PatternIsolation,
+		PatternLocomotion:
+		return true
+	}
+	return false
+}
+
+// NormalizeAliases trims aliases and collapses runs of whitespace inside
+// them, dropping case-insensitive duplicates. Blank and overlong aliases are
+// invalid.
+func NormalizeAliases(aliases []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+
+	for _, alias := range aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if alias == "" || len([]rune(alias)) > maxAliasLength {
+			return nil, ErrInvalidInput
+		}
+		key := strings.ToLower(alias)
+		if !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, alias)
+		}
+	}
+
+	return normalized, nil
+}
+
+// SetAliases replaces the aliases of an exercise within tx
+func (m ExerciseModel) SetAliases(tx *sql.Tx, exerciseID int64, aliases []string) error {
+	return setExerciseAliases(tx, exerciseID, aliases)
+}
+
+// ValidateVariation checks that the exercise parentID exists and can be seen
+// by the owner of exerciseID, and that making exerciseID a variation of it
+// would not make an exercise a variation of itself. exerciseID is 0 for an
+// exercise not yet created; ownerUserID is nil for catalog exercises.
+func (m ExerciseModel) ValidateVariation(exerciseID, parentID int64, ownerUserID *int64) error {
+	return validateVariation(m.DB, exerciseID, parentID, ownerUserID)
+}
+
+func setExerciseAliases(q dbtx, exerciseID int64, aliases []string) error {
+	aliases, err := NormalizeAliases(aliases)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec("DELETE FROM exercise_aliases WHERE exercise_id = ?", exerciseID)
+	if err != nil {
+		return err
+	}
+
+	for _, alias := range aliases {
+		_, err := q.Exec("INSERT INTO exercise_aliases (exercise_id, alias) VALUES (?, ?)", exerciseID, alias)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exerciseAliases loads the aliases of each of the given exercises in one query
+func exerciseAliases(q dbtx, exerciseIDs []int64) (map[int64][]string, error) {
+	result := map[int64][]string{}
+	if len(exerciseIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inClause(exerciseIDs)
+	rows, err := q.Query(`
+		SELECT exercise_id, alias
+		FROM exercise_aliases
+		WHERE exercise_id IN (`+placeholders+`)
+		ORDER BY exercise_id, alias`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exerciseID int64
+		var alias string
+		if err := rows.Scan(&exerciseID, &alias); err != nil {
+			return nil, err
+		}
+		result[exerciseID] = append(result[exerciseID], alias)
+	}
+
+	return result, rows.Err()
+}
+
+func validateVariation(q dbtx, exerciseID, parentID int64, ownerUserID *int64) error {
+	// The parent must be one the owner can see
+	var visible bool
+	err := q.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM exercises e WHERE e.id = ? AND `+visibleToUser+`
+		)`, parentID, ownerUserID,
+	).Scan(&visible)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return ErrInvalidInput
+	}
+
+	// Walk up from the parent; reaching the exercise would close a cycle
+	seen := map[int64]bool{}
+	for id := parentID; ; {
+		if id == exerciseID || seen[id] {
+			return ErrInvalidInput
+		}
+		seen[id] = true
+
+		var next sql.NullInt64
+		err := q.QueryRow("SELECT variation_of FROM exercises WHERE id = ?", id).Scan(&next)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidInput
+			}
+			return err
+		}
+		if !next.Valid {
+			return nil
+		}
+		id = next.Int64
+	}
+}
@@ -0,0 +1,78 @@
+package data
+
+import "time"
+
+// trendAlpha is the smoothing factor of the estimated 1RM trend line. Higher
+// values follow recent sessions more closely.
+const trendAlpha = 0.3
+
+// ExerciseSession summarises one workout's worth of a single exercise
+type ExerciseSession struct {
+	WorkoutID          int64            `json:"workout_id"`
+	Date               time.Time        `json:"date"`
+	Sets               int              `json:"sets"`
+	Reps               int              `json:"reps"`
+	Volume             float64          `json:"volume"`
+	BestSet            *WorkoutExercise `json:"best_set"`
+	EstimatedOneRepMax float64          `json:"estimated_1rm"`
+	Trend              *float64         `json:"trend_1rm,omitempty"`
+}
+
+// GetSessions retrieves every session in which a user performed an exercise,
+// oldest first, with the best set, estimated 1RM and a smoothed 1RM trend
+func (m WorkoutExerciseModel) GetSessions(userID, exerciseID int64) ([]*ExerciseSession, error) {
+	history, err := m.GetHistoryForUser(userID, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeSessions(history), nil
+}
+
+// SummarizeSessions groups a history ordered oldest first into sessions. The
+// best set of a session is the one with the highest Epley estimated 1RM, and
+// the trend is an exponential moving average of those estimates.
+func SummarizeSessions(history []*ExercisePerformance) []*ExerciseSession {
+	var sessions []*ExerciseSession
+	var trend *float64
+
+	for start := 0; start < len(history); {
+		end := start
+		for end < len(history) && history[end].WorkoutID == history[start].WorkoutID {
+			end++
+		}
+
+		session := &ExerciseSession{
+			WorkoutID: history[start].WorkoutID,
+			Date:      CivilDate(history[start].WorkoutDate),
+		}
+		for _, p := range history[start:end] {
+			session.Sets += p.Sets
+			session.Reps += p.Sets * p.Reps
+			session.Volume += Volume(p.WorkoutExercise)
+
+			oneRepMax := EpleyOneRepMax(weightOf(p.Weight), p.Reps)
+			if session.BestSet == nil || oneRepMax > session.EstimatedOneRepMax {
+				best := p.WorkoutExercise
+				session.BestSet = &best
+				session.EstimatedOneRepMax = oneRepMax
+			}
+		}
+		start = end
+
+		if session.EstimatedOneRepMax > 0 {
+			next := session.EstimatedOneRepMax
+			if trend != nil {
+				next = trendAlpha*session.EstimatedOneRepMax + (1-trendAlpha)*(*trend)
+			}
+			trend = &next
+		}
+		if trend != nil {
+			value := *trend
+			session.Trend = &value
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
@@ -0,0 +1,180 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// MergeResult counts what a merge moved from the source exercise to the target
+type MergeResult struct {
+	WorkoutExercises int64 `json:"workout_exercises"`
+	Goals            int64 `json:"goals"`
+	Media            int64 `json:"media"`
+	Variations       int64 `json:"variations"`
+	Aliases          int   `json:"aliases"`
+	RecordsRebuilt   int   `json:"records_rebuilt"` // Users whose records were rebuilt
+}
+
+// Merge folds the exercise sourceID into targetID in one transaction: workout
+// entries, goals, media and variations move to the target, the source's name
+// and aliases become aliases of the target unless that would show them to
+// users who could not see the source, personal records and goals are
+// re-evaluated for every affected user, and the source is deleted. Callers
+// check that the two exercises are compatible first.
+func (m ExerciseModel) Merge(sourceID, targetID int64) (*MergeResult, error) {
+	if sourceID < 1 || targetID < 1 || sourceID == targetID {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sourceName, targetName, sourceVisibility, targetVisibility string
+	err = tx.QueryRow("SELECT name, visibility FROM exercises WHERE id = ?", sourceID).Scan(&sourceName, &sourceVisibility)
+	if err == nil {
+		err = tx.QueryRow("SELECT name, visibility FROM exercises WHERE id = ?", targetID).Scan(&targetName, &targetVisibility)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	// Everyone with history or records on the source needs their records
+	// rebuilt against the combined history
+	userIDs, err := queryIDs(tx, `
+		SELECT w.user_id
+		FROM workout_exercises we
+		JOIN workouts w ON w.id = we.workout_id
+		WHERE we.exercise_id = ?
+		UNION
+		SELECT user_id FROM personal_records WHERE exercise_id = ?`, sourceID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Goals on either exercise are measured against the combined history, so
+	// their owners' goals are evaluated again too
+	goalUserIDs, err := queryIDs(tx, `
+		SELECT user_id FROM goals WHERE exercise_id IN (?, ?)
+		UNION
+		SELECT w.user_id
+		FROM workout_exercises we
+		JOIN workouts w ON w.id = we.workout_id
+		WHERE we.exercise_id = ?`, sourceID, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MergeResult{}
+	moves := []struct {
+		query string
+		count *int64
+	}{
+		{"UPDATE workout_exercises SET exercise_id = ? WHERE exercise_id = ?", &result.WorkoutExercises},
+		{"UPDATE goals SET exercise_id = ? WHERE exercise_id = ?", &result.Goals},
+		{"UPDATE exercise_media SET exercise_id = ? WHERE exercise_id = ?", &result.Media},
+	}
+	for _, move := range moves {
+		res, err := tx.Exec(move.query, targetID, sourceID)
+		if err != nil {
+			return nil, err
+		}
+		if *move.count, err = res.RowsAffected(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Variations of the source become variations of the target, except the
+	// target itself, which would otherwise become its own variation
+	res, err := tx.Exec(`
+		UPDATE exercises
+		SET variation_of = CASE WHEN id = ? THEN NULL ELSE ? END
+		WHERE variation_of = ?`, targetID, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if result.Variations, err = res.RowsAffected(); err != nil {
+		return nil, err
+	}
+
+	// Keep the source findable by the names it went by, as long as the target
+	// is seen by no one who could not see the source
+	sourceAliases, err := exerciseAliases(tx, []int64{sourceID})
+	if err != nil {
+		return nil, err
+	}
+	names := append([]string{sourceName}, sourceAliases[sourceID]...)
+	if visibilityReach[targetVisibility] > visibilityReach[sourceVisibility] {
+		names = nil
+	}
+	for _, alias := range names {
+		if strings.EqualFold(alias, targetName) {
+			continue
+		}
+		res, err := tx.Exec("INSERT OR IGNORE INTO exercise_aliases (exercise_id, alias) VALUES (?, ?)", targetID, alias)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n > 0 {
+			result.Aliases++
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM personal_records WHERE exercise_id = ?", sourceID); err != nil {
+		return nil, err
+	}
+	if err := deleteExerciseDetails(tx, sourceID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM exercises WHERE id = ?", sourceID); err != nil {
+		return nil, err
+	}
+
+	for _, userID := range userIDs {
+		if err := refreshPersonalRecords(tx, userID, []int64{targetID}); err != nil {
+			return nil, err
+		}
+	}
+	result.RecordsRebuilt = len(userIDs)
+
+	for _, userID := range goalUserIDs {
+		if err := evaluateGoals(tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// visibilityReach orders the exercise visibilities from the fewest users to the most
+var visibilityReach = map[string]int{VisibilityPrivate: 0, VisibilityTeam: 1, VisibilityGlobal: 2}
+
+// queryIDs runs a query selecting a single ID column
+func queryIDs(q dbtx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,565 @@
+package data
+
+import (
+	"database/sql"
+	"math"
+	"time"
+)
+
+// Goal types
+const (
+	GoalLiftWeight       = "lift_weight"       // Heaviest weight lifted on an exercise, in kg
+	GoalOneRepMax        = "e1rm"              // Epley estimated 1RM on an exercise, in kg
+	GoalFrequency        = "frequency"         // Workouts per week since the goal was set, averaged over four weeks
+	GoalRelativeStrength = "relative_strength" // Heaviest weight lifted as a multiple of body weight
+)
+
+// Goal statuses
+const (
+	GoalActive   = "active"
+	GoalAchieved = "achieved"
+)
+
+// ValidGoalType reports whether goalType is a known goal type
+func ValidGoalType(goalType string) bool {
+	switch goalType {
+	case GoalLiftWeight, GoalOneRepMax, GoalFrequency, GoalRelativeStrength:
+		return true
+	}
+	return false
+}
+
+// GoalIsWeight reports whether the target of a goal type is a weight
+func GoalIsWeight(goalType string) bool {
+	return goalType == GoalLiftWeight || goalType == GoalOneRepMax
+}
+
+// frequencyWindowDays is the trailing window frequency goals are averaged over
+const frequencyWindowDays = 28
+
+// projectionWindowDays limits the history used to project completion to the
+// recent rate of progress
+const projectionWindowDays = 90
+
+// Goal is a target a user is working towards
+type Goal struct {
+	ID                int64         `json:"id"`
+	UserID            int64         `json:"user_id"`
+	GoalType          string        `json:"goal_type"`
+	ExerciseID        *int64        `json:"exercise_id,omitempty"`
+	TargetValue       float64       `json:"target_value"`
+	Unit              string        `json:"unit,omitempty"` // Set for weight goals
+	Deadline          *time.Time    `json:"deadline,omitempty"`
+	Status            string        `json:"status"`
+	AchievedOn        *time.Time    `json:"achieved_on,omitempty"`
+	AchievedWorkoutID *int64        `json:"achieved_workout_id,omitempty"`
+	Notes             string        `json:"notes"`
+	Progress          *GoalProgress `json:"progress,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+// GoalProgress is how close a goal is to being achieved
+type GoalProgress struct {
+	Current     float64 `json:"current"`
+	Percent     float64 `json:"percent"`
+	ProjectedOn *string `json:"projected_on,omitempty"` // When the recent trend reaches the target
+	OnTrack     *bool   `json:"on_track,omitempty"`     // Whether the projection is before the deadline
+}
+
+// goalPoint is the value of a goal's metric after one workout
+type goalPoint struct {
+	date      time.Time
+	workoutID int64
+	value     float64
+}
+
+// GoalModel wraps the database connection pool
+type GoalModel struct {
+	DB *sql.DB
+}
+
+const goalColumns = `
+	id, user_id, goal_type, exercise_id, target_value, deadline, status,
+	achieved_on, achieved_workout_id, notes, created_at, updated_at`
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (*Goal, error) {
+	goal := &Goal{}
+	var notes sql.NullString
+	var deadline, achievedOn sql.NullTime
+	err := row.Scan(
+		&goal.ID,
+		&goal.UserID,
+		&goal.GoalType,
+		&goal.ExerciseID,
+		&goal.TargetValue,
+		&deadline,
+		&goal.Status,
+		&achievedOn,
+		&goal.AchievedWorkoutID,
+		&notes,
+		&goal.CreatedAt,
+		&goal.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	goal.Notes = notes.String
+	if deadline.Valid {
+		d := CivilDate(deadline.Time)
+		goal.Deadline = &d
+	}
+	if achievedOn.Valid {
+		d := CivilDate(achievedOn.Time)
+		goal.AchievedOn = &d
+	}
+	if GoalIsWeight(goal.GoalType) {
+		goal.Unit = UnitKilograms
+	}
+	return goal, nil
+}
+
+// GetByID retrieves a goal with its current progress
+func (m GoalModel) GetByID(id int64) (*Goal, error) {
+	if id < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	goal, err := scanGoal(m.DB.QueryRow("SELECT"+goalColumns+" FROM goals WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	if err := attachProgress(m.DB, goal, Today(userLocation(m.DB, goal.UserID))); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// GetAll retrieves the goals of a user with their current progress, optionally
+// only those with the given status
+func (m GoalModel) GetAll(userID int64, status string) ([]*Goal, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+	if status != "" && status != GoalActive && status != GoalAchieved {
+		return nil, ErrInvalidInput
+	}
+
+	goals, err := userGoals(m.DB, userID, status)
+	if err != nil {
+		return nil, err
+	}
+
+	today := Today(userLocation(m.DB, userID))
+	for _, goal := range goals {
+		if err := attachProgress(m.DB, goal, today); err != nil {
+			return nil, err
+		}
+	}
+	return goals, nil
+}
+
+// Create inserts a new goal, marking it achieved straight away if the user's
+// history already meets it
+func (m GoalModel) Create(goal *Goal) error {
+	if err := validateGoal(goal); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := goalExerciseExists(tx, goal); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO goals (user_id, goal_type, exercise_id, target_value, deadline, status, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		goal.UserID, goal.GoalType, goal.ExerciseID, goal.TargetValue,
+		civilDateValue(goal.Deadline), GoalActive, goal.Notes,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	goal.ID = id
+	goal.Status = GoalActive
+
+	if err := evaluateGoals(tx, goal.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	stored, err := m.GetByID(id)
+	if err != nil {
+		return err
+	}
+	*goal = *stored
+	return nil
+}
+
+// Update modifies the target, deadline and notes of a goal. Changing the
+// target re-opens an achieved goal until the history meets the new target.
+func (m GoalModel) Update(goal *Goal) error {
+	if goal.ID < 1 {
+		return ErrInvalidInput
+	}
+	if err := validateGoal(goal); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := goalExerciseExists(tx, goal); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE goals
+		SET goal_type = ?, exercise_id = ?, target_value = ?, deadline = ?, notes = ?,
+		    status = ?, achieved_on = NULL, achieved_workout_id = NULL,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		goal.GoalType, goal.ExerciseID, goal.TargetValue, civilDateValue(goal.Deadline), goal.Notes,
+		GoalActive, goal.ID, goal.UserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if err := evaluateGoals(tx, goal.UserID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	stored, err := m.GetByID(goal.ID)
+	if err != nil {
+		return err
+	}
+	*goal = *stored
+	return nil
+}
+
+// Delete removes a goal belonging to a user
+func (m GoalModel) Delete(userID, id int64) error {
+	if userID < 1 || id < 1 {
+		return ErrInvalidInput
+	}
+
+	result, err := m.DB.Exec("DELETE FROM goals WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func validateGoal(goal *Goal) error {
+	if goal.UserID < 1 || !ValidGoalType(goal.GoalType) || goal.TargetValue <= 0 {
+		return ErrInvalidInput
+	}
+	needsExercise := goal.GoalType != GoalFrequency
+	if needsExercise != (goal.ExerciseID != nil) {
+		return ErrInvalidInput
+	}
+	if goal.GoalType == GoalFrequency && goal.TargetValue > 14 {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+func goalExerciseExists(q dbtx, goal *Goal) error {
+	if goal.ExerciseID == nil {
+		return nil
+	}
+	var exists bool
+	err := q.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM exercises e WHERE e.id = ? AND "+visibleToUser+")", *goal.ExerciseID, goal.UserID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+func civilDateValue(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+	return date.Format(DateFormat)
+}
+
+// userGoals loads the goals of a user, optionally only those with status
+func userGoals(q dbtx, userID int64, status string) ([]*Goal, error) {
+	query := "SELECT" + goalColumns + " FROM goals WHERE user_id = ?"
+	args := []interface{}{userID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []*Goal
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+
+	return goals, rows.Err()
+}
+
+// evaluateGoals marks the active goals of a user achieved once their history
+// reaches the target, recording the first workout that did. It runs inside the
+// transaction of workout writes. Achieved goals stay achieved if that workout
+// is later edited or deleted.
+func evaluateGoals(q dbtx, userID int64) error {
+	goals, err := userGoals(q, userID, GoalActive)
+	if err != nil {
+		return err
+	}
+
+	for _, goal := range goals {
+		points, err := goalSeries(q, goal)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range points {
+			if p.value < goal.TargetValue {
+				continue
+			}
+			if _, err := q.Exec(`
+				UPDATE goals
+				SET status = ?, achieved_on = ?, achieved_workout_id = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`,
+				GoalAchieved, p.date.Format(DateFormat), p.workoutID, goal.ID,
+			); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// attachProgress computes the current value of a goal's metric as of today,
+// the user's local date, and, for active goals, when the recent trend projects
+// it to be reached
+func attachProgress(q dbtx, goal *Goal, today time.Time) error {
+	points, err := goalSeries(q, goal)
+	if err != nil {
+		return err
+	}
+
+	progress := &GoalProgress{}
+	if goal.GoalType == GoalFrequency {
+		progress.Current = workoutsPerWeek(points, today)
+	} else {
+		for _, p := range points {
+			progress.Current = math.Max(progress.Current, p.value)
+		}
+	}
+	progress.Percent = math.Min(100, progress.Current/goal.TargetValue*100)
+
+	if goal.Status == GoalActive {
+		if projected, ok := projectGoal(points, goal.TargetValue); ok {
+			date := projected.Format(DateFormat)
+			progress.ProjectedOn = &date
+			if goal.Deadline != nil {
+				onTrack := !projected.After(*goal.Deadline)
+				progress.OnTrack = &onTrack
+			}
+		}
+	}
+
+	goal.Progress = progress
+	return nil
+}
+
+// projectGoal fits a least-squares line through the recent values of a goal's
+// metric and returns the date it crosses target, if it is rising
+func projectGoal(points []goalPoint, target float64) (time.Time, bool) {
+	if len(points) < 2 {
+		return time.Time{}, false
+	}
+
+	last := points[len(points)-1].date
+	cutoff := last.AddDate(0, 0, -projectionWindowDays)
+
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		if p.date.Before(cutoff) {
+			continue
+		}
+		x := p.date.Sub(last).Hours() / 24
+		n++
+		sumX += x
+		sumY += p.value
+		sumXY += x * p.value
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return time.Time{}, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	if slope <= 0 {
+		return time.Time{}, false
+	}
+	intercept := (sumY - slope*sumX) / n
+
+	days := math.Ceil((target - intercept) / slope)
+	if days < 0 {
+		days = 0
+	}
+	return last.AddDate(0, 0, int(days)), true
+}
+
+// goalSeries returns the value of a goal's metric after each of the user's
+// workouts that affect it, oldest first
+func goalSeries(q dbtx, goal *Goal) ([]goalPoint, error) {
+	if goal.GoalType == GoalFrequency {
+		// Only workouts from the day the goal was set count towards it
+		since := CivilDate(goal.CreatedAt.In(userLocation(q, goal.UserID)))
+		return frequencySeries(q, goal.UserID, since)
+	}
+
+	history, err := exerciseHistory(q, goal.UserID, *goal.ExerciseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyWeights []*Measurement
+	if goal.GoalType == GoalRelativeStrength {
+		bodyWeights, err = userMeasurements(q, goal.UserID, MeasurementFilter{Metric: MetricBodyWeight})
+		if err != nil {
+			return nil, err
+		}
+		if len(bodyWeights) == 0 {
+			return nil, nil
+		}
+	}
+
+	var points []goalPoint
+	for _, p := range history {
+		weight := weightOf(p.Weight)
+		if weight <= 0 || p.Reps < 1 {
+			continue
+		}
+
+		value := weight
+		switch goal.GoalType {
+		case GoalOneRepMax:
+			value = EpleyOneRepMax(weight, p.Reps)
+		case GoalRelativeStrength:
+			value = weight / NearestMeasurement(bodyWeights, p.WorkoutDate).Value
+		}
+
+		// Keep the best value of each workout
+		date := CivilDate(p.WorkoutDate)
+		if n := len(points); n > 0 && points[n-1].workoutID == p.WorkoutID {
+			points[n-1].value = math.Max(points[n-1].value, value)
+			continue
+		}
+		points = append(points, goalPoint{date: date, workoutID: p.WorkoutID, value: value})
+	}
+
+	return points, nil
+}
+
+// frequencySeries returns the trailing workouts per week after each workout of
+// a user on or after since
+func frequencySeries(q dbtx, userID int64, since time.Time) ([]goalPoint, error) {
+	rows, err := q.Query(`
+		SELECT id, date
+		FROM workouts
+		WHERE user_id = ? AND status = 'completed' AND date(date) >= ?
+		ORDER BY date, id`, userID, since.Format(DateFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []goalPoint
+	for rows.Next() {
+		var p goalPoint
+		if err := rows.Scan(&p.workoutID, &p.date); err != nil {
+			return nil, err
+		}
+		p.date = CivilDate(p.date)
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range points {
+		points[i].value = workoutsPerWeek(points[:i+1], points[i].date)
+	}
+	return points, nil
+}
+
+// workoutsPerWeek averages the workouts in points over the trailing window ending on date
+func workoutsPerWeek(points []goalPoint, date time.Time) float64 {
+	start := date.AddDate(0, 0, 1-frequencyWindowDays)
+	count := 0
+	for _, p := range points {
+		if !p.date.Before(start) && !p.date.After(date) {
+			count++
+		}
+	}
+	return float64(count) * 7 / frequencyWindowDays
+}
@@ -0,0 +1,334 @@
+package data
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// GymProfile is a place a user trains and the equipment available there
+type GymProfile struct {
+	ID           int64       `json:"id"`
+	UserID       int64       `json:"user_id"`
+	Name         string      `json:"name"`
+	EquipmentIDs []int64     `json:"equipment_ids"`
+	Equipment    []Equipment `json:"equipment"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// GymModel wraps the database connection pool
+type GymModel struct {
+	DB *sql.DB
+}
+
+// performableInGym is an SQL condition on exercises aliased e that holds
+// when the gym bound to its placeholder has all the equipment the exercise
+// needs
+const performableInGym = `NOT EXISTS (
+		SELECT 1 FROM exercise_equipment ee
+		WHERE ee.exercise_id = e.id
+		  AND ee.equipment_id NOT IN (SELECT equipment_id FROM gym_equipment WHERE gym_id = ?))`
+
+// GetByID retrieves a gym profile with its equipment
+func (m GymModel) GetByID(id int64) (*GymProfile, error) {
+	if id < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	gym := &GymProfile{}
+	err := m.DB.QueryRow(`
+		SELECT id, user_id, name, created_at, updated_at
+		FROM gym_profiles
+		WHERE id = ?`, id,
+	).Scan(&gym.ID, &gym.UserID, &gym.Name, &gym.CreatedAt, &gym.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	if err := attachGymEquipment(m.DB, []*GymProfile{gym}); err != nil {
+		return nil, err
+	}
+	return gym, nil
+}
+
+// GetAll retrieves the gym profiles of a user
+func (m GymModel) GetAll(userID int64) ([]*GymProfile, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT id, user_id, name, created_at, updated_at
+		FROM gym_profiles
+		WHERE user_id = ?
+		ORDER BY name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gyms []*GymProfile
+
+	for rows.Next() {
+		gym := &GymProfile{}
+		if err := rows.Scan(&gym.ID, &gym.UserID, &gym.Name, &gym.CreatedAt, &gym.UpdatedAt); err != nil {
+			return nil, err
+		}
+		gyms = append(gyms, gym)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := attachGymEquipment(m.DB, gyms); err != nil {
+		return nil, err
+	}
+	return gyms, nil
+}
+
+// Create inserts a new gym profile with its equipment
+func (m GymModel) Create(gym *GymProfile) error {
+	if gym.UserID < 1 || strings.TrimSpace(gym.Name) == "" {
+		return ErrInvalidInput
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Check if the user already has a gym with this name
+	var exists bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM gym_profiles WHERE user_id = ? AND name = ?
+		)`, gym.UserID, gym.Name,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateRecord
+	}
+
+	result, err := tx.Exec("INSERT INTO gym_profiles (user_id, name) VALUES (?, ?)", gym.UserID, gym.Name)
+	if err != nil {
+		return err
+	}
+
+	gym.ID, err = result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err := setGymEquipment(tx, gym.ID, gym.EquipmentIDs); err != nil {
+		return err
+	}
+	if err := attachGymEquipment(tx, []*GymProfile{gym}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update renames a gym profile and replaces its equipment
+func (m GymModel) Update(gym *GymProfile) error {
+	if gym.ID < 1 || strings.TrimSpace(gym.Name) == "" {
+		return ErrInvalidInput
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Check if another gym of the same user has this name
+	var exists bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM gym_profiles
+			WHERE name = ? AND id != ?
+			  AND user_id = (SELECT user_id FROM gym_profiles WHERE id = ?)
+		)`, gym.Name, gym.ID, gym.ID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateRecord
+	}
+
+	result, err := tx.Exec(`
+		UPDATE gym_profiles
+		SET name = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, gym.Name, gym.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if err := setGymEquipment(tx, gym.ID, gym.EquipmentIDs); err != nil {
+		return err
+	}
+	if err := attachGymEquipment(tx, []*GymProfile{gym}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a gym profile
+func (m GymModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrInvalidInput
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM gym_equipment WHERE gym_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM gym_profiles WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+func setGymEquipment(q dbtx, gymID int64, equipmentIDs []int64) error {
+	if err := validateEquipmentIDs(q, equipmentIDs); err != nil {
+		return err
+	}
+
+	_, err := q.Exec("DELETE FROM gym_equipment WHERE gym_id = ?", gymID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range equipmentIDs {
+		_, err := q.Exec("INSERT INTO gym_equipment (gym_id, equipment_id) VALUES (?, ?)", gymID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachGymEquipment loads the equipment of each gym in one query
+func attachGymEquipment(q dbtx, gyms []*GymProfile) error {
+	if len(gyms) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*GymProfile, len(gyms))
+	ids := make([]int64, len(gyms))
+	for i, gym := range gyms {
+		byID[gym.ID] = gym
+		ids[i] = gym.ID
+		gym.Equipment = []Equipment{}
+		gym.EquipmentIDs = []int64{}
+	}
+
+	placeholders, args := inClause(ids)
+	rows, err := q.Query(`
+		SELECT ge.gym_id, eq.id, eq.name, eq.created_at
+		FROM gym_equipment ge
+		JOIN equipment eq ON eq.id = ge.equipment_id
+		WHERE ge.gym_id IN (`+placeholders+`)
+		ORDER BY ge.gym_id, eq.name`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gymID int64
+		var equipment Equipment
+		if err := rows.Scan(&gymID, &equipment.ID, &equipment.Name, &equipment.CreatedAt); err != nil {
+			return err
+		}
+		gym := byID[gymID]
+		gym.Equipment = append(gym.Equipment, equipment)
+		gym.EquipmentIDs = append(gym.EquipmentIDs, equipment.ID)
+	}
+
+	return rows.Err()
+}
+
+// Adapt swaps each exercise of an unsaved workout that cannot be performed in
+// the gym for its best substitute that can. Swapped entries keep their sets
+// and reps but drop their weight, which does not carry over between
+// exercises, and note what they replaced. Exercises with no substitute are
+// kept.
+func (m GymModel) Adapt(gymID int64, workout *Workout) error {
+	for i := range workout.Details {
+		detail := &workout.Details[i]
+
+		var performable bool
+		err := m.DB.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM exercises e WHERE e.id = ? AND `+performableInGym+`)`,
+			detail.ExerciseID, gymID,
+		).Scan(&performable)
+		if err != nil {
+			return err
+		}
+		if performable {
+			continue
+		}
+
+		substitutes, err := exerciseSubstitutes(m.DB, detail.ExerciseID, workout.UserID, gymID)
+		if err != nil {
+			return err
+		}
+		if len(substitutes) == 0 {
+			continue
+		}
+
+		var original string
+		if err := m.DB.QueryRow("SELECT name FROM exercises WHERE id = ?", detail.ExerciseID).Scan(&original); err != nil {
+			return err
+		}
+
+		detail.ExerciseID = substitutes[0].Exercise.ID
+		detail.Weight = nil
+		detail.EnteredWeight = nil
+		note := "In place of " + original
+		if detail.Notes != "" {
+			note = detail.Notes + "; " + note
+		}
+		detail.Notes = note
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// Bar is a barbell available to a user
+type Bar struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// PlatePair is a plate weight and how many pairs of it are available
+type PlatePair struct {
+	Weight float64 `json:"weight"`
+	Pairs  int     `json:"pairs"`
+}
+
+// Inventory is the equipment a user loads weights with. Every weight is in Unit.
+type Inventory struct {
+	UserID            int64       `json:"user_id"`
+	Unit              string      `json:"unit"`
+	Bars              []Bar       `json:"bars"`
+	Plates            []PlatePair `json:"plates"`
+	CollarWeight      float64     `json:"collar_weight"` // Per collar; one is used on each side
+	DumbbellIncrement float64     `json:"dumbbell_increment"`
+	IsDefault         bool        `json:"is_default"` // True until the user saves their own inventory
+}
+
+// Bounds on what an inventory may hold. They are far beyond any real gym and
+// keep the plate table LoadBar builds small.
+const (
+	maxPlatePairs  = 20
+	maxPlateKinds  = 20
+	maxPlateWeight = 100
+	maxBars        = 10
+	maxBarWeight   = 200
+)
+
+// DefaultInventory returns a typical commercial gym setup in unit
+func DefaultInventory(userID int64, unit string) *Inventory {
+	if unit == UnitPounds {
+		return &Inventory{
+			UserID: userID,
+			Unit:   UnitPounds,
+			Bars:   []Bar{{Name: "Olympic bar", Weight: 45}},
+			Plates: []PlatePair{
+				{Weight: 45, Pairs: 4}, {Weight: 35, Pairs: 1}, {Weight: 25, Pairs: 2},
+				{Weight: 10, Pairs: 2}, {Weight: 5, Pairs: 2}, {Weight: 2.5, Pairs: 1},
+			},
+			DumbbellIncrement: 5,
+			IsDefault:         true,
+		}
+	}
+	return &Inventory{
+		UserID: userID,
+		Unit:   UnitKilograms,
+		Bars:   []Bar{{Name: "Olympic bar", Weight: 20}},
+		Plates: []PlatePair{
+			{Weight: 25, Pairs: 4}, {Weight: 20, Pairs: 1}, {Weight: 15, Pairs: 1}, {Weight: 10, Pairs: 1},
+			{Weight: 5, Pairs: 1}, {Weight: 2.5, Pairs: 1}, {Weight: 1.25, Pairs: 1},
+		},
+		DumbbellIncrement: 2,
+		IsDefault:         true,
+	}
+}
+
+// Validate checks that the inventory can be used for loading
+func (inv *Inventory) Validate() error {
+	if inv.UserID < 1 || !ValidUnit(inv.Unit) || len(inv.Bars) == 0 || len(inv.Bars) > maxBars {
+		return ErrInvalidInput
+	}
+	if len(inv.Plates) > maxPlateKinds {
+		return ErrInvalidInput
+	}
+	if inv.CollarWeight < 0 || inv.DumbbellIncrement <= 0 {
+		return ErrInvalidInput
+	}
+	for _, bar := range inv.Bars {
+		if bar.Name == "" || bar.Weight < 0 || bar.Weight > maxBarWeight {
+			return ErrInvalidInput
+		}
+	}
+	// Plates are compared in the hundredths LoadBar works in, so two weights
+	// that round alike count as the same plate
+	seen := map[int]bool{}
+	for _, plate := range inv.Plates {
+		units := toPlateUnits(plate.Weight)
+		if units < 1 || plate.Weight > maxPlateWeight || plate.Pairs < 1 || plate.Pairs > maxPlatePairs || seen[units] {
+			return ErrInvalidInput
+		}
+		seen[units] = true
+	}
+	return nil
+}
+
+// InventoryModel wraps the database connection pool
+type InventoryModel struct {
+	DB *sql.DB
+}
+
+// Get retrieves the inventory of a user, or the default inventory in
+// defaultUnit when they have not saved one
+func (m InventoryModel) Get(userID int64, defaultUnit string) (*Inventory, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inv := &Inventory{UserID: userID}
+	err = tx.QueryRow(`
+		SELECT unit, collar_weight, dumbbell_increment
+		FROM equipment_inventories
+		WHERE user_id = ?`, userID,
+	).Scan(&inv.Unit, &inv.CollarWeight, &inv.DumbbellIncrement)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultInventory(userID, defaultUnit), nil
+		}
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT name, weight
+		FROM inventory_bars
+		WHERE user_id = ?
+		ORDER BY position, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var bar Bar
+		if err := rows.Scan(&bar.Name, &bar.Weight); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inv.Bars = append(inv.Bars, bar)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(`
+		SELECT weight, pairs
+		FROM inventory_plates
+		WHERE user_id = ?
+		ORDER BY weight DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var plate PlatePair
+		if err := rows.Scan(&plate.Weight, &plate.Pairs); err != nil {
+			return nil, err
+		}
+		inv.Plates = append(inv.Plates, plate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return inv, tx.Commit()
+}
+
+// Save replaces the inventory of a user
+func (m InventoryModel) Save(inv *Inventory) error {
+	if err := inv.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO equipment_inventories (user_id, unit, collar_weight, dumbbell_increment)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			unit = excluded.unit,
+			collar_weight = excluded.collar_weight,
+			dumbbell_increment = excluded.dumbbell_increment,
+			updated_at = CURRENT_TIMESTAMP`,
+		inv.UserID, inv.Unit, inv.CollarWeight, inv.DumbbellIncrement,
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM inventory_bars WHERE user_id = ?", inv.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM inventory_plates WHERE user_id = ?", inv.UserID); err != nil {
+		return err
+	}
+
+	for i, bar := range inv.Bars {
+		if _, err := tx.Exec(
+			"INSERT INTO inventory_bars (user_id, name, weight, position) VALUES (?, ?, ?, ?)",
+			inv.UserID, bar.Name, bar.Weight, i,
+		); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(inv.Plates, func(i, j int) bool { return inv.Plates[i].Weight > inv.Plates[j].Weight })
+	for _, plate := range inv.Plates {
+		if _, err := tx.Exec(
+			"INSERT INTO inventory_plates (user_id, weight, pairs) VALUES (?, ?, ?)",
+			inv.UserID, plate.Weight, plate.Pairs,
+		); err != nil {
+			return err
+		}
+	}
+
+	inv.IsDefault = false
+	return tx.Commit()
+}
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// Page sizes for list endpoints
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// ListQuery pages and orders a list. Sort names a field, descending when
+// prefixed with "-", and Cursor continues after the last item of a previous
+// page. A zero Limit returns every remaining item.
+type ListQuery struct {
+	Limit  int
+	Sort   string
+	Cursor string
+}
+
+// Page tells a client whether a list continues and where
+type Page struct {
+	NextCursor *string `json:"next_cursor"` // Nil on the last page
+	HasMore    bool    `json:"has_more"`
+}
+
+// sortKey is a field a list can be sorted by
+type sortKey[T any] struct {
+	column string              // SQL expression to order by
+	value  func(T) interface{} // The item's value of column, kept in cursors
+}
+
+// listSpec describes how a list of T can be sorted and paged. Items are
+// ordered by the sort column and then by ID, so every position is unique
+// and a cursor can resume right after the last item seen.
+type listSpec[T any] struct {
+	sorts       map[string]sortKey[T]
+	defaultSort string
+	idColumn    string
+	id          func(T) int64
+}
+
+// cursor is the position after which the next page starts, encoded as
+// base64url JSON so clients treat it as opaque
+type cursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    int64       `json:"i"`
+}
+
+// listClauses holds the SQL a list query adds to a select
+type listClauses struct {
+	sort    string
+	key     string
+	where   string // Empty, or a condition to AND onto the query
+	args    []interface{}
+	orderBy string // ORDER BY and, when paging, LIMIT
+	limit   int
+}
+
+// clauses resolves q against the spec. The query should append where, its
+// own args, then args, then orderBy.
+func (spec listSpec[T]) clauses(q ListQuery) (*listClauses, error) {
+	if q.Limit < 0 {
+		return nil, ErrInvalidInput
+	}
+
+	c := &listClauses{sort: q.Sort, limit: q.Limit}
+	if c.sort == "" {
+		c.sort = spec.defaultSort
+	}
+	c.key = strings.TrimPrefix(c.sort, "-")
+	key, ok := spec.sorts[c.key]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	direction, comparison := "ASC", ">"
+	if strings.HasPrefix(c.sort, "-") {
+		direction, comparison = "DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		if err != nil || after.Sort != c.sort {
+			return nil, ErrInvalidCursor
+		}
+		c.where = "(" + key.column + " " + comparison + " ? OR (" + key.column + " = ? AND " +
+			spec.idColumn + " " + comparison + " ?))"
+		c.args = []interface{}{after.Value, after.Value, after.ID}
+	}
+
+	c.orderBy = " ORDER BY " + key.column + " " + direction + ", " + spec.idColumn + " " + direction
+	if c.limit > 0 {
+		// One extra row tells whether another page follows
+		c.orderBy += " LIMIT ?"
+	}
+	return c, nil
+}
+
+// limitArgs returns the arguments of the LIMIT clause, if any
+func (c *listClauses) limitArgs() []interface{} {
+	if c.limit > 0 {
+		return []interface{}{c.limit + 1}
+	}
+	return nil
+}
+
+// page trims the extra row fetched by the query and describes what follows
+func (spec listSpec[T]) page(c *listClauses, items []T) ([]T, *Page) {
+	page := &Page{}
+	if c.limit == 0 || len(items) <= c.limit {
+		return items, page
+	}
+
+	items = items[:c.limit]
+	last := items[len(items)-1]
+	next := encodeCursor(cursor{Sort: c.sort, Value: spec.sorts[c.key].value(last), ID: spec.id(last)})
+	page.NextCursor = &next
+	page.HasMore = true
+	return items, page
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+
+	// Sort values are only ever strings or numbers
+	switch c.Value.(type) {
+	case string, float64:
+		return c, nil
+	}
+	return c, ErrInvalidCursor
+}
@@ -0,0 +1,231 @@
+package data
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Rolling windows of the acute:chronic workload model, in days
+const (
+	AcuteWindowDays   = 7
+	ChronicWindowDays = 28
+)
+
+// referenceSessionRPE is the effort a session's tonnage is scaled against, so
+// a session logged at this RPE, or without one, counts its tonnage as-is
+const referenceSessionRPE = 7
+
+// Load warning flags
+const (
+	FlagACWRSpike     = "acwr_spike"    // Acute load well above what the athlete is used to
+	FlagHighMonotony  = "high_monotony" // Little variation between hard and easy days
+	FlagUndertraining = "undertraining" // Acute load well below the chronic load
+)
+
+// LoadThresholds are the limits above which load metrics are flagged
+type LoadThresholds struct {
+	ACWRMax     float64 `json:"acwr_max"`
+	ACWRMin     float64 `json:"acwr_min"`
+	MonotonyMax float64 `json:"monotony_max"`
+}
+
+// DefaultLoadThresholds returns the thresholds commonly used in the sports science literature
+func DefaultLoadThresholds() LoadThresholds {
+	return LoadThresholds{ACWRMax: 1.5, ACWRMin: 0.8, MonotonyMax: 2}
+}
+
+// Validate checks that the thresholds are usable
+func (t LoadThresholds) Validate() error {
+	for _, v := range []float64{t.ACWRMax, t.ACWRMin, t.MonotonyMax} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return ErrInvalidInput
+		}
+	}
+	if t.ACWRMax <= 1 || t.ACWRMin <= 0 || t.ACWRMin >= 1 || t.MonotonyMax <= 0 {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// LoadDay holds the training load metrics of one day
+type LoadDay struct {
+	Date     string   `json:"date"`
+	Load     float64  `json:"load"`
+	Acute    float64  `json:"acute_load"`   // Average daily load over the last 7 days
+	Chronic  float64  `json:"chronic_load"` // Average daily load over the last 28 days
+	ACWR     *float64 `json:"acwr,omitempty"`
+	Monotony *float64 `json:"monotony,omitempty"` // Mean over standard deviation of the last 7 days
+	Strain   *float64 `json:"strain,omitempty"`   // Weekly load multiplied by monotony
+	Flags    []string `json:"flags"`
+}
+
+// LoadReport is the training load series of a date range with a deload recommendation
+type LoadReport struct {
+	Thresholds     LoadThresholds `json:"thresholds"`
+	Days           []LoadDay      `json:"days"`
+	DeloadAdvised  bool           `json:"deload_recommended"`
+	DeloadReasons  []string       `json:"deload_reasons"`
+	ChronicHistory bool           `json:"chronic_history"` // Whether 28 days of history back the latest ratio
+}
+
+// Load computes daily training load and the acute:chronic workload ratio,
+// monotony and strain for each day of the query range. The load of a workout
+// is its tonnage scaled by session RPE relative to an RPE of 7. The ratio is
+// only flagged once the user has 28 days of history, since a short history
+// understates the chronic load.
+func (m StatsModel) Load(q StatsQuery, thresholds LoadThresholds) (*LoadReport, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	if err := thresholds.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Load the days before the range that feed its first rolling windows
+	start := q.From.AddDate(0, 0, 1-ChronicWindowDays)
+	daily, err := m.dailyLoads(q.UserID, start, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var first sql.NullString
+	err = m.DB.QueryRow(`
+		SELECT MIN(date(date))
+		FROM workouts
+		WHERE user_id = ? AND status = 'completed'`, q.UserID,
+	).Scan(&first)
+	if err != nil {
+		return nil, err
+	}
+	var firstWorkout time.Time
+	if first.Valid {
+		firstWorkout, _ = time.Parse(DateFormat, first.String)
+	}
+
+	loads := make([]float64, 0, len(daily))
+	for day := start; !day.After(q.To); day = day.AddDate(0, 0, 1) {
+		loads = append(loads, daily[day.Format(DateFormat)])
+	}
+
+	report := &LoadReport{Thresholds: thresholds, Days: []LoadDay{}, DeloadReasons: []string{}}
+	var latest LoadDay
+	for i := ChronicWindowDays - 1; i < len(loads); i++ {
+		date := start.AddDate(0, 0, i)
+		acuteLoads := loads[i+1-AcuteWindowDays : i+1]
+		day := LoadDay{
+			Date:    date.Format(DateFormat),
+			Load:    loads[i],
+			Acute:   mean(acuteLoads),
+			Chronic: mean(loads[i+1-ChronicWindowDays : i+1]),
+			Flags:   []string{},
+		}
+
+		if day.Chronic > 0 {
+			acwr := day.Acute / day.Chronic
+			day.ACWR = &acwr
+		}
+		if sd := stdDev(acuteLoads); sd > 0 {
+			monotony := day.Acute / sd
+			strain := day.Acute * AcuteWindowDays * monotony
+			day.Monotony = &monotony
+			day.Strain = &strain
+		}
+
+		// A ratio is only meaningful once there are four weeks of history
+		chronicHistory := !firstWorkout.IsZero() && !firstWorkout.After(date.AddDate(0, 0, 1-ChronicWindowDays))
+		if day.ACWR != nil && chronicHistory {
+			if *day.ACWR > thresholds.ACWRMax {
+				day.Flags = append(day.Flags, FlagACWRSpike)
+			} else if *day.ACWR < thresholds.ACWRMin {
+				day.Flags = append(day.Flags, FlagUndertraining)
+			}
+		}
+		if day.Monotony != nil && *day.Monotony > thresholds.MonotonyMax {
+			day.Flags = append(day.Flags, FlagHighMonotony)
+		}
+
+		report.Days = append(report.Days, day)
+		report.ChronicHistory = chronicHistory
+		latest = day
+	}
+
+	// Recommend a deload on the state at the end of the range
+	for _, flag := range latest.Flags {
+		switch flag {
+		case FlagACWRSpike:
+			report.DeloadReasons = append(report.DeloadReasons,
+				"Acute load is more than "+formatRatio(thresholds.ACWRMax)+"x the chronic load; volume has ramped up too quickly.")
+		case FlagHighMonotony:
+			report.DeloadReasons = append(report.DeloadReasons,
+				"Training monotony is above "+formatRatio(thresholds.MonotonyMax)+"; add easier days or reduce volume.")
+		}
+	}
+	report.DeloadAdvised = len(report.DeloadReasons) > 0
+
+	return report, nil
+}
+
+// dailyLoads returns the training load of each day with completed workouts
+// between from and to, keyed by date
+func (m StatsModel) dailyLoads(userID int64, from, to time.Time) (map[string]float64, error) {
+	rows, err := m.DB.Query(`
+		SELECT w.date, w.session_rpe, COALESCE(SUM(we.sets * we.reps * COALESCE(we.weight, 0)), 0)
+		FROM workouts w
+		LEFT JOIN workout_exercises we ON we.workout_id = w.id
+		WHERE w.user_id = ? AND w.status = 'completed' AND date(w.date) BETWEEN ? AND ?
+		GROUP BY w.id`,
+		userID, from.Format(DateFormat), to.Format(DateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	daily := map[string]float64{}
+	for rows.Next() {
+		var date time.Time
+		var sessionRPE sql.NullFloat64
+		var tonnage float64
+		if err := rows.Scan(&date, &sessionRPE, &tonnage); err != nil {
+			return nil, err
+		}
+
+		load := tonnage
+		if sessionRPE.Valid {
+			load *= sessionRPE.Float64 / referenceSessionRPE
+		}
+		daily[CivilDate(date).Format(DateFormat)] += load
+	}
+
+	return daily, rows.Err()
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stdDev returns the population standard deviation of values
+func stdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	avg := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - avg) * (v - avg)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func formatRatio(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
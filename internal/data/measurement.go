@@ -0,0 +1,376 @@
+package data
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// Measurement metrics
+const (
+	MetricBodyWeight = "body_weight"
+	MetricBodyFat    = "body_fat"
+	MetricNeck       = "neck"
+	MetricChest      = "chest"
+	MetricWaist      = "waist"
+	MetricHips       = "hips"
+	MetricArms       = "arms"
+	MetricThighs     = "thighs"
+	MetricCalves     = "calves"
+)
+
+// Units of the non-weight metrics
+const (
+	UnitPercent     = "percent"
+	UnitCentimeters = "cm"
+	UnitInches      = "in"
+
+	CentimetersPerInch = 2.54
+)
+
+// MetricUnit returns the canonical unit a metric is stored in, or "" for an
+// unknown metric
+func MetricUnit(metric string) string {
+	switch metric {
+	case MetricBodyWeight:
+		return UnitKilograms
+	case MetricBodyFat:
+		return UnitPercent
+	case MetricNeck, MetricChest, MetricWaist, MetricHips, MetricArms, MetricThighs, MetricCalves:
+		return UnitCentimeters
+	}
+	return ""
+}
+
+// ValidMetric reports whether metric is a known measurement metric
+func ValidMetric(metric string) bool {
+	return MetricUnit(metric) != ""
+}
+
+// ToMetricUnit converts a value entered in unit to the canonical unit of
+// metric, returning ErrInvalidInput when unit does not apply to the metric
+func ToMetricUnit(metric string, value float64, unit string) (float64, error) {
+	canonical := MetricUnit(metric)
+	switch {
+	case canonical == "":
+		return 0, ErrInvalidInput
+	case unit == "" || unit == canonical:
+		return value, nil
+	case canonical == UnitKilograms && unit == UnitPounds:
+		return ToKilograms(value, unit), nil
+	case canonical == UnitCentimeters && unit == UnitInches:
+		return value * CentimetersPerInch, nil
+	}
+	return 0, ErrInvalidInput
+}
+
+// Measurement is a single dated body measurement
+type Measurement struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Metric     string    `json:"metric"`
+	Value      float64   `json:"value"`
+	Unit       string    `json:"unit"`
+	MeasuredOn time.Time `json:"measured_on"`
+	Notes      string    `json:"notes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// MeasurementFilter narrows the measurements returned by GetAll. From and To
+// are inclusive calendar dates and are ignored when zero.
+type MeasurementFilter struct {
+	Metric string
+	From   time.Time
+	To     time.Time
+}
+
+// MeasurementModel wraps the database connection pool
+type MeasurementModel struct {
+	DB *sql.DB
+}
+
+// GetByID retrieves a single measurement by its ID
+func (m MeasurementModel) GetByID(id int64) (*Measurement, error) {
+	if id < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	measurement := &Measurement{}
+	var notes sql.NullString
+	err := m.DB.QueryRow(`
+		SELECT id, user_id, metric, value, measured_on, notes, created_at, updated_at
+		FROM measurements
+		WHERE id = ?`, id,
+	).Scan(
+		&measurement.ID,
+		&measurement.UserID,
+		&measurement.Metric,
+		&measurement.Value,
+		&measurement.MeasuredOn,
+		&notes,
+		&measurement.CreatedAt,
+		&measurement.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	measurement.Notes = notes.String
+	measurement.Unit = MetricUnit(measurement.Metric)
+	measurement.MeasuredOn = CivilDate(measurement.MeasuredOn)
+	return measurement, nil
+}
+
+// GetAll retrieves the measurements of a user, oldest first
+func (m MeasurementModel) GetAll(userID int64, filter MeasurementFilter) ([]*Measurement, error) {
+	return userMeasurements(m.DB, userID, filter)
+}
+
+func userMeasurements(q dbtx, userID int64, filter MeasurementFilter) ([]*Measurement, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+	if filter.Metric != "" && !ValidMetric(filter.Metric) {
+		return nil, ErrInvalidInput
+	}
+
+	query := `
+		SELECT id, user_id, metric, value, measured_on, notes, created_at, updated_at
+		FROM measurements
+		WHERE user_id = ?`
+	args := []interface{}{userID}
+
+	if filter.Metric != "" {
+		query += " AND metric = ?"
+		args = append(args, filter.Metric)
+	}
+	if !filter.From.IsZero() {
+		query += " AND date(measured_on) >= ?"
+		args = append(args, filter.From.Format(DateFormat))
+	}
+	if !filter.To.IsZero() {
+		query += " AND date(measured_on) <= ?"
+		args = append(args, filter.To.Format(DateFormat))
+	}
+	query += `
+		ORDER BY measured_on, id`
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var measurements []*Measurement
+
+	for rows.Next() {
+		measurement := &Measurement{}
+		var notes sql.NullString
+		err := rows.Scan(
+			&measurement.ID,
+			&measurement.UserID,
+			&measurement.Metric,
+			&measurement.Value,
+			&measurement.MeasuredOn,
+			&notes,
+			&measurement.CreatedAt,
+			&measurement.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		measurement.Notes = notes.String
+		measurement.Unit = MetricUnit(measurement.Metric)
+		measurement.MeasuredOn = CivilDate(measurement.MeasuredOn)
+		measurements = append(measurements, measurement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return measurements, nil
+}
+
+// Create inserts a new measurement. Value must already be in the metric's
+// canonical unit.
+func (m MeasurementModel) Create(measurement *Measurement) error {
+	if err := validateMeasurement(measurement); err != nil {
+		return err
+	}
+
+	result, err := m.DB.Exec(`
+		INSERT INTO measurements (user_id, metric, value, measured_on, notes)
+		VALUES (?, ?, ?, ?, ?)`,
+		measurement.UserID,
+		measurement.Metric,
+		measurement.Value,
+		measurement.MeasuredOn.Format(DateFormat),
+		measurement.Notes,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	measurement.ID = id
+	measurement.Unit = MetricUnit(measurement.Metric)
+	return nil
+}
+
+// Update modifies an existing measurement
+func (m MeasurementModel) Update(measurement *Measurement) error {
+	if measurement.ID < 1 {
+		return ErrInvalidInput
+	}
+	if err := validateMeasurement(measurement); err != nil {
+		return err
+	}
+
+	result, err := m.DB.Exec(`
+		UPDATE measurements
+		SET metric = ?, value = ?, measured_on = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		measurement.Metric,
+		measurement.Value,
+		measurement.MeasuredOn.Format(DateFormat),
+		measurement.Notes,
+		measurement.ID,
+		measurement.UserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	measurement.Unit = MetricUnit(measurement.Metric)
+	return nil
+}
+
+// Delete removes a measurement belonging to a user
+func (m MeasurementModel) Delete(userID, id int64) error {
+	if userID < 1 || id < 1 {
+		return ErrInvalidInput
+	}
+
+	result, err := m.DB.Exec("DELETE FROM measurements WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func validateMeasurement(measurement *Measurement) error {
+	if measurement.UserID < 1 || !ValidMetric(measurement.Metric) || measurement.MeasuredOn.IsZero() {
+		return ErrInvalidInput
+	}
+	if measurement.Value <= 0 || (measurement.Metric == MetricBodyFat && measurement.Value >= 100) {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// TrendPoint is the value of a metric on one day alongside its moving average
+type TrendPoint struct {
+	Date          string  `json:"date"`
+	Value         float64 `json:"value"`
+	MovingAverage float64 `json:"moving_average"`
+	Samples       int     `json:"samples"` // Measurements averaged into the moving average
+}
+
+// MeasurementTrend computes a trailing moving average over measurements of a
+// single metric ordered oldest first. Measurements taken on the same day are
+// averaged into one value, and the moving average of each day covers every
+// measurement taken in the window days ending on it.
+func MeasurementTrend(measurements []*Measurement, window int) []TrendPoint {
+	type day struct {
+		date  time.Time
+		sum   float64
+		count int
+	}
+	var days []day
+	for _, m := range measurements {
+		date := CivilDate(m.MeasuredOn)
+		if n := len(days); n > 0 && days[n-1].date.Equal(date) {
+			days[n-1].sum += m.Value
+			days[n-1].count++
+			continue
+		}
+		days = append(days, day{date: date, sum: m.Value, count: 1})
+	}
+
+	points := make([]TrendPoint, 0, len(days))
+	var windowSum float64
+	var windowCount, start int
+
+	for _, d := range days {
+		windowSum += d.sum
+		windowCount += d.count
+		for !days[start].date.After(d.date.AddDate(0, 0, -window)) {
+			windowSum -= days[start].sum
+			windowCount -= days[start].count
+			start++
+		}
+
+		points = append(points, TrendPoint{
+			Date:          d.date.Format(DateFormat),
+			Value:         d.sum / float64(d.count),
+			MovingAverage: windowSum / float64(windowCount),
+			Samples:       windowCount,
+		})
+	}
+
+	return points
+}
+
+// NearestMeasurement returns the measurement taken closest to date from a
+// list ordered oldest first, preferring the earlier one on ties, or nil when
+// the list is empty
+func NearestMeasurement(measurements []*Measurement, date time.Time) *Measurement {
+	if len(measurements) == 0 {
+		return nil
+	}
+
+	date = CivilDate(date)
+	i := sort.Search(len(measurements), func(i int) bool {
+		return !CivilDate(measurements[i].MeasuredOn).Before(date)
+	})
+
+	switch {
+	case i == 0:
+		return measurements[0]
+	case i == len(measurements):
+		return measurements[i-1]
+	}
+
+	before, after := measurements[i-1], measurements[i]
+	if date.Sub(CivilDate(before.MeasuredOn)) <= CivilDate(after.MeasuredOn).Sub(date) {
+		return before
+	}
+	return after
+}
@@ -0,0 +1,165 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Kinds of exercise media
+const (
+	MediaImage = "image"
+	MediaVideo = "video"
+)
+
+// Media is an image or video attached to an exercise
+type Media struct {
+	ID           int64     `json:"id"`
+	ExerciseID   int64     `json:"exercise_id"`
+	UserID       int64     `json:"user_id"` // Uploader
+	Kind         string    `json:"kind"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	Width        *int      `json:"width,omitempty"`  // Images only
+	Height       *int      `json:"height,omitempty"` // Images only
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"` // Empty when there is no thumbnail
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Signed download links, set when responding
+	URL          string     `json:"url,omitempty"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// MediaModel wraps the database connection pool
+type MediaModel struct {
+	DB *sql.DB
+}
+
+const mediaColumns = `
+		id, exercise_id, user_id, kind, content_type, size_bytes, width, height,
+		storage_key, thumbnail_key, created_at`
+
+func scanMedia(row interface{ Scan(...interface{}) error }) (*Media, error) {
+	media := &Media{}
+	var width, height sql.NullInt64
+	var thumbnailKey sql.NullString
+
+	err := row.Scan(
+		&media.ID,
+		&media.ExerciseID,
+		&media.UserID,
+		&media.Kind,
+		&media.ContentType,
+		&media.SizeBytes,
+		&width,
+		&height,
+		&media.StorageKey,
+		&thumbnailKey,
+		&media.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if width.Valid && height.Valid {
+		w, h := int(width.Int64), int(height.Int64)
+		media.Width, media.Height = &w, &h
+	}
+	media.ThumbnailKey = thumbnailKey.String
+	return media, nil
+}
+
+// GetByID retrieves a single media item
+func (m MediaModel) GetByID(id int64) (*Media, error) {
+	if id < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	media, err := scanMedia(m.DB.QueryRow("SELECT"+mediaColumns+" FROM exercise_media WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+	return media, nil
+}
+
+// GetByExercise retrieves the media of an exercise, oldest first
+func (m MediaModel) GetByExercise(exerciseID int64) ([]*Media, error) {
+	rows, err := m.DB.Query(`
+		SELECT`+mediaColumns+`
+		FROM exercise_media
+		WHERE exercise_id = ?
+		ORDER BY created_at, id`, exerciseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*Media{}
+
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, media)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Create records media already written to the blob store
+func (m MediaModel) Create(media *Media) error {
+	if media.ExerciseID < 1 || media.UserID < 1 || media.StorageKey == "" ||
+		(media.Kind != MediaImage && media.Kind != MediaVideo) {
+		return ErrInvalidInput
+	}
+
+	result, err := m.DB.Exec(`
+		INSERT INTO exercise_media (
+			exercise_id, user_id, kind, content_type, size_bytes, width, height, storage_key, thumbnail_key
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		media.ExerciseID, media.UserID, media.Kind, media.ContentType, media.SizeBytes,
+		media.Width, media.Height, media.StorageKey, nullString(media.ThumbnailKey),
+	)
+	if err != nil {
+		return err
+	}
+
+	media.ID, err = result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	media.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// Delete removes the record of a media item. Its blobs are left to the caller.
+func (m MediaModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrInvalidInput
+	}
+
+	result, err := m.DB.Exec("DELETE FROM exercise_media WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -1,8 +1,18 @@
 package data
 
 type Models struct {
-	Workouts  *WorkoutModel
-	BodyParts *BodyPartModel
-	Exercises *ExerciseModel
-	Users     *UserModel
+	Workouts         *WorkoutModel
+	WorkoutExercises *WorkoutExerciseModel
+	BodyParts        *BodyPartModel
+	Exercises        *ExerciseModel
+	Muscles          *MuscleModel
+	Equipment        *EquipmentModel
+	Media            *MediaModel
+	Gyms             *GymModel
+	Users            *UserModel
+	PersonalRecords  *PersonalRecordModel
+	Stats            *StatsModel
+	Measurements     *MeasurementModel
+	Inventories      *InventoryModel
+	Goals            *GoalModel
 }
@@ -0,0 +1,218 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Roles a muscle plays in an exercise
+const (
+	RolePrimary    = "primary"
+	RoleSecondary  = "secondary"
+	RoleStabilizer = "stabilizer"
+)
+
+// ValidMuscleRole reports whether role is a known muscle role
+func ValidMuscleRole(role string) bool {
+	switch role {
+	case RolePrimary, RoleSecondary, RoleStabilizer:
+		return true
+	}
+	return false
+}
+
+// DefaultContribution is the share of each set credited to a muscle with the
+// given role when the exercise does not set one. Stabilizers work but are not
+// credited with volume.
+func DefaultContribution(role string) float64 {
+	switch role {
+	case RolePrimary:
+		return 1
+	case RoleSecondary:
+		return 0.5
+	}
+	return 0
+}
+
+// Muscle represents a muscle record from the database
+type Muscle struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	BodyPartID int64     `json:"body_part_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ExerciseMuscle is a muscle worked by an exercise. Contribution is the share
+// of each set credited to the muscle; in responses it is always filled in,
+// defaulting by role.
+type ExerciseMuscle struct {
+	MuscleID     int64    `json:"muscle_id"`
+	Name         string   `json:"name,omitempty"`
+	BodyPartID   int64    `json:"body_part_id,omitempty"`
+	Role         string   `json:"role"`
+	Contribution *float64 `json:"contribution"`
+}
+
+// MuscleModel wraps the database connection pool
+type MuscleModel struct {
+	DB *sql.DB
+}
+
+// GetAll retrieves all muscles, optionally only those of one body part
+func (m MuscleModel) GetAll(bodyPartID int64) ([]*Muscle, error) {
+	query := `
+		SELECT id, name, body_part_id, created_at, updated_at
+		FROM muscles`
+	var args []interface{}
+	if bodyPartID > 0 {
+		query += " WHERE body_part_id = ?"
+		args = append(args, bodyPartID)
+	}
+	query += " ORDER BY name"
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var muscles []*Muscle
+
+	for rows.Next() {
+		muscle := &Muscle{}
+		err := rows.Scan(
+			&muscle.ID,
+			&muscle.Name,
+			&muscle.BodyPartID,
+			&muscle.CreatedAt,
+			&muscle.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		muscles = append(muscles, muscle)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return muscles, nil
+}
+
+// ForExercises retrieves the muscles worked by each of the given exercises,
+// primary muscles first
+func (m MuscleModel) ForExercises(exerciseIDs []int64) (map[int64][]ExerciseMuscle, error) {
+	return exerciseMuscles(m.DB, exerciseIDs)
+}
+
+func exerciseMuscles(q dbtx, exerciseIDs []int64) (map[int64][]ExerciseMuscle, error) {
+	result := map[int64][]ExerciseMuscle{}
+	if len(exerciseIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders, args := inClause(exerciseIDs)
+	rows, err := q.Query(`
+		SELECT em.exercise_id, mu.id, mu.name, mu.body_part_id, em.role, em.contribution
+		FROM exercise_muscles em
+		JOIN muscles mu ON mu.id = em.muscle_id
+		WHERE em.exercise_id IN (`+placeholders+`)
+		ORDER BY em.exercise_id,
+		         CASE em.role WHEN 'primary' THEN 0 WHEN 'secondary' THEN 1 ELSE 2 END,
+		         mu.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exerciseID int64
+		var muscle ExerciseMuscle
+		err := rows.Scan(&exerciseID, &muscle.MuscleID, &muscle.Name, &muscle.BodyPartID, &muscle.Role, &muscle.Contribution)
+		if err != nil {
+			return nil, err
+		}
+		if muscle.Contribution == nil {
+			contribution := DefaultContribution(muscle.Role)
+			muscle.Contribution = &contribution
+		}
+		result[exerciseID] = append(result[exerciseID], muscle)
+	}
+
+	return result, rows.Err()
+}
+
+// SetForExercise replaces the muscles worked by an exercise within tx. Each
+// muscle must exist and appear once, contributions must lie between 0 and 1,
+// and at least one muscle must be primary. With no muscles given, the first
+// muscle of bodyPartID becomes the exercise's primary muscle.
+func (m MuscleModel) SetForExercise(tx *sql.Tx, exerciseID, bodyPartID int64, muscles []ExerciseMuscle) error {
+	return setExerciseMuscles(tx, exerciseID, bodyPartID, muscles)
+}
+
+func setExerciseMuscles(tx dbtx, exerciseID, bodyPartID int64, muscles []ExerciseMuscle) error {
+	if len(muscles) > 0 {
+		if err := validateExerciseMuscles(tx, muscles); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("DELETE FROM exercise_muscles WHERE exercise_id = ?", exerciseID)
+	if err != nil {
+		return err
+	}
+
+	if len(muscles) == 0 {
+		_, err := tx.Exec(`
+			INSERT INTO exercise_muscles (exercise_id, muscle_id, role)
+			SELECT ?, MIN(id), 'primary' FROM muscles WHERE body_part_id = ? HAVING COUNT(*) > 0`,
+			exerciseID, bodyPartID,
+		)
+		return err
+	}
+
+	for _, muscle := range muscles {
+		_, err := tx.Exec(`
+			INSERT INTO exercise_muscles (exercise_id, muscle_id, role, contribution)
+			VALUES (?, ?, ?, ?)`,
+			exerciseID, muscle.MuscleID, muscle.Role, muscle.Contribution,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateExerciseMuscles(q dbtx, muscles []ExerciseMuscle) error {
+	seen := map[int64]bool{}
+	hasPrimary := false
+
+	for _, muscle := range muscles {
+		if !ValidMuscleRole(muscle.Role) || seen[muscle.MuscleID] {
+			return ErrInvalidInput
+		}
+		if muscle.Contribution != nil && (*muscle.Contribution < 0 || *muscle.Contribution > 1) {
+			return ErrInvalidInput
+		}
+		seen[muscle.MuscleID] = true
+		hasPrimary = hasPrimary || muscle.Role == RolePrimary
+
+		var exists bool
+		err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM muscles WHERE id = ?)", muscle.MuscleID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrInvalidInput
+		}
+	}
+
+	if !hasPrimary {
+		return ErrInvalidInput
+	}
+	return nil
+}
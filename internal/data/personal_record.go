@@ -0,0 +1,368 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Personal record types
+const (
+	RecordMaxWeight     = "max_weight"
+	RecordRepsAtWeight  = "reps_at_weight"
+	RecordEpleyOneRM    = "e1rm_epley"
+	RecordBrzyckiOneRM  = "e1rm_brzycki"
+	RecordSessionVolume = "session_volume"
+)
+
+// PersonalRecord represents a record set by a user on an exercise
+type PersonalRecord struct {
+	ID                int64     `json:"id"`
+	UserID            int64     `json:"user_id"`
+	ExerciseID        int64     `json:"exercise_id"`
+	ExerciseName      string    `json:"exercise_name,omitempty"`
+	RecordType        string    `json:"record_type"`
+	Value             float64   `json:"value"`
+	PreviousValue     *float64  `json:"previous_value,omitempty"`
+	Weight            *float64  `json:"weight,omitempty"`
+	Reps              *int      `json:"reps,omitempty"`
+	WorkoutID         int64     `json:"workout_id"`
+	WorkoutExerciseID *int64    `json:"workout_exercise_id,omitempty"`
+	AchievedOn        time.Time `json:"achieved_on"`
+}
+
+// PersonalRecordModel wraps the database connection pool
+type PersonalRecordModel struct {
+	DB *sql.DB
+}
+
+// RecordFilter narrows the records returned by GetCurrent and GetHistory
+type RecordFilter struct {
+	ExerciseID int64
+	RecordType string
+}
+
+// GetCurrent retrieves the standing records for a user
+func (m PersonalRecordModel) GetCurrent(userID int64, filter RecordFilter) ([]*PersonalRecord, error) {
+	return m.query(userID, filter, `
+		AND NOT EXISTS (
+			SELECT 1 FROM personal_records newer
+			WHERE newer.user_id = pr.user_id
+			  AND newer.exercise_id = pr.exercise_id
+			  AND newer.record_type = pr.record_type
+			  AND (pr.record_type != 'reps_at_weight' OR newer.weight = pr.weight)
+			  AND newer.value > pr.value
+		)`)
+}
+
+// GetHistory retrieves every record a user has set, including ones since broken
+func (m PersonalRecordModel) GetHistory(userID int64, filter RecordFilter) ([]*PersonalRecord, error) {
+	return m.query(userID, filter, "")
+}
+
+func (m PersonalRecordModel) query(userID int64, filter RecordFilter, extra string) ([]*PersonalRecord, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+	if filter.RecordType != "" && !validRecordType(filter.RecordType) {
+		return nil, ErrInvalidInput
+	}
+
+	query := `
+		SELECT pr.id, pr.user_id, pr.exercise_id, e.name, pr.record_type, pr.value,
+		       pr.previous_value, pr.weight, pr.reps, pr.workout_id,
+		       pr.workout_exercise_id, pr.achieved_on
+		FROM personal_records pr
+		JOIN exercises e ON pr.exercise_id = e.id
+		WHERE pr.user_id = ?`
+	args := []interface{}{userID}
+
+	if filter.ExerciseID > 0 {
+		query += " AND pr.exercise_id = ?"
+		args = append(args, filter.ExerciseID)
+	}
+	if filter.RecordType != "" {
+		query += " AND pr.record_type = ?"
+		args = append(args, filter.RecordType)
+	}
+	query += extra + `
+		ORDER BY pr.achieved_on DESC, pr.id DESC`
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*PersonalRecord
+
+	for rows.Next() {
+		record := &PersonalRecord{}
+		err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.ExerciseID,
+			&record.ExerciseName,
+			&record.RecordType,
+			&record.Value,
+			&record.PreviousValue,
+			&record.Weight,
+			&record.Reps,
+			&record.WorkoutID,
+			&record.WorkoutExerciseID,
+			&record.AchievedOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func validRecordType(recordType string) bool {
+	switch recordType {
+	case RecordMaxWeight, RecordRepsAtWeight, RecordEpleyOneRM, RecordBrzyckiOneRM, RecordSessionVolume:
+		return true
+	}
+	return false
+}
+
+// refreshPersonalRecords rebuilds the record history of a user for each of the
+// given exercises from their full workout history. It is run inside the
+// transaction of any write that touches workout exercises so records stay
+// correct when workouts are edited or deleted.
+func refreshPersonalRecords(q dbtx, userID int64, exerciseIDs []int64) error {
+	for _, exerciseID := range exerciseIDs {
+		if _, err := q.Exec(
+			"DELETE FROM personal_records WHERE user_id = ? AND exercise_id = ?",
+			userID, exerciseID,
+		); err != nil {
+			return err
+		}
+
+		// The load of assisted movements is assistance, so heavier is not better
+		var trackingType string
+		err := q.QueryRow("SELECT tracking_type FROM exercises WHERE id = ?", exerciseID).Scan(&trackingType)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if trackingType == TrackingAssistedBodyweight {
+			continue
+		}
+
+		history, err := exerciseHistory(q, userID, exerciseID)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range detectRecords(userID, exerciseID, history) {
+			if _, err := q.Exec(`
+				INSERT INTO personal_records (
+					user_id, exercise_id, record_type, value, previous_value,
+					weight, reps, workout_id, workout_exercise_id, achieved_on
+				)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				record.UserID, record.ExerciseID, record.RecordType, record.Value,
+				record.PreviousValue, record.Weight, record.Reps, record.WorkoutID,
+				record.WorkoutExerciseID, record.AchievedOn,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// detectRecords walks a history ordered oldest first, session by session, and
+// returns a record each time a session beats the best value seen before it
+func detectRecords(userID, exerciseID int64, history []*ExercisePerformance) []*PersonalRecord {
+	var records []*PersonalRecord
+	best := map[string]float64{}
+	bestRepsAt := map[float64]float64{}
+
+	newRecord := func(p *ExercisePerformance, recordType string, value, previous float64) *PersonalRecord {
+		record := &PersonalRecord{
+			UserID:     userID,
+			ExerciseID: exerciseID,
+			RecordType: recordType,
+			Value:      value,
+			WorkoutID:  p.WorkoutID,
+			AchievedOn: p.WorkoutDate,
+		}
+		if previous > 0 {
+			record.PreviousValue = &previous
+		}
+		return record
+	}
+	withSet := func(record *PersonalRecord, p *ExercisePerformance) *PersonalRecord {
+		weight, reps, id := weightOf(p.Weight), p.Reps, p.ID
+		record.Weight = &weight
+		record.Reps = &reps
+		record.WorkoutExerciseID = &id
+		return record
+	}
+
+	for start := 0; start < len(history); {
+		end := start
+		for end < len(history) && history[end].WorkoutID == history[start].WorkoutID {
+			end++
+		}
+		session := history[start:end]
+		start = end
+
+		// Best of each set-level metric within the session
+		bestSet := map[string]*ExercisePerformance{}
+		sessionBest := map[string]float64{}
+		repsAt := map[float64]*ExercisePerformance{}
+		var volume float64
+
+		for _, p := range session {
+			weight := weightOf(p.Weight)
+			if weight <= 0 || p.Reps < 1 {
+				continue
+			}
+			volume += Volume(p.WorkoutExercise)
+
+			for recordType, value := range map[string]float64{
+				RecordMaxWeight:    weight,
+				RecordEpleyOneRM:   EpleyOneRepMax(weight, p.Reps),
+				RecordBrzyckiOneRM: BrzyckiOneRepMax(weight, p.Reps),
+			} {
+				if value > sessionBest[recordType] {
+					sessionBest[recordType] = value
+					bestSet[recordType] = p
+				}
+			}
+			if current, ok := repsAt[weight]; !ok || p.Reps > current.Reps {
+				repsAt[weight] = p
+			}
+		}
+
+		for _, recordType := range []string{RecordMaxWeight, RecordEpleyOneRM, RecordBrzyckiOneRM} {
+			if value := sessionBest[recordType]; value > best[recordType] {
+				records = append(records, withSet(newRecord(bestSet[recordType], recordType, value, best[recordType]), bestSet[recordType]))
+				best[recordType] = value
+			}
+		}
+		for _, p := range session {
+			weight := weightOf(p.Weight)
+			if repsAt[weight] != p {
+				continue
+			}
+			if reps := float64(p.Reps); reps > bestRepsAt[weight] {
+				records = append(records, withSet(newRecord(p, RecordRepsAtWeight, reps, bestRepsAt[weight]), p))
+				bestRepsAt[weight] = reps
+			}
+		}
+		if volume > best[RecordSessionVolume] {
+			records = append(records, newRecord(session[0], RecordSessionVolume, volume, best[RecordSessionVolume]))
+			best[RecordSessionVolume] = volume
+		}
+	}
+
+	return records
+}
+
+// exerciseHistory retrieves every entry of an exercise in a user's completed
+// workouts, oldest first
+func exerciseHistory(q dbtx, userID, exerciseID int64) ([]*ExercisePerformance, error) {
+	rows, err := q.Query(`
+		SELECT
+			we.id, we.workout_id, we.exercise_id, we.sets, we.reps,
+			we.weight, we.weight_input, we.weight_unit, we.rpe, we.notes,
+			we.duration_seconds, we.distance_meters, w.date
+		FROM workout_exercises we
+		JOIN workouts w ON we.workout_id = w.id
+		WHERE w.user_id = ? AND we.exercise_id = ? AND w.status = 'completed'
+		ORDER BY w.date, w.id, we.id`, userID, exerciseID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*ExercisePerformance
+
+	for rows.Next() {
+		p := &ExercisePerformance{}
+		var notes sql.NullString
+		err := rows.Scan(
+			&p.ID,
+			&p.WorkoutID,
+			&p.ExerciseID,
+			&p.Sets,
+			&p.Reps,
+			&p.Weight,
+			&p.EnteredWeight,
+			&p.EnteredUnit,
+			&p.RPE,
+			&notes,
+			&p.DurationSeconds,
+			&p.DistanceMeters,
+			&p.WorkoutDate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		p.Notes = notes.String
+		p.Unit = UnitKilograms
+		derivePace(&p.WorkoutExercise)
+		history = append(history, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// attachRecords marks the details of a workout with the records they set
+func attachRecords(q dbtx, workout *Workout) error {
+	rows, err := q.Query(`
+		SELECT workout_exercise_id, record_type
+		FROM personal_records
+		WHERE workout_id = ? AND workout_exercise_id IS NOT NULL
+		ORDER BY id`, workout.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	records := map[int64][]string{}
+	for rows.Next() {
+		var workoutExerciseID int64
+		var recordType string
+		if err := rows.Scan(&workoutExerciseID, &recordType); err != nil {
+			return err
+		}
+		records[workoutExerciseID] = append(records[workoutExerciseID], recordType)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range workout.Details {
+		workout.Details[i].Records = records[workout.Details[i].ID]
+	}
+	return nil
+}
+
+// distinctExerciseIDs returns the exercise IDs referenced by a set of workout details
+func distinctExerciseIDs(details []WorkoutExercise) []int64 {
+	seen := map[int64]bool{}
+	var ids []int64
+	for _, detail := range details {
+		if !seen[detail.ExerciseID] {
+			seen[detail.ExerciseID] = true
+			ids = append(ids, detail.ExerciseID)
+		}
+	}
+	return ids
+}
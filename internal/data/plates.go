@@ -0,0 +1,200 @@
+package data
+
+import (
+	"math"
+	"sort"
+)
+
+// PlateCount is the number of plates of one weight loaded on each side of a bar
+type PlateCount struct {
+	Weight float64 `json:"weight"`
+	Count  int     `json:"count"`
+}
+
+// PlateLoad is how to load a bar for a target weight
+type PlateLoad struct {
+	Target       float64      `json:"target"`
+	Achieved     float64      `json:"achieved"`
+	Exact        bool         `json:"exact"`
+	Unit         string       `json:"unit"`
+	Bar          Bar          `json:"bar"`
+	CollarWeight float64      `json:"collar_weight"`
+	PerSide      []PlateCount `json:"per_side"`
+}
+
+// plateScale converts weights to integer hundredths so plate sums are exact
+const plateScale = 100
+
+// maxPerSide caps the weight LoadBar considers on each side of a bar, in the
+// inventory's unit, whatever the target
+const maxPerSide = 1000
+
+// MaxPlateTarget bounds the target weight LoadBar is asked for, in either unit.
+// It is well past anything a bar can hold and keeps targets in plate units
+// from overflowing.
+const MaxPlateTarget = 10000
+
+func toPlateUnits(weight float64) int {
+	return int(math.Round(weight * plateScale))
+}
+
+// LoadBar works out the plates to put on each side of bar to reach target, or
+// the nearest achievable load when target cannot be hit exactly. Ties between
+// a lighter and a heavier load go to the lighter one, and among loads of the
+// same weight the one using the fewest plates is chosen.
+func (inv *Inventory) LoadBar(target float64, bar Bar) *PlateLoad {
+	load := &PlateLoad{
+		Target:       target,
+		Unit:         inv.Unit,
+		Bar:          bar,
+		CollarWeight: inv.CollarWeight,
+		PerSide:      []PlateCount{},
+	}
+
+	empty := bar.Weight + 2*inv.CollarWeight
+	plates := append([]PlatePair(nil), inv.Plates...)
+	sort.Slice(plates, func(i, j int) bool { return plates[i].Weight > plates[j].Weight })
+
+	// Work in multiples of the largest step every plate is a multiple of, and
+	// only up to one plate past the target, to keep the table small
+	step, heaviest := 0, 0
+	for _, plate := range plates {
+		step = gcd(step, toPlateUnits(plate.Weight))
+		heaviest = max(heaviest, toPlateUnits(plate.Weight))
+	}
+	if step == 0 {
+		step = 1
+	}
+	want := float64(toPlateUnits(target-empty)) / 2 / float64(step)
+
+	maxSide := 0
+	for _, plate := range plates {
+		maxSide += toPlateUnits(plate.Weight) / step * plate.Pairs
+	}
+	maxSide = min(maxSide, max(0, int(math.Ceil(want))+heaviest/step), maxPerSide*plateScale/step)
+
+	// fewest[i][s] is the fewest plates needed to make s per side from the first
+	// i plate weights, and used[i][s] how many of plate i-1 that takes
+	const unreachable = math.MaxInt32
+	fewest := make([][]int32, len(plates)+1)
+	used := make([][]uint8, len(plates)+1)
+	for i := range fewest {
+		fewest[i] = make([]int32, maxSide+1)
+		used[i] = make([]uint8, maxSide+1)
+	}
+	for s := 1; s <= maxSide; s++ {
+		fewest[0][s] = unreachable
+	}
+	for i, plate := range plates {
+		w := toPlateUnits(plate.Weight) / step
+		for s := 0; s <= maxSide; s++ {
+			fewest[i+1][s] = unreachable
+			for k := 0; k <= plate.Pairs && k*w <= s; k++ {
+				if prev := fewest[i][s-k*w]; prev != unreachable && prev+int32(k) < fewest[i+1][s] {
+					fewest[i+1][s] = prev + int32(k)
+					used[i+1][s] = uint8(k)
+				}
+			}
+		}
+	}
+
+	// Find the reachable per-side load nearest the target
+	best := 0
+	for s := 0; s <= maxSide; s++ {
+		if fewest[len(plates)][s] == unreachable {
+			continue
+		}
+		if math.Abs(float64(s)-want) < math.Abs(float64(best)-want) {
+			best = s
+		}
+	}
+
+	for i, s := len(plates), best; i > 0; i-- {
+		if k := int(used[i][s]); k > 0 {
+			load.PerSide = append(load.PerSide, PlateCount{Weight: plates[i-1].Weight, Count: k})
+			s -= k * toPlateUnits(plates[i-1].Weight) / step
+		}
+	}
+	sort.Slice(load.PerSide, func(i, j int) bool { return load.PerSide[i].Weight > load.PerSide[j].Weight })
+
+	load.Achieved = float64(toPlateUnits(empty)+2*best*step) / plateScale
+	load.Exact = toPlateUnits(load.Achieved) == toPlateUnits(target)
+	return load
+}
+
+// NearestLoadable returns the weight closest to target that can be loaded on
+// any of the inventory's bars
+func (inv *Inventory) NearestLoadable(target float64) float64 {
+	nearest := math.Inf(1)
+	for _, bar := range inv.Bars {
+		achieved := inv.LoadBar(target, bar).Achieved
+		if math.Abs(achieved-target) < math.Abs(nearest-target) ||
+			(math.Abs(achieved-target) == math.Abs(nearest-target) && achieved < nearest) {
+			nearest = achieved
+		}
+	}
+	return nearest
+}
+
+// NearestDumbbell rounds target to the inventory's dumbbell increment
+func (inv *Inventory) NearestDumbbell(target float64) float64 {
+	return roundToIncrement(target, inv.DumbbellIncrement)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// How the weight of an exercise is loaded, which decides what is loadable
+const (
+	LoadingBarbell  = "barbell"
+	LoadingDumbbell = "dumbbell"
+	LoadingNone     = "none"
+)
+
+// ValidLoading reports whether loading is a known loading method
+func ValidLoading(loading string) bool {
+	switch loading {
+	case LoadingBarbell, LoadingDumbbell, LoadingNone:
+		return true
+	}
+	return false
+}
+
+// DefaultLoading returns the usual loading method for a tracking type: barbell
+// for weighted reps, dumbbells for loaded carries and none otherwise
+func DefaultLoading(trackingType string) string {
+	switch trackingType {
+	case TrackingWeightReps:
+		return LoadingBarbell
+	case TrackingWeightDuration:
+		return LoadingDumbbell
+	}
+	return LoadingNone
+}
+
+// RoundToLoadable returns the loadable weight nearest to a weight given in
+// unit, expressed in unit
+func (inv *Inventory) RoundToLoadable(weight float64, unit, loading string) float64 {
+	target := weight
+	if unit != inv.Unit {
+		target = FromKilograms(ToKilograms(weight, unit), inv.Unit)
+	}
+
+	switch loading {
+	case LoadingBarbell:
+		target = inv.NearestLoadable(target)
+	case LoadingDumbbell:
+		target = inv.NearestDumbbell(target)
+	default:
+		return weight
+	}
+
+	if unit != inv.Unit {
+		return math.Round(FromKilograms(ToKilograms(target, inv.Unit), unit)*10) / 10
+	}
+	return target
+}
@@ -0,0 +1,259 @@
+package data
+
+import (
+	"fmt"
+	"math"
+)
+
+// ProgressionConfig holds the tunable parameters shared by progression strategies
+type ProgressionConfig struct {
+	RepMin    int     `json:"rep_min"`
+	RepMax    int     `json:"rep_max"`
+	Increment float64 `json:"increment"`
+	TargetRPE float64 `json:"target_rpe"`
+}
+
+// DefaultProgressionConfig returns the parameters used when the client supplies none
+func DefaultProgressionConfig() ProgressionConfig {
+	return ProgressionConfig{
+		RepMin:    8,
+		RepMax:    12,
+		Increment: 2.5,
+		TargetRPE: 8,
+	}
+}
+
+// maxProgressionIncrement bounds the weight added on progression, in either unit
+const maxProgressionIncrement = 100
+
+// Validate checks that the configuration is internally consistent
+func (c ProgressionConfig) Validate() error {
+	if c.RepMin < 1 || c.RepMax < c.RepMin || c.TargetRPE < 1 || c.TargetRPE > 10 ||
+		c.Increment <= 0 || c.Increment > maxProgressionIncrement {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// Suggestion is a recommended prescription for the next session of an exercise
+type Suggestion struct {
+	Strategy  string                 `json:"strategy"`
+	Sets      int                    `json:"sets"`
+	Reps      int                    `json:"reps"`
+	Weight    *float64               `json:"weight,omitempty"`
+	TargetRPE *float64               `json:"target_rpe,omitempty"`
+	Rationale string                 `json:"rationale"`
+	Previous  []*ExercisePerformance `json:"previous,omitempty"`
+}
+
+// ProgressionStrategy computes the next session from a user's history of an exercise
+type ProgressionStrategy interface {
+	Suggest(last []*ExercisePerformance, cfg ProgressionConfig) *Suggestion
+}
+
+// Names of the built-in progression strategies
+const (
+	StrategyDoubleProgression = "double_progression"
+	StrategyLinear            = "linear"
+	StrategyRPE               = "rpe"
+)
+
+var progressionStrategies = map[string]ProgressionStrategy{
+	StrategyDoubleProgression: doubleProgression{},
+	StrategyLinear:            linearProgression{},
+	StrategyRPE:               rpeProgression{},
+}
+
+// GetProgressionStrategy looks up a progression strategy by name
+func GetProgressionStrategy(name string) (ProgressionStrategy, bool) {
+	strategy, ok := progressionStrategies[name]
+	return strategy, ok
+}
+
+// SuggestNext builds a suggestion for the session following the given history,
+// which must be ordered oldest first as returned by GetHistoryForUser
+func SuggestNext(strategyName string, history []*ExercisePerformance, cfg ProgressionConfig) (*Suggestion, error) {
+	strategy, ok := GetProgressionStrategy(strategyName)
+	if !ok {
+		return nil, ErrInvalidInput
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	last := lastSession(history)
+	if len(last) == 0 {
+		return &Suggestion{
+			Strategy:  strategyName,
+			Sets:      3,
+			Reps:      cfg.RepMin,
+			Rationale: "No previous sessions logged for this exercise; start with a weight you can lift comfortably for every rep.",
+		}, nil
+	}
+
+	suggestion := strategy.Suggest(last, cfg)
+	suggestion.Strategy = strategyName
+	suggestion.Previous = last
+	return suggestion, nil
+}
+
+// lastSession returns the entries belonging to the most recent workout in the history
+func lastSession(history []*ExercisePerformance) []*ExercisePerformance {
+	if len(history) == 0 {
+		return nil
+	}
+
+	workoutID := history[len(history)-1].WorkoutID
+	start := len(history) - 1
+	for start > 0 && history[start-1].WorkoutID == workoutID {
+		start--
+	}
+	return history[start:]
+}
+
+// topEntry returns the heaviest entry of a session, preferring more reps on ties
+func topEntry(session []*ExercisePerformance) *ExercisePerformance {
+	var top *ExercisePerformance
+	for _, p := range session {
+		if top == nil || weightOf(p.Weight) > weightOf(top.Weight) ||
+			(weightOf(p.Weight) == weightOf(top.Weight) && p.Reps > top.Reps) {
+			top = p
+		}
+	}
+	return top
+}
+
+func weightOf(weight *float64) float64 {
+	if weight == nil {
+		return 0
+	}
+	return *weight
+}
+
+// roundToIncrement rounds a weight to the nearest multiple of increment
+func roundToIncrement(weight, increment float64) float64 {
+	if increment <= 0 {
+		return weight
+	}
+	return math.Round(weight/increment) * increment
+}
+
+// doubleProgression adds reps within a range and only raises the weight once
+// every set at the working weight reaches the top of the range. Lighter
+// warm-up sets are ignored.
+type doubleProgression struct{}
+
+func (doubleProgression) Suggest(last []*ExercisePerformance, cfg ProgressionConfig) *Suggestion {
+	top := topEntry(last)
+	weight := weightOf(top.Weight)
+
+	// The weakest working set decides, so one good set among several at the
+	// same weight is not enough
+	weakest, sets := top.Reps, 0
+	for _, p := range last {
+		if weightOf(p.Weight) == weight {
+			weakest = min(weakest, p.Reps)
+			sets += p.Sets
+		}
+	}
+
+	if weakest >= cfg.RepMax {
+		next := weight + cfg.Increment
+		return &Suggestion{
+			Sets:   sets,
+			Reps:   cfg.RepMin,
+			Weight: &next,
+			Rationale: fmt.Sprintf("Every set last session hit %d reps at %g, the top of the %d-%d range. Add %g and drop back to %d reps.",
+				weakest, weight, cfg.RepMin, cfg.RepMax, cfg.Increment, cfg.RepMin),
+		}
+	}
+
+	reps := weakest + 1
+	if reps < cfg.RepMin {
+		reps = cfg.RepMin
+	}
+	return &Suggestion{
+		Sets:   sets,
+		Reps:   reps,
+		Weight: top.Weight,
+		Rationale: fmt.Sprintf("The weakest set last session was %d reps at %g, below the top of the %d-%d range. Keep the weight and aim for %d reps on every set.",
+			weakest, weight, cfg.RepMin, cfg.RepMax, reps),
+	}
+}
+
+// linearProgression adds a fixed increment every session unless the last one
+// was a grinder at or near failure
+type linearProgression struct{}
+
+func (linearProgression) Suggest(last []*ExercisePerformance, cfg ProgressionConfig) *Suggestion {
+	top := topEntry(last)
+	weight := weightOf(top.Weight)
+
+	if top.RPE != nil && *top.RPE >= 9.5 {
+		return &Suggestion{
+			Sets:   top.Sets,
+			Reps:   top.Reps,
+			Weight: top.Weight,
+			Rationale: fmt.Sprintf("Last session at %g was logged at RPE %g, at or near failure. Repeat the weight before adding more.",
+				weight, *top.RPE),
+		}
+	}
+
+	next := weight + cfg.Increment
+	return &Suggestion{
+		Sets:   top.Sets,
+		Reps:   top.Reps,
+		Weight: &next,
+		Rationale: fmt.Sprintf("Linear progression: add %g to last session's %g for the same %dx%d.",
+			cfg.Increment, weight, top.Sets, top.Reps),
+	}
+}
+
+// rpeProgression estimates a one-rep max from the last top set and its RPE,
+// then prescribes the load that should land on the target RPE
+type rpeProgression struct{}
+
+// assumedRPE is used for entries logged without an RPE
+const assumedRPE = 10
+
+func (rpeProgression) Suggest(last []*ExercisePerformance, cfg ProgressionConfig) *Suggestion {
+	top := topEntry(last)
+	weight := weightOf(top.Weight)
+
+	rpe := float64(assumedRPE)
+	rpeNote := "no RPE was logged, so the set is treated as taken to failure"
+	if top.RPE != nil {
+		rpe = *top.RPE
+		rpeNote = fmt.Sprintf("logged at RPE %g", rpe)
+	}
+
+	reps := top.Reps
+	if reps < cfg.RepMin {
+		reps = cfg.RepMin
+	} else if reps > cfg.RepMax {
+		reps = cfg.RepMax
+	}
+
+	oneRepMax := weight / percentOfMax(top.Reps, rpe)
+	next := roundToIncrement(oneRepMax*percentOfMax(reps, cfg.TargetRPE), cfg.Increment)
+	target := cfg.TargetRPE
+
+	return &Suggestion{
+		Sets:      top.Sets,
+		Reps:      reps,
+		Weight:    &next,
+		TargetRPE: &target,
+		Rationale: fmt.Sprintf("Last top set was %d reps at %g, %s, for an estimated 1RM of %.1f. %d reps at RPE %g is about %.0f%% of that.",
+			top.Reps, weight, rpeNote, oneRepMax, reps, cfg.TargetRPE, percentOfMax(reps, cfg.TargetRPE)*100),
+	}
+}
+
+// percentOfMax approximates the fraction of a one-rep max that can be lifted for
+// reps at the given RPE, treating reps in reserve as additional reps in Epley's formula
+func percentOfMax(reps int, rpe float64) float64 {
+	repsToFailure := float64(reps) + (10 - rpe)
+	if repsToFailure <= 1 {
+		return 1
+	}
+	return 1 / (1 + repsToFailure/30)
+}
@@ -0,0 +1,47 @@
+package data
+
+import "time"
+
+// RelativeStrength expresses one session of a lift as multiples of the
+// lifter's body weight around the time of the session
+type RelativeStrength struct {
+	WorkoutID          int64     `json:"workout_id"`
+	Date               time.Time `json:"date"`
+	BestWeight         float64   `json:"best_weight"`
+	EstimatedOneRepMax float64   `json:"estimated_1rm"`
+	BodyWeight         float64   `json:"body_weight"`
+	BodyWeightDate     time.Time `json:"body_weight_date"`
+	BestWeightMultiple float64   `json:"best_weight_multiple"`
+	OneRepMaxMultiple  float64   `json:"estimated_1rm_multiple"`
+}
+
+// RelativeStrengthSeries pairs each session with the body weight measurement
+// taken closest to its date. Sessions without a loaded set are skipped, and
+// nothing is returned when no body weight has been logged.
+func RelativeStrengthSeries(sessions []*ExerciseSession, bodyWeights []*Measurement) []RelativeStrength {
+	var series []RelativeStrength
+
+	for _, session := range sessions {
+		if session.BestSet == nil || session.EstimatedOneRepMax <= 0 {
+			continue
+		}
+		bodyWeight := NearestMeasurement(bodyWeights, session.Date)
+		if bodyWeight == nil {
+			break
+		}
+
+		best := weightOf(session.BestSet.Weight)
+		series = append(series, RelativeStrength{
+			WorkoutID:          session.WorkoutID,
+			Date:               session.Date,
+			BestWeight:         best,
+			EstimatedOneRepMax: session.EstimatedOneRepMax,
+			BodyWeight:         bodyWeight.Value,
+			BodyWeightDate:     bodyWeight.MeasuredOn,
+			BestWeightMultiple: best / bodyWeight.Value,
+			OneRepMaxMultiple:  session.EstimatedOneRepMax / bodyWeight.Value,
+		})
+	}
+
+	return series
+}
@@ -0,0 +1,472 @@
+package data
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// Bucket sizes for time series statistics
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// Grouping keys for volume statistics
+const (
+	GroupByBodyPart = "body_part"
+	GroupByExercise = "exercise"
+	GroupByMuscle   = "muscle" // Sets are credited to each muscle worked by its contribution
+)
+
+// StatsQuery describes the range and bucketing of a statistics request. From
+// and To are inclusive calendar dates; Location is the caller's timezone and
+// determines where "today" falls.
+type StatsQuery struct {
+	UserID   int64
+	From     time.Time
+	To       time.Time
+	Bucket   string
+	Location *time.Location
+}
+
+// Validate checks that the query can be executed
+func (q StatsQuery) Validate() error {
+	if q.UserID < 1 || q.From.IsZero() || q.To.IsZero() || q.To.Before(q.From) {
+		return ErrInvalidInput
+	}
+	switch q.Bucket {
+	case BucketDay, BucketWeek, BucketMonth:
+		return nil
+	}
+	return ErrInvalidInput
+}
+
+// VolumeTotals holds the training volume accumulated in a period. Sets and
+// reps are fractional when credited to muscles.
+type VolumeTotals struct {
+	Sets    float64 `json:"sets"`
+	Reps    float64 `json:"reps"`
+	Tonnage float64 `json:"tonnage"`
+}
+
+func (t *VolumeTotals) add(sets, reps int, weight float64) {
+	t.addShare(sets, reps, weight, 1)
+}
+
+// addShare adds the given share of an entry's volume
+func (t *VolumeTotals) addShare(sets, reps int, weight, share float64) {
+	t.Sets += float64(sets) * share
+	t.Reps += float64(sets*reps) * share
+	t.Tonnage += float64(sets*reps) * weight * share
+}
+
+// VolumeBucket is the volume of a single day, week or month
+type VolumeBucket struct {
+	Start string `json:"start"`
+	VolumeTotals
+}
+
+// VolumeSeries is the volume time series of one body part or exercise
+type VolumeSeries struct {
+	ID      int64          `json:"id"`
+	Name    string         `json:"name"`
+	Totals  VolumeTotals   `json:"totals"`
+	Buckets []VolumeBucket `json:"buckets"`
+}
+
+// FrequencyBucket summarises the sessions logged in a single period
+type FrequencyBucket struct {
+	Start                string   `json:"start"`
+	Workouts             int      `json:"workouts"`
+	TrainingDays         int      `json:"training_days"`
+	AverageSessionLength *float64 `json:"average_session_minutes,omitempty"`
+}
+
+// FrequencyStats summarises how often a user trained over a date range
+type FrequencyStats struct {
+	Workouts             int               `json:"workouts"`
+	TrainingDays         int               `json:"training_days"`
+	WorkoutsPerWeek      float64           `json:"workouts_per_week"`
+	AverageSessionLength *float64          `json:"average_session_minutes,omitempty"`
+	Buckets              []FrequencyBucket `json:"buckets"`
+}
+
+// StatsModel runs aggregate queries over a user's workouts
+type StatsModel struct {
+	DB *sql.DB
+}
+
+// volumeRow is one workout exercise entry with the catalog data needed to group it
+type volumeRow struct {
+	workoutID    int64
+	date         time.Time
+	exerciseID   int64
+	exerciseName string
+	bodyPartID   int64
+	bodyPartName string
+	sets         int
+	reps         int
+	weight       float64
+
+	// Set when loading volume per muscle
+	muscleID   int64
+	muscleName string
+	share      float64
+}
+
+// ValidGroupBy reports whether groupBy is a known volume grouping
+func ValidGroupBy(groupBy string) bool {
+	return groupBy == GroupByBodyPart || groupBy == GroupByExercise || groupBy == GroupByMuscle
+}
+
+// Volume returns sets, reps and tonnage per body part, exercise or muscle,
+// bucketed by day, week or month. When grouping by body part, a depth of 0 or
+// more rolls volume up to the body parts at that depth of the tree, the top
+// level being 0; a negative depth keeps each exercise's own body part.
+func (m StatsModel) Volume(q StatsQuery, groupBy string, depth int) ([]*VolumeSeries, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	if !ValidGroupBy(groupBy) {
+		return nil, ErrInvalidInput
+	}
+
+	var rollUp map[int64]*BodyPart
+	if groupBy == GroupByBodyPart && depth >= 0 {
+		var err error
+		if rollUp, err = bodyPartRollUp(m.DB, depth); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []volumeRow
+	var err error
+	if groupBy == GroupByMuscle {
+		rows, err = m.muscleVolumeRows(q)
+	} else {
+		rows, err = m.volumeRows(q)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	starts := BucketStarts(q.From, q.To, q.Bucket)
+	index := map[string]int{}
+	for i, start := range starts {
+		index[start.Format(DateFormat)] = i
+	}
+
+	seriesByID := map[int64]*VolumeSeries{}
+	var series []*VolumeSeries
+
+	for _, row := range rows {
+		id, name, share := row.bodyPartID, row.bodyPartName, 1.0
+		if ancestor, ok := rollUp[id]; ok {
+			id, name = ancestor.ID, ancestor.Name
+		}
+		switch groupBy {
+		case GroupByExercise:
+			id, name = row.exerciseID, row.exerciseName
+		case GroupByMuscle:
+			id, name, share = row.muscleID, row.muscleName, row.share
+		}
+
+		s, ok := seriesByID[id]
+		if !ok {
+			s = &VolumeSeries{ID: id, Name: name, Buckets: make([]VolumeBucket, len(starts))}
+			for i, start := range starts {
+				s.Buckets[i].Start = start.Format(DateFormat)
+			}
+			seriesByID[id] = s
+			series = append(series, s)
+		}
+
+		bucket := BucketStart(row.date, q.Bucket).Format(DateFormat)
+		s.Buckets[index[bucket]].addShare(row.sets, row.reps, row.weight, share)
+		s.Totals.addShare(row.sets, row.reps, row.weight, share)
+	}
+
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	return series, nil
+}
+
+// Frequency returns how many sessions and training days fell in each bucket,
+// along with the average session length where it was logged
+func (m StatsModel) Frequency(q StatsQuery) (*FrequencyStats, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT date, duration_minutes
+		FROM workouts
+		WHERE user_id = ? AND status = 'completed' AND date(date) BETWEEN ? AND ?
+		ORDER BY date, id`,
+		q.UserID, q.From.Format(DateFormat), q.To.Format(DateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	starts := BucketStarts(q.From, q.To, q.Bucket)
+	stats := &FrequencyStats{Buckets: make([]FrequencyBucket, len(starts))}
+	index := map[string]int{}
+	for i, start := range starts {
+		stats.Buckets[i].Start = start.Format(DateFormat)
+		index[stats.Buckets[i].Start] = i
+	}
+
+	type durationSum struct {
+		minutes int
+		count   int
+	}
+	bucketDurations := make([]durationSum, len(starts))
+	var total durationSum
+	days := map[string]bool{}
+
+	for rows.Next() {
+		var date time.Time
+		var duration sql.NullInt64
+		if err := rows.Scan(&date, &duration); err != nil {
+			return nil, err
+		}
+
+		day := CivilDate(date)
+		i := index[BucketStart(day, q.Bucket).Format(DateFormat)]
+		stats.Buckets[i].Workouts++
+		stats.Workouts++
+
+		if !days[day.Format(DateFormat)] {
+			days[day.Format(DateFormat)] = true
+			stats.Buckets[i].TrainingDays++
+			stats.TrainingDays++
+		}
+
+		if duration.Valid {
+			bucketDurations[i].minutes += int(duration.Int64)
+			bucketDurations[i].count++
+			total.minutes += int(duration.Int64)
+			total.count++
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, d := range bucketDurations {
+		if d.count > 0 {
+			avg := float64(d.minutes) / float64(d.count)
+			stats.Buckets[i].AverageSessionLength = &avg
+		}
+	}
+	if total.count > 0 {
+		avg := float64(total.minutes) / float64(total.count)
+		stats.AverageSessionLength = &avg
+	}
+
+	rangeDays := int(q.To.Sub(q.From).Hours()/24) + 1
+	stats.WorkoutsPerWeek = float64(stats.Workouts) * 7 / float64(rangeDays)
+
+	return stats, nil
+}
+
+// volumeRows loads the workout exercise entries of a user within the query range
+func (m StatsModel) volumeRows(q StatsQuery) ([]volumeRow, error) {
+	rows, err := m.DB.Query(`
+		SELECT w.id, w.date, e.id, e.name, bp.id, bp.name, we.sets, we.reps, we.weight
+		FROM workout_exercises we
+		JOIN workouts w ON we.workout_id = w.id
+		JOIN exercises e ON we.exercise_id = e.id
+		JOIN body_parts bp ON e.body_part_id = bp.id
+		WHERE w.user_id = ? AND w.status = 'completed' AND date(w.date) BETWEEN ? AND ?
+		ORDER BY w.date, w.id, we.id`,
+		q.UserID, q.From.Format(DateFormat), q.To.Format(DateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []volumeRow
+
+	for rows.Next() {
+		var row volumeRow
+		var weight sql.NullFloat64
+		err := rows.Scan(
+			&row.workoutID,
+			&row.date,
+			&row.exerciseID,
+			&row.exerciseName,
+			&row.bodyPartID,
+			&row.bodyPartName,
+			&row.sets,
+			&row.reps,
+			&weight,
+		)
+		if err != nil {
+			return nil, err
+		}
+		row.date = CivilDate(row.date)
+		row.weight = weight.Float64
+		result = append(result, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// muscleVolumeRows loads the workout exercise entries of a user within the
+// query range once for every muscle they credit, with the share credited
+func (m StatsModel) muscleVolumeRows(q StatsQuery) ([]volumeRow, error) {
+	rows, err := m.DB.Query(`
+		SELECT w.id, w.date, e.id, e.name, bp.id, bp.name, we.sets, we.reps, we.weight,
+		       mu.id, mu.name, em.role, em.contribution
+		FROM workout_exercises we
+		JOIN workouts w ON we.workout_id = w.id
+		JOIN exercises e ON we.exercise_id = e.id
+		JOIN body_parts bp ON e.body_part_id = bp.id
+		JOIN exercise_muscles em ON em.exercise_id = e.id
+		JOIN muscles mu ON mu.id = em.muscle_id
+		WHERE w.user_id = ? AND w.status = 'completed' AND date(w.date) BETWEEN ? AND ?
+		ORDER BY w.date, w.id, we.id, mu.id`,
+		q.UserID, q.From.Format(DateFormat), q.To.Format(DateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []volumeRow
+
+	for rows.Next() {
+		var row volumeRow
+		var weight, contribution sql.NullFloat64
+		var role string
+		err := rows.Scan(
+			&row.workoutID,
+			&row.date,
+			&row.exerciseID,
+			&row.exerciseName,
+			&row.bodyPartID,
+			&row.bodyPartName,
+			&row.sets,
+			&row.reps,
+			&weight,
+			&row.muscleID,
+			&row.muscleName,
+			&role,
+			&contribution,
+		)
+		if err != nil {
+			return nil, err
+		}
+		row.share = DefaultContribution(role)
+		if contribution.Valid {
+			row.share = contribution.Float64
+		}
+		if row.share == 0 {
+			continue
+		}
+		row.date = CivilDate(row.date)
+		row.weight = weight.Float64
+		result = append(result, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DateFormat is the layout used for calendar dates throughout the API
+const DateFormat = "2006-01-02"
+
+// CivilDate strips the time of day from a workout date. Workout dates are
+// calendar dates, so the stored year, month and day are kept as-is rather
+// than being converted between timezones.
+func CivilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BucketStart returns the first day of the day, ISO week or month containing date
+func BucketStart(date time.Time, bucket string) time.Time {
+	date = CivilDate(date)
+	switch bucket {
+	case BucketWeek:
+		offset := (int(date.Weekday()) + 6) % 7 // Days since Monday
+		return date.AddDate(0, 0, -offset)
+	case BucketMonth:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+// BucketStarts lists the start of every bucket overlapping the range from..to
+func BucketStarts(from, to time.Time, bucket string) []time.Time {
+	var starts []time.Time
+	for start := BucketStart(from, bucket); !start.After(CivilDate(to)); {
+		starts = append(starts, start)
+		switch bucket {
+		case BucketWeek:
+			start = start.AddDate(0, 0, 7)
+		case BucketMonth:
+			start = start.AddDate(0, 1, 0)
+		default:
+			start = start.AddDate(0, 0, 1)
+		}
+	}
+	return starts
+}
+
+// bodyPartRollUp maps every body part to its ancestor at depth, the top level
+// being depth 0. Body parts no deeper than depth map to themselves.
+func bodyPartRollUp(q dbtx, depth int) (map[int64]*BodyPart, error) {
+	rows, err := q.Query("SELECT id, name, parent_id FROM body_parts")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]*BodyPart{}
+	for rows.Next() {
+		bodyPart := &BodyPart{}
+		if err := rows.Scan(&bodyPart.ID, &bodyPart.Name, &bodyPart.ParentID); err != nil {
+			return nil, err
+		}
+		byID[bodyPart.ID] = bodyPart
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rollUp := make(map[int64]*BodyPart, len(byID))
+	for id, bodyPart := range byID {
+		// Collect the path from the body part up to its root; the length
+		// bound stops at any cycle written around the model
+		path := []*BodyPart{bodyPart}
+		for current := bodyPart; current.ParentID != nil && len(path) <= len(byID); {
+			parent, ok := byID[*current.ParentID]
+			if !ok {
+				break
+			}
+			path = append(path, parent)
+			current = parent
+		}
+
+		level := len(path) - 1 - depth
+		if level < 0 {
+			level = 0
+		}
+		rollUp[id] = path[level]
+	}
+
+	return rollUp, nil
+}
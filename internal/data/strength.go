@@ -0,0 +1,26 @@
+package data
+
+// EpleyOneRepMax estimates a one-rep max using Epley's formula
+func EpleyOneRepMax(weight float64, reps int) float64 {
+	if reps < 1 || weight <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+	return weight * (1 + float64(reps)/30)
+}
+
+// BrzyckiOneRepMax estimates a one-rep max using Brzycki's formula, which is
+// only defined below 37 reps
+func BrzyckiOneRepMax(weight float64, reps int) float64 {
+	if reps < 1 || reps >= 37 || weight <= 0 {
+		return 0
+	}
+	return weight * 36 / float64(37-reps)
+}
+
+// Volume returns the tonnage of a workout exercise entry
+func Volume(we WorkoutExercise) float64 {
+	return float64(we.Sets*we.Reps) * weightOf(we.Weight)
+}
@@ -0,0 +1,175 @@
+package data
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// Weights of the parts of a substitute's score besides muscle overlap, which
+// counts fully
+const (
+	patternWeight   = 0.5
+	equipmentWeight = 0.25
+	variationWeight = 0.25
+)
+
+// Substitute is an exercise suggested in place of another, with how closely
+// it matches
+type Substitute struct {
+	Exercise         *Exercise
+	Score            float64
+	MuscleOverlap    float64 // Share of each set credited to the same muscles
+	SamePattern      bool    // Trains the same movement pattern
+	EquipmentOverlap float64 // Share of its equipment the original also uses; 1 when it needs none
+	Variation        bool    // A variation of, or sibling variation to, the original
+}
+
+// Substitutes ranks exercises that could replace exerciseID: those logged
+// compatibly that work the same muscles or train the same movement pattern,
+// best first. Only exercises the user can see are considered and, with a
+// gymID, only those performable in that gym.
+func (m ExerciseModel) Substitutes(exerciseID, userID, gymID int64) ([]*Substitute, error) {
+	if exerciseID < 1 || userID < 1 {
+		return nil, ErrInvalidInput
+	}
+	return exerciseSubstitutes(m.DB, exerciseID, userID, gymID)
+}
+
+func exerciseSubstitutes(q dbtx, exerciseID, userID, gymID int64) ([]*Substitute, error) {
+	original, err := scanExercise(q.QueryRow(`
+		SELECT`+exerciseColumns+`
+		FROM exercises e
+		JOIN body_parts b ON b.id = e.body_part_id
+		WHERE e.id = ? AND `+visibleToUser, exerciseID, userID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	trackingTypes := substituteTracking(original.TrackingType)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(trackingTypes)), ", ")
+	query := `
+		SELECT` + exerciseColumns + `
+		FROM exercises e
+		JOIN body_parts b ON b.id = e.body_part_id
+		WHERE e.id != ? AND ` + visibleToUser + ` AND e.tracking_type IN (` + placeholders + `)`
+	args := []interface{}{exerciseID, userID}
+	for _, t := range trackingTypes {
+		args = append(args, t)
+	}
+	if gymID > 0 {
+		query += " AND " + performableInGym
+		args = append(args, gymID)
+	}
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []*Exercise
+
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, exercise)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := attachExerciseDetails(q, append(candidates, original)); err != nil {
+		return nil, err
+	}
+
+	var substitutes []*Substitute
+	for _, candidate := range candidates {
+		substitute := scoreSubstitute(original, candidate)
+		if substitute.MuscleOverlap > 0 || substitute.SamePattern {
+			substitutes = append(substitutes, substitute)
+		}
+	}
+
+	sort.SliceStable(substitutes, func(i, j int) bool {
+		a, b := substitutes[i], substitutes[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		aSame, bSame := a.Exercise.BodyPartID == original.BodyPartID, b.Exercise.BodyPartID == original.BodyPartID
+		if aSame != bSame {
+			return aSame
+		}
+		return a.Exercise.Name < b.Exercise.Name
+	})
+
+	return substitutes, nil
+}
+
+// scoreSubstitute rates how closely candidate matches original
+func scoreSubstitute(original, candidate *Exercise) *Substitute {
+	substitute := &Substitute{Exercise: candidate}
+
+	shares := map[int64]float64{}
+	for _, muscle := range original.Muscles {
+		shares[muscle.MuscleID] = *muscle.Contribution
+	}
+	for _, muscle := range candidate.Muscles {
+		substitute.MuscleOverlap += minFloat(shares[muscle.MuscleID], *muscle.Contribution)
+	}
+
+	substitute.SamePattern = original.MovementPattern != "" && original.MovementPattern == candidate.MovementPattern
+
+	substitute.EquipmentOverlap = 1
+	if len(candidate.Equipment) > 0 {
+		uses := map[int64]bool{}
+		for _, e := range original.Equipment {
+			uses[e.ID] = true
+		}
+		shared := 0
+		for _, e := range candidate.Equipment {
+			if uses[e.ID] {
+				shared++
+			}
+		}
+		substitute.EquipmentOverlap = float64(shared) / float64(len(candidate.Equipment))
+	}
+
+	substitute.Variation = sameFamily(original, candidate)
+
+	substitute.Score = substitute.MuscleOverlap + substitute.EquipmentOverlap*equipmentWeight
+	if substitute.SamePattern {
+		substitute.Score += patternWeight
+	}
+	if substitute.Variation {
+		substitute.Score += variationWeight
+	}
+	return substitute
+}
+
+// sameFamily reports whether one exercise is a variation of the other or
+// both are variations of the same exercise
+func sameFamily(a, b *Exercise) bool {
+	if a.VariationOf != nil && *a.VariationOf == b.ID {
+		return true
+	}
+	if b.VariationOf != nil && *b.VariationOf == a.ID {
+		return true
+	}
+	return a.VariationOf != nil && b.VariationOf != nil && *a.VariationOf == *b.VariationOf
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+package data
+
+import "database/sql"
+
+// Tracking types describe what is logged for each set of an exercise
+const (
+	TrackingWeightReps         = "weight_reps"         // Barbell and machine lifts
+	TrackingBodyweightReps     = "bodyweight_reps"     // Push-ups, pull-ups; weight is optional added load
+	TrackingAssistedBodyweight = "assisted_bodyweight" // Assisted pull-ups, dips; weight is the assistance
+	TrackingDuration           = "duration"            // Planks and other timed holds
+	TrackingWeightDuration     = "weight_duration"     // Loaded carries and weighted holds
+	TrackingDistanceDuration   = "distance_duration"   // Runs, rows and rides
+)
+
+// ValidTrackingType reports whether trackingType is a known tracking type
+func ValidTrackingType(trackingType string) bool {
+	switch trackingType {
+	case TrackingWeightReps, TrackingBodyweightReps, TrackingAssistedBodyweight,
+		TrackingDuration, TrackingWeightDuration, TrackingDistanceDuration:
+		return true
+	}
+	return false
+}
+
+// substituteTracking lists the tracking types of exercises whose entries can
+// stand in for those of trackingType once their weight is dropped
+func substituteTracking(trackingType string) []string {
+	switch trackingType {
+	case TrackingWeightReps, TrackingBodyweightReps, TrackingAssistedBodyweight:
+		return []string{TrackingWeightReps, TrackingBodyweightReps}
+	case TrackingDuration, TrackingWeightDuration:
+		return []string{TrackingDuration}
+	}
+	return []string{trackingType}
+}
+
+// validateEntry checks that a workout exercise carries the fields its
+// exercise's tracking type requires, and none that it cannot use
+func validateEntry(we *WorkoutExercise, trackingType string) error {
+	if we.Sets < 1 || we.Reps < 0 || !validRPE(we.RPE) {
+		return ErrInvalidInput
+	}
+	if we.EnteredUnit != "" && !ValidUnit(we.EnteredUnit) {
+		return ErrInvalidInput
+	}
+	if (we.Weight != nil && *we.Weight < 0) ||
+		(we.DurationSeconds != nil && *we.DurationSeconds < 1) ||
+		(we.DistanceMeters != nil && *we.DistanceMeters <= 0) {
+		return ErrInvalidInput
+	}
+
+	hasWeight := we.Weight != nil && *we.Weight > 0
+	hasDuration := we.DurationSeconds != nil
+	hasDistance := we.DistanceMeters != nil
+
+	switch trackingType {
+	case TrackingWeightReps, TrackingBodyweightReps:
+		if we.Reps < 1 || hasDuration || hasDistance {
+			return ErrInvalidInput
+		}
+	case TrackingAssistedBodyweight:
+		if we.Reps < 1 || !hasWeight || hasDuration || hasDistance {
+			return ErrInvalidInput
+		}
+	case TrackingDuration:
+		if !hasDuration || hasWeight || hasDistance {
+			return ErrInvalidInput
+		}
+	case TrackingWeightDuration:
+		if !hasDuration || !hasWeight || hasDistance {
+			return ErrInvalidInput
+		}
+	case TrackingDistanceDuration:
+		if !hasDistance || !hasDuration || hasWeight {
+			return ErrInvalidInput
+		}
+	default:
+		return ErrInvalidInput
+	}
+
+	return nil
+}
+
+// validateEntries checks a set of workout exercises against the tracking
+// types of their exercises. Unknown exercises and those the user cannot see
+// are rejected.
+func validateEntries(q dbtx, userID int64, details []WorkoutExercise) error {
+	trackingTypes := map[int64]string{}
+
+	for i := range details {
+		exerciseID := details[i].ExerciseID
+		trackingType, ok := trackingTypes[exerciseID]
+		if !ok {
+			err := q.QueryRow(
+				"SELECT e.tracking_type FROM exercises e WHERE e.id = ? AND "+visibleToUser, exerciseID, userID,
+			).Scan(&trackingType)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return ErrInvalidInput
+				}
+				return err
+			}
+			trackingTypes[exerciseID] = trackingType
+		}
+
+		if err := validateEntry(&details[i], trackingType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// derivePace fills in the pace of entries that log both distance and duration
+func derivePace(we *WorkoutExercise) {
+	we.PaceSecondsPerKm = nil
+	if we.DistanceMeters == nil || we.DurationSeconds == nil || *we.DistanceMeters <= 0 {
+		return
+	}
+	pace := float64(*we.DurationSeconds) / (*we.DistanceMeters / 1000)
+	we.PaceSecondsPerKm = &pace
+}
@@ -0,0 +1,108 @@
+package data
+
+import "math"
+
+// Weight units. Weights are stored in kilograms alongside the value and unit
+// the user originally entered.
+const (
+	UnitKilograms = "kg"
+	UnitPounds    = "lb"
+)
+
+// KilogramsPerPound is the exact international avoirdupois pound
+const KilogramsPerPound = 0.45359237
+
+// ValidUnit reports whether unit is a supported weight unit
+func ValidUnit(unit string) bool {
+	return unit == UnitKilograms || unit == UnitPounds
+}
+
+// ToKilograms converts a weight in unit to kilograms
+func ToKilograms(value float64, unit string) float64 {
+	if unit == UnitPounds {
+		return value * KilogramsPerPound
+	}
+	return value
+}
+
+// FromKilograms converts a weight in kilograms to unit without rounding
+func FromKilograms(kilograms float64, unit string) float64 {
+	if unit == UnitPounds {
+		return kilograms / KilogramsPerPound
+	}
+	return kilograms
+}
+
+// PlateIncrement is the smallest step converted weights are rounded to: the
+// total added by a pair of the smallest common change plates
+func PlateIncrement(unit string) float64 {
+	if unit == UnitPounds {
+		return 1
+	}
+	return 0.5
+}
+
+// DefaultProgressionIncrement is the usual jump between sessions in unit
+func DefaultProgressionIncrement(unit string) float64 {
+	if unit == UnitPounds {
+		return 5
+	}
+	return 2.5
+}
+
+// RoundToPlate rounds a weight to the plate increment of its unit
+func RoundToPlate(value float64, unit string) float64 {
+	return roundToIncrement(value, PlateIncrement(unit))
+}
+
+// SetWeight records a weight as entered by the user, storing the canonical
+// kilogram value alongside the original value and unit
+func (we *WorkoutExercise) SetWeight(value float64, unit string) {
+	entered := value
+	kilograms := ToKilograms(value, unit)
+	we.Weight = &kilograms
+	we.EnteredWeight = &entered
+	we.EnteredUnit = unit
+}
+
+// WeightIn returns the weight of an entry expressed in unit. A weight entered
+// in the same unit is returned exactly as entered; otherwise the stored value
+// is converted and rounded to the unit's plate increment.
+func (we *WorkoutExercise) WeightIn(unit string) *float64 {
+	if we.Weight == nil {
+		return nil
+	}
+	if we.EnteredWeight != nil && we.EnteredUnit == unit {
+		value := *we.EnteredWeight
+		return &value
+	}
+	value := RoundToPlate(FromKilograms(*we.Weight, unit), unit)
+	return &value
+}
+
+// ConvertMeasure converts a derived kilogram quantity such as an estimated
+// 1RM or tonnage to unit, rounded to one decimal place. Kilogram quantities
+// are returned unchanged.
+func ConvertMeasure(kilograms float64, unit string) float64 {
+	if unit != UnitPounds {
+		return kilograms
+	}
+	return math.Round(FromKilograms(kilograms, unit)*10) / 10
+}
+
+// normalizeWeight fills in the entered weight of entries built with a bare
+// kilogram Weight, so every stored row records what was entered
+func normalizeWeight(we *WorkoutExercise) {
+	if we.EnteredUnit == "" {
+		we.EnteredUnit = UnitKilograms
+	}
+	if we.Weight == nil {
+		we.EnteredWeight = nil
+		return
+	}
+	if we.EnteredWeight == nil {
+		entered := FromKilograms(*we.Weight, we.EnteredUnit)
+		we.EnteredWeight = &entered
+	}
+	we.Unit = UnitKilograms
+}
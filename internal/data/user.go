@@ -11,6 +11,7 @@ type User struct {
 	Name          string    `json:"name"`
 	OAuthProvider string    `json:"oauth_provider"`
 	OAuthID       string    `json:"-"`
+	IsAdmin       bool      `json:"is_admin"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
@@ -27,7 +28,7 @@ const UserContextKey = contextKey("user")
 func (m UserModel) GetByOAuth(provider, oauthID string) (*User, error) {
 	user := &User{}
 	err := m.DB.QueryRow(`
-        SELECT id, email, name, oauth_provider, oauth_id, created_at, updated_at
+        SELECT id, email, name, oauth_provider, oauth_id, is_admin, created_at, updated_at
         FROM users 
         WHERE oauth_provider = ? AND oauth_id = ?`,
 		provider, oauthID,
@@ -37,6 +38,7 @@ func (m UserModel) GetByOAuth(provider, oauthID string) (*User, error) {
 		&user.Name,
 		&user.OAuthProvider,
 		&user.OAuthID,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -91,7 +93,7 @@ func (m UserModel) CreateOrUpdate(user *User) error {
 func (m UserModel) GetByID(id int64) (*User, error) {
 	user := &User{}
 	err := m.DB.QueryRow(`
-        SELECT id, email, name, oauth_provider, oauth_id, created_at, updated_at
+        SELECT id, email, name, oauth_provider, oauth_id, is_admin, created_at, updated_at
         FROM users 
         WHERE id = ?`,
 		id,
@@ -101,6 +103,7 @@ func (m UserModel) GetByID(id int64) (*User, error) {
 		&user.Name,
 		&user.OAuthProvider,
 		&user.OAuthID,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
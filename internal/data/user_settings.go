@@ -0,0 +1,103 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserSettings holds a user's preferences
+type UserSettings struct {
+	UserID        int64  `json:"user_id"`
+	PreferredUnit string `json:"preferred_unit"`
+	WeeklyTarget  *int   `json:"weekly_target"` // Workouts per week the user aims for
+	Timezone      string `json:"timezone"`      // IANA timezone name
+}
+
+// maxWeeklyTarget bounds the weekly workout target
+const maxWeeklyTarget = 14
+
+// GetSettings retrieves the preferences of a user
+func (m UserModel) GetSettings(userID int64) (*UserSettings, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	settings := &UserSettings{UserID: userID}
+	err := m.DB.QueryRow(`
+        SELECT preferred_unit, weekly_target, timezone
+        FROM users
+        WHERE id = ?`,
+		userID,
+	).Scan(&settings.PreferredUnit, &settings.WeeklyTarget, &settings.Timezone)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings saves the preferences of a user
+func (m UserModel) UpdateSettings(settings *UserSettings) error {
+	if settings.UserID < 1 || !ValidUnit(settings.PreferredUnit) {
+		return ErrInvalidInput
+	}
+	if settings.WeeklyTarget != nil && (*settings.WeeklyTarget < 1 || *settings.WeeklyTarget > maxWeeklyTarget) {
+		return ErrInvalidInput
+	}
+	if !ValidTimezone(settings.Timezone) {
+		return ErrInvalidInput
+	}
+
+	result, err := m.DB.Exec(`
+        UPDATE users
+        SET preferred_unit = ?, weekly_target = ?, timezone = ?, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?`,
+		settings.PreferredUnit, settings.WeeklyTarget, settings.Timezone, settings.UserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ValidTimezone reports whether name is a loadable IANA timezone
+func ValidTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil && name != "" && name != "Local"
+}
+
+// Location returns the timezone of a user, falling back to UTC when the user
+// does not exist or their timezone cannot be loaded
+func (m UserModel) Location(userID int64) *time.Location {
+	return userLocation(m.DB, userID)
+}
+
+func userLocation(q dbtx, userID int64) *time.Location {
+	var name string
+	if err := q.QueryRow("SELECT timezone FROM users WHERE id = ?", userID).Scan(&name); err != nil {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Today returns the current calendar date in location
+func Today(location *time.Location) time.Time {
+	return CivilDate(time.Now().In(location))
+}
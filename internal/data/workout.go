@@ -5,14 +5,32 @@ import (
 	"time"
 )
 
+// Workout statuses
+const (
+	WorkoutCompleted = "completed"
+	WorkoutPlanned   = "planned"
+)
+
+// ValidWorkoutStatus reports whether status is a known workout status
+func ValidWorkoutStatus(status string) bool {
+	return status == WorkoutCompleted || status == WorkoutPlanned
+}
+
 type Workout struct {
-	ID      int64             `json:"id"`
-	UserID  int64             `json:"user_id"`
-	User    *User             `json:"user,omitempty"` // Optional user details
-	Name    string            `json:"name"`
-	Date    time.Time         `json:"date"`
-	Notes   string            `json:"notes"`
-	Details []WorkoutExercise `json:"details,omitempty"`
+	ID              int64             `json:"id"`
+	UserID          int64             `json:"user_id"`
+	User            *User             `json:"user,omitempty"` // Optional user details
+	Name            string            `json:"name"`
+	Date            time.Time         `json:"date"`     // Local calendar date in Timezone
+	Timezone        string            `json:"timezone"` // IANA timezone the workout took place in
+	StartedAt       *time.Time        `json:"started_at,omitempty"`
+	EndedAt         *time.Time        `json:"ended_at,omitempty"`
+	Notes           string            `json:"notes"`
+	DurationMinutes *int              `json:"duration_minutes,omitempty"` // Optional session length
+	Status          string            `json:"status"`                     // completed or planned
+	SessionRPE      *float64          `json:"session_rpe,omitempty"`      // Overall effort, 1-10
+	Tags            []string          `json:"tags,omitempty"`
+	Details         []WorkoutExercise `json:"details,omitempty"`
 }
 
 // WorkoutModel handles database operations for workouts
@@ -36,10 +54,13 @@ func (m WorkoutModel) GetByID(id int64) (*Workout, error) {
 	// Get workout
 	workout := &Workout{}
 	err = tx.QueryRow(`
-        SELECT id, user_id, name, date, notes
+        SELECT id, user_id, name, date, timezone, started_at, ended_at, notes, duration_minutes,
+               status, session_rpe
         FROM workouts
         WHERE id = ?`, id,
-	).Scan(&workout.ID, &workout.UserID, &workout.Name, &workout.Date, &workout.Notes)
+	).Scan(&workout.ID, &workout.UserID, &workout.Name, &workout.Date, &workout.Timezone,
+		&workout.StartedAt, &workout.EndedAt, &workout.Notes, &workout.DurationMinutes,
+		&workout.Status, &workout.SessionRPE)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -50,9 +71,11 @@ func (m WorkoutModel) GetByID(id int64) (*Workout, error) {
 
 	// Get workout exercises
 	rows, err := tx.Query(`
-        SELECT id, exercise_id, sets, reps, weight
+        SELECT id, exercise_id, sets, reps, weight, weight_input, weight_unit, rpe, notes,
+               position, group_id, group_type, duration_seconds, distance_meters
         FROM workout_exercises
-        WHERE workout_id = ?`, id)
+        WHERE workout_id = ?
+        ORDER BY position, id`, id)
 	if err != nil {
 		return nil, err
 	}
@@ -60,16 +83,30 @@ func (m WorkoutModel) GetByID(id int64) (*Workout, error) {
 
 	for rows.Next() {
 		var detail WorkoutExercise
+		var notes, groupType sql.NullString
 		err := rows.Scan(
 			&detail.ID,
 			&detail.ExerciseID,
 			&detail.Sets,
 			&detail.Reps,
 			&detail.Weight,
+			&detail.EnteredWeight,
+			&detail.EnteredUnit,
+			&detail.RPE,
+			&notes,
+			&detail.Position,
+			&detail.GroupID,
+			&groupType,
+			&detail.DurationSeconds,
+			&detail.DistanceMeters,
 		)
 		if err != nil {
 			return nil, err
 		}
+		detail.Notes = notes.String
+		detail.GroupType = groupType.String
+		detail.Unit = UnitKilograms
+		derivePace(&detail)
 		detail.WorkoutID = id
 		workout.Details = append(workout.Details, detail)
 	}
@@ -78,13 +115,25 @@ func (m WorkoutModel) GetByID(id int64) (*Workout, error) {
 		return nil, err
 	}
 
+	if err := attachTags(tx, []*Workout{workout}); err != nil {
+		return nil, err
+	}
+
+	// Mark the entries that set personal records
+	if err := attachRecords(tx, workout); err != nil {
+		return nil, err
+	}
+
 	return workout, tx.Commit()
 }
 
 // Create inserts a new workout and its exercises
 func (m WorkoutModel) Create(workout *Workout) error {
-	if workout.UserID < 1 || workout.Name == "" {
-		return ErrInvalidInput
+	if err := validateWorkout(workout); err != nil {
+		return err
+	}
+	if err := validateGroups(workout.Details); err != nil {
+		return err
 	}
 
 	tx, err := m.DB.Begin()
@@ -93,11 +142,18 @@ func (m WorkoutModel) Create(workout *Workout) error {
 	}
 	defer tx.Rollback()
 
+	if err := resolveWorkoutTime(tx, workout); err != nil {
+		return err
+	}
+
 	// Insert workout
 	result, err := tx.Exec(`
-        INSERT INTO workouts (user_id, name, date, notes)
-        VALUES (?, ?, ?, ?)`,
-		workout.UserID, workout.Name, workout.Date, workout.Notes,
+        INSERT INTO workouts (
+            user_id, name, date, timezone, started_at, ended_at, notes, duration_minutes, status, session_rpe
+        )
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		workout.UserID, workout.Name, workout.Date, workout.Timezone, workout.StartedAt, workout.EndedAt,
+		workout.Notes, workout.DurationMinutes, workout.Status, workout.SessionRPE,
 	)
 	if err != nil {
 		return err
@@ -110,27 +166,27 @@ func (m WorkoutModel) Create(workout *Workout) error {
 	}
 	workout.ID = workoutID
 
+	if err := saveWorkoutTags(tx, workout.ID, workout.Tags); err != nil {
+		return err
+	}
+
 	// Insert workout exercises
-	for i := range workout.Details {
-		workout.Details[i].WorkoutID = workoutID
-		result, err := tx.Exec(`
-            INSERT INTO workout_exercises (workout_id, exercise_id, sets, reps, weight)
-            VALUES (?, ?, ?, ?, ?)`,
-			workoutID,
-			workout.Details[i].ExerciseID,
-			workout.Details[i].Sets,
-			workout.Details[i].Reps,
-			workout.Details[i].Weight,
-		)
-		if err != nil {
-			return err
-		}
+	if err := validateEntries(tx, workout.UserID, workout.Details); err != nil {
+		return err
+	}
+	if err := insertWorkoutExercises(tx, workout); err != nil {
+		return err
+	}
 
-		exerciseID, err := result.LastInsertId()
-		if err != nil {
-			return err
-		}
-		workout.Details[i].ID = exerciseID
+	// Check the new entries against the user's personal records
+	if err := refreshPersonalRecords(tx, workout.UserID, distinctExerciseIDs(workout.Details)); err != nil {
+		return err
+	}
+	if err := attachRecords(tx, workout); err != nil {
+		return err
+	}
+	if err := evaluateGoals(tx, workout.UserID); err != nil {
+		return err
 	}
 
 	return tx.Commit()
@@ -138,9 +194,15 @@ func (m WorkoutModel) Create(workout *Workout) error {
 
 // Update modifies an existing workout and its exercises
 func (m WorkoutModel) Update(workout *Workout) error {
-	if workout.ID < 1 || workout.UserID < 1 || workout.Name == "" {
+	if workout.ID < 1 {
 		return ErrInvalidInput
 	}
+	if err := validateWorkout(workout); err != nil {
+		return err
+	}
+	if err := validateGroups(workout.Details); err != nil {
+		return err
+	}
 
 	tx, err := m.DB.Begin()
 	if err != nil {
@@ -148,22 +210,25 @@ func (m WorkoutModel) Update(workout *Workout) error {
 	}
 	defer tx.Rollback()
 
-	// Check if workout exists
-	var exists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM workouts WHERE id = ?)", workout.ID).Scan(&exists)
-	if err != nil {
+	if err := resolveWorkoutTime(tx, workout); err != nil {
 		return err
 	}
-	if !exists {
-		return ErrRecordNotFound
+
+	// Check if workout exists, remembering what it held so records can be
+	// re-evaluated for exercises that were removed
+	previousUserID, previousExerciseIDs, err := workoutExerciseIDs(tx, workout.ID)
+	if err != nil {
+		return err
 	}
 
 	// Update workout
 	result, err := tx.Exec(`
         UPDATE workouts 
-        SET user_id = ?, name = ?, date = ?, notes = ?
+        SET user_id = ?, name = ?, date = ?, timezone = ?, started_at = ?, ended_at = ?, notes = ?,
+            duration_minutes = ?, status = ?, session_rpe = ?
         WHERE id = ?`,
-		workout.UserID, workout.Name, workout.Date, workout.Notes, workout.ID,
+		workout.UserID, workout.Name, workout.Date, workout.Timezone, workout.StartedAt, workout.EndedAt,
+		workout.Notes, workout.DurationMinutes, workout.Status, workout.SessionRPE, workout.ID,
 	)
 	if err != nil {
 		return err
@@ -177,33 +242,102 @@ func (m WorkoutModel) Update(workout *Workout) error {
 		return ErrRecordNotFound
 	}
 
-	// Delete existing workout exercises
+	if err := saveWorkoutTags(tx, workout.ID, workout.Tags); err != nil {
+		return err
+	}
+
+	// Delete existing workout exercises and the records they held
+	_, err = tx.Exec("DELETE FROM personal_records WHERE workout_id = ?", workout.ID)
+	if err != nil {
+		return err
+	}
 	_, err = tx.Exec("DELETE FROM workout_exercises WHERE workout_id = ?", workout.ID)
 	if err != nil {
 		return err
 	}
 
 	// Insert new workout exercises
-	for i := range workout.Details {
-		workout.Details[i].WorkoutID = workout.ID
-		result, err := tx.Exec(`
-            INSERT INTO workout_exercises (workout_id, exercise_id, sets, reps, weight)
-            VALUES (?, ?, ?, ?, ?)`,
-			workout.ID,
-			workout.Details[i].ExerciseID,
-			workout.Details[i].Sets,
-			workout.Details[i].Reps,
-			workout.Details[i].Weight,
-		)
-		if err != nil {
-			return err
-		}
+	if err := validateEntries(tx, workout.UserID, workout.Details); err != nil {
+		return err
+	}
+	if err := insertWorkoutExercises(tx, workout); err != nil {
+		return err
+	}
 
-		exerciseID, err := result.LastInsertId()
-		if err != nil {
-			return err
-		}
-		workout.Details[i].ID = exerciseID
+	if err := refreshPersonalRecords(tx, previousUserID, previousExerciseIDs); err != nil {
+		return err
+	}
+	if err := refreshPersonalRecords(tx, workout.UserID, distinctExerciseIDs(workout.Details)); err != nil {
+		return err
+	}
+	if err := attachRecords(tx, workout); err != nil {
+		return err
+	}
+	if err := evaluateGoals(tx, workout.UserID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateSummary modifies the fields of an existing workout without touching
+// its exercises
+func (m WorkoutModel) UpdateSummary(workout *Workout) error {
+	if workout.ID < 1 {
+		return ErrInvalidInput
+	}
+	if err := validateWorkout(workout); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := resolveWorkoutTime(tx, workout); err != nil {
+		return err
+	}
+
+	previousUserID, exerciseIDs, err := workoutExerciseIDs(tx, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+        UPDATE workouts
+        SET user_id = ?, name = ?, date = ?, timezone = ?, started_at = ?, ended_at = ?, notes = ?,
+            duration_minutes = ?, status = ?, session_rpe = ?
+        WHERE id = ?`,
+		workout.UserID, workout.Name, workout.Date, workout.Timezone, workout.StartedAt, workout.EndedAt,
+		workout.Notes, workout.DurationMinutes, workout.Status, workout.SessionRPE, workout.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if err := saveWorkoutTags(tx, workout.ID, workout.Tags); err != nil {
+		return err
+	}
+
+	// The date, status or owner may have changed which records the entries hold
+	if err := refreshPersonalRecords(tx, previousUserID, exerciseIDs); err != nil {
+		return err
+	}
+	if err := refreshPersonalRecords(tx, workout.UserID, exerciseIDs); err != nil {
+		return err
+	}
+	if err := evaluateGoals(tx, workout.UserID); err != nil {
+		return err
 	}
 
 	return tx.Commit()
@@ -221,11 +355,28 @@ func (m WorkoutModel) Delete(id int64) error {
 	}
 	defer tx.Rollback()
 
-	// Delete workout exercises first (due to foreign key)
+	userID, exerciseIDs, err := workoutExerciseIDs(tx, id)
+	if err != nil {
+		return err
+	}
+
+	// Delete records and workout exercises first (due to foreign key)
+	_, err = tx.Exec("DELETE FROM personal_records WHERE workout_id = ?", id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE goals SET achieved_workout_id = NULL WHERE achieved_workout_id = ?", id)
+	if err != nil {
+		return err
+	}
 	_, err = tx.Exec("DELETE FROM workout_exercises WHERE workout_id = ?", id)
 	if err != nil {
 		return err
 	}
+	_, err = tx.Exec("DELETE FROM workout_tags WHERE workout_id = ?", id)
+	if err != nil {
+		return err
+	}
 
 	// Delete workout
 	result, err := tx.Exec("DELETE FROM workouts WHERE id = ?", id)
@@ -241,23 +392,72 @@ func (m WorkoutModel) Delete(id int64) error {
 		return ErrRecordNotFound
 	}
 
+	// Records set in this workout may have been superseding older ones
+	if err := refreshPersonalRecords(tx, userID, exerciseIDs); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-// GetAll retrieves all workouts for a user
-func (m WorkoutModel) GetAll(userID int64) ([]*Workout, error) {
+// workoutList sorts workouts by date, most recent first by default, or by name
+var workoutList = listSpec[*Workout]{
+	sorts: map[string]sortKey[*Workout]{
+		"date": {"date(w.date)", func(w *Workout) interface{} { return w.Date.Format(DateFormat) }},
+		"name": {"w.name", func(w *Workout) interface{} { return w.Name }},
+	},
+	defaultSort: "-date",
+	idColumn:    "w.id",
+	id:          func(w *Workout) int64 { return w.ID },
+}
+
+// GetAll retrieves one page of the workouts of a user that match filter,
+// most recent first unless list sorts them otherwise
+func (m WorkoutModel) GetAll(userID int64, filter WorkoutFilter, list ListQuery) ([]*Workout, *Page, error) {
 	if userID < 1 {
-		return nil, ErrInvalidInput
+		return nil, nil, ErrInvalidInput
+	}
+	if filter.Match == "" {
+		filter.Match = MatchAll
+	}
+	if !ValidMatch(filter.Match) || (filter.Status != "" && !ValidWorkoutStatus(filter.Status)) {
+		return nil, nil, ErrInvalidInput
+	}
+	tags, err := NormalizeTags(filter.Tags)
+	if err != nil {
+		return nil, nil, err
 	}
+	filter.Tags = tags
 
-	// Query all workouts for the user
-	rows, err := m.DB.Query(`
-        SELECT id, user_id, name, date, notes
-        FROM workouts
-        WHERE user_id = ?
-        ORDER BY date DESC`, userID)
+	clauses, err := workoutList.clauses(list)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	query := `
+        SELECT w.id, w.user_id, w.name, w.date, w.timezone, w.started_at, w.ended_at, w.notes,
+               w.duration_minutes, w.status, w.session_rpe
+        FROM workouts w
+        WHERE w.user_id = ?`
+	args := []interface{}{userID}
+
+	if filter.Status != "" {
+		query += " AND w.status = ?"
+		args = append(args, filter.Status)
+	}
+	conditions, conditionArgs := filter.where()
+	query += conditions
+	args = append(args, conditionArgs...)
+	if clauses.where != "" {
+		query += " AND " + clauses.where
+		args = append(args, clauses.args...)
+	}
+	query += clauses.orderBy
+	args = append(args, clauses.limitArgs()...)
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -270,17 +470,310 @@ func (m WorkoutModel) GetAll(userID int64) ([]*Workout, error) {
 			&workout.UserID,
 			&workout.Name,
 			&workout.Date,
+			&workout.Timezone,
+			&workout.StartedAt,
+			&workout.EndedAt,
 			&workout.Notes,
+			&workout.DurationMinutes,
+			&workout.Status,
+			&workout.SessionRPE,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		workouts = append(workouts, workout)
 	}
 
 	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	workouts, page := workoutList.page(clauses, workouts)
+	if err := attachTags(m.DB, workouts); err != nil {
+		return nil, nil, err
+	}
+
+	return workouts, page, nil
+}
+
+// GetLatest retrieves the user's most recent completed workout with its
+// exercises. An empty name matches any workout; otherwise names are compared
+// case-insensitively.
+func (m WorkoutModel) GetLatest(userID int64, name string) (*Workout, error) {
+	if userID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	var id int64
+	err := m.DB.QueryRow(`
+        SELECT id
+        FROM workouts
+        WHERE user_id = ? AND status = ? AND (? = '' OR name = ? COLLATE NOCASE)
+        ORDER BY date DESC, id DESC
+        LIMIT 1`,
+		userID, WorkoutCompleted, name, name,
+	).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
 		return nil, err
 	}
 
-	return workouts, nil
+	return m.GetByID(id)
+}
+
+// Clone returns an unsaved copy of the workout and its exercises on date with
+// the given status. A planned copy drops the duration and session RPE, which
+// describe how the original session went.
+func (workout *Workout) Clone(date time.Time, status string) *Workout {
+	clone := &Workout{
+		UserID:          workout.UserID,
+		Name:            workout.Name,
+		Date:            date,
+		Timezone:        workout.Timezone,
+		Notes:           workout.Notes,
+		DurationMinutes: workout.DurationMinutes,
+		Status:          status,
+		SessionRPE:      workout.SessionRPE,
+		Tags:            workout.Tags,
+		Details:         make([]WorkoutExercise, 0, len(workout.Details)),
+	}
+	if status == WorkoutPlanned {
+		clone.DurationMinutes = nil
+		clone.SessionRPE = nil
+	}
+
+	for _, detail := range workout.Details {
+		clone.Details = append(clone.Details, WorkoutExercise{
+			ExerciseID:      detail.ExerciseID,
+			Sets:            detail.Sets,
+			Reps:            detail.Reps,
+			Weight:          detail.Weight,
+			EnteredWeight:   detail.EnteredWeight,
+			EnteredUnit:     detail.EnteredUnit,
+			RPE:             detail.RPE,
+			DurationSeconds: detail.DurationSeconds,
+			DistanceMeters:  detail.DistanceMeters,
+			Notes:           detail.Notes,
+			GroupID:         detail.GroupID,
+			GroupType:       detail.GroupType,
+		})
+	}
+
+	return clone
+}
+
+// insertWorkoutExercises inserts the details of a workout in order, recording
+// each entry's index as its position
+func insertWorkoutExercises(q dbtx, workout *Workout) error {
+	for i := range workout.Details {
+		detail := &workout.Details[i]
+		detail.WorkoutID = workout.ID
+		detail.Position = i
+		normalizeWeight(detail)
+
+		result, err := q.Exec(`
+            INSERT INTO workout_exercises (
+                workout_id, exercise_id, sets, reps, weight, weight_input, weight_unit, rpe, notes,
+                position, group_id, group_type, duration_seconds, distance_meters
+            )
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			workout.ID,
+			detail.ExerciseID,
+			detail.Sets,
+			detail.Reps,
+			detail.Weight,
+			detail.EnteredWeight,
+			detail.EnteredUnit,
+			detail.RPE,
+			detail.Notes,
+			detail.Position,
+			detail.GroupID,
+			nullString(detail.GroupType),
+			detail.DurationSeconds,
+			detail.DistanceMeters,
+		)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		detail.ID = id
+		derivePace(detail)
+	}
+
+	return nil
+}
+
+// Reorder sets the order of a workout's exercises to match workoutExerciseIDs,
+// which must list every exercise in the workout exactly once
+func (m WorkoutModel) Reorder(workoutID int64, workoutExerciseIDs []int64) error {
+	if workoutID < 1 || len(workoutExerciseIDs) == 0 {
+		return ErrInvalidInput
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM workouts WHERE id = ?)", workoutID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRecordNotFound
+	}
+
+	details, err := workoutDetails(tx, workoutID)
+	if err != nil {
+		return err
+	}
+	if len(details) != len(workoutExerciseIDs) {
+		return ErrInvalidInput
+	}
+
+	byID := map[int64]WorkoutExercise{}
+	for _, detail := range details {
+		byID[detail.ID] = detail
+	}
+
+	reordered := make([]WorkoutExercise, 0, len(details))
+	for position, id := range workoutExerciseIDs {
+		detail, ok := byID[id]
+		if !ok {
+			return ErrInvalidInput
+		}
+		delete(byID, id)
+
+		detail.Position = position
+		reordered = append(reordered, detail)
+
+		if _, err := tx.Exec(
+			"UPDATE workout_exercises SET position = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			position, id,
+		); err != nil {
+			return err
+		}
+	}
+
+	// Members of a group must stay next to each other
+	if err := validateGroups(reordered); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// workoutDetails loads the exercises of a workout in their stored order
+func workoutDetails(q dbtx, workoutID int64) ([]WorkoutExercise, error) {
+	rows, err := q.Query(`
+        SELECT id, exercise_id, position, group_id, group_type
+        FROM workout_exercises
+        WHERE workout_id = ?
+        ORDER BY position, id`, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var details []WorkoutExercise
+	for rows.Next() {
+		detail := WorkoutExercise{WorkoutID: workoutID}
+		var groupType sql.NullString
+		if err := rows.Scan(&detail.ID, &detail.ExerciseID, &detail.Position, &detail.GroupID, &groupType); err != nil {
+			return nil, err
+		}
+		detail.GroupType = groupType.String
+		details = append(details, detail)
+	}
+
+	return details, rows.Err()
+}
+
+// validateWorkout checks the fields every workout write shares, defaulting the
+// status to completed and normalizing the tags
+func validateWorkout(workout *Workout) error {
+	if workout.UserID < 1 || workout.Name == "" {
+		return ErrInvalidInput
+	}
+	if workout.DurationMinutes != nil && *workout.DurationMinutes < 0 {
+		return ErrInvalidInput
+	}
+	if !validRPE(workout.SessionRPE) {
+		return ErrInvalidInput
+	}
+	if workout.Status == "" {
+		workout.Status = WorkoutCompleted
+	}
+	if !ValidWorkoutStatus(workout.Status) {
+		return ErrInvalidInput
+	}
+	tags, err := NormalizeTags(workout.Tags)
+	if err != nil {
+		return err
+	}
+	workout.Tags = tags
+	return nil
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// resolveWorkoutTime defaults the timezone of a workout to its user's and
+// checks its local date against its start and end instants. A workout given
+// only a start takes its date from the start's local day, and one with both
+// instants but no duration takes the elapsed time between them, which stays
+// correct across daylight saving changes.
+func resolveWorkoutTime(q dbtx, workout *Workout) error {
+	if workout.Timezone == "" {
+		workout.Timezone = userLocation(q, workout.UserID).String()
+	}
+	if !ValidTimezone(workout.Timezone) {
+		return ErrInvalidInput
+	}
+	location, err := time.LoadLocation(workout.Timezone)
+	if err != nil {
+		return ErrInvalidInput
+	}
+
+	if workout.StartedAt != nil {
+		started := workout.StartedAt.UTC()
+		workout.StartedAt = &started
+
+		day := CivilDate(started.In(location))
+		if workout.Date.IsZero() {
+			workout.Date = day
+		} else if !CivilDate(workout.Date).Equal(day) {
+			return ErrInvalidInput
+		}
+	}
+
+	if workout.EndedAt != nil {
+		if workout.StartedAt == nil || !workout.EndedAt.After(*workout.StartedAt) {
+			return ErrInvalidInput
+		}
+		ended := workout.EndedAt.UTC()
+		workout.EndedAt = &ended
+
+		if workout.DurationMinutes == nil {
+			minutes := int(ended.Sub(*workout.StartedAt).Round(time.Minute).Minutes())
+			workout.DurationMinutes = &minutes
+		}
+	}
+
+	if workout.Date.IsZero() {
+		return ErrInvalidInput
+	}
+	workout.Date = CivilDate(workout.Date)
+	return nil
 }
@@ -7,15 +7,26 @@ import (
 
 // WorkoutExercise represents a junction between workouts and exercises with additional metadata
 type WorkoutExercise struct {
-	ID         int64     `json:"id"`
-	WorkoutID  int64     `json:"workout_id"`
-	ExerciseID int64     `json:"exercise_id"`
-	Sets       int       `json:"sets"`
-	Reps       int       `json:"reps"`
-	Weight     *float64  `json:"weight,omitempty"` // Pointer to allow NULL in database
-	Notes      string    `json:"notes,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID               int64     `json:"id"`
+	WorkoutID        int64     `json:"workout_id"`
+	ExerciseID       int64     `json:"exercise_id"`
+	Sets             int       `json:"sets"`
+	Reps             int       `json:"reps"`
+	Weight           *float64  `json:"weight,omitempty"` // Pointer to allow NULL in database; kilograms unless Unit says otherwise
+	Unit             string    `json:"unit,omitempty"`   // Unit of Weight in responses
+	EnteredWeight    *float64  `json:"-"`                // Weight as the user entered it
+	EnteredUnit      string    `json:"-"`                // Unit the user entered the weight in
+	RPE              *float64  `json:"rpe,omitempty"`    // Rate of perceived exertion, 1-10
+	DurationSeconds  *int      `json:"duration_seconds,omitempty"`
+	DistanceMeters   *float64  `json:"distance_meters,omitempty"`
+	PaceSecondsPerKm *float64  `json:"pace_seconds_per_km,omitempty"` // Derived from distance and duration
+	Notes            string    `json:"notes,omitempty"`
+	Records          []string  `json:"records,omitempty"` // Personal record types set by this entry
+	Position         int       `json:"position"`
+	GroupID          *int64    `json:"group_id,omitempty"`   // Entries sharing a group ID are performed together
+	GroupType        string    `json:"group_type,omitempty"` // superset, circuit or giant_set
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 	// Include nested structs for related data
 	Exercise *Exercise `json:"exercise,omitempty"`
 }
@@ -25,6 +36,77 @@ type WorkoutExerciseModel struct {
 	DB *sql.DB
 }
 
+// workoutExerciseColumns selects an entry joined with its exercise, in the
+// order scanWorkoutExercise expects
+const workoutExerciseColumns = `
+			we.id, we.workout_id, we.exercise_id, we.sets, we.reps,
+			we.weight, we.weight_input, we.weight_unit, we.rpe, we.notes,
+			we.position, we.group_id, we.group_type,
+			we.duration_seconds, we.distance_meters, we.created_at, we.updated_at,
+			e.name, e.description, e.body_part_id, e.tracking_type, e.visibility`
+
+func scanWorkoutExercise(row interface{ Scan(...interface{}) error }) (*WorkoutExercise, error) {
+	we := &WorkoutExercise{
+		Exercise: &Exercise{},
+	}
+	var notes, groupType sql.NullString
+	err := row.Scan(
+		&we.ID,
+		&we.WorkoutID,
+		&we.ExerciseID,
+		&we.Sets,
+		&we.Reps,
+		&we.Weight,
+		&we.EnteredWeight,
+		&we.EnteredUnit,
+		&we.RPE,
+		&notes,
+		&we.Position,
+		&we.GroupID,
+		&groupType,
+		&we.DurationSeconds,
+		&we.DistanceMeters,
+		&we.CreatedAt,
+		&we.UpdatedAt,
+		&we.Exercise.Name,
+		&we.Exercise.Description,
+		&we.Exercise.BodyPartID,
+		&we.Exercise.TrackingType,
+		&we.Exercise.Visibility,
+	)
+	if err != nil {
+		return nil, err
+	}
+	we.Notes = notes.String
+	we.GroupType = groupType.String
+	we.Exercise.ID = we.ExerciseID
+	we.Unit = UnitKilograms
+	derivePace(we)
+	return we, nil
+}
+
+// GetByID retrieves a single workout exercise with its exercise details
+func (m WorkoutExerciseModel) GetByID(id int64) (*WorkoutExercise, error) {
+	if id < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	we, err := scanWorkoutExercise(m.DB.QueryRow(`
+		SELECT`+workoutExerciseColumns+`
+		FROM workout_exercises we
+		JOIN exercises e ON we.exercise_id = e.id
+		WHERE we.id = ?`, id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return we, nil
+}
+
 // GetByWorkoutID retrieves all exercises for a specific workout
 func (m WorkoutExerciseModel) GetByWorkoutID(workoutID int64) ([]*WorkoutExercise, error) {
 	if workoutID < 1 {
@@ -33,14 +115,11 @@ func (m WorkoutExerciseModel) GetByWorkoutID(workoutID int64) ([]*WorkoutExercis
 
 	// Join with exercises table to get exercise details
 	rows, err := m.DB.Query(`
-		SELECT 
-			we.id, we.workout_id, we.exercise_id, we.sets, we.reps, 
-			we.weight, we.notes, we.created_at, we.updated_at,
-			e.name, e.description, e.body_part_id
+		SELECT`+workoutExerciseColumns+`
 		FROM workout_exercises we
 		JOIN exercises e ON we.exercise_id = e.id
 		WHERE we.workout_id = ?
-		ORDER BY we.id`, workoutID,
+		ORDER BY we.position, we.id`, workoutID,
 	)
 	if err != nil {
 		return nil, err
@@ -50,27 +129,10 @@ func (m WorkoutExerciseModel) GetByWorkoutID(workoutID int64) ([]*WorkoutExercis
 	var workoutExercises []*WorkoutExercise
 
 	for rows.Next() {
-		we := &WorkoutExercise{
-			Exercise: &Exercise{},
-		}
-		err := rows.Scan(
-			&we.ID,
-			&we.WorkoutID,
-			&we.ExerciseID,
-			&we.Sets,
-			&we.Reps,
-			&we.Weight,
-			&we.Notes,
-			&we.CreatedAt,
-			&we.UpdatedAt,
-			&we.Exercise.Name,
-			&we.Exercise.Description,
-			&we.Exercise.BodyPartID,
-		)
+		we, err := scanWorkoutExercise(rows)
 		if err != nil {
 			return nil, err
 		}
-		we.Exercise.ID = we.ExerciseID
 		workoutExercises = append(workoutExercises, we)
 	}
 
@@ -83,14 +145,50 @@ func (m WorkoutExerciseModel) GetByWorkoutID(workoutID int64) ([]*WorkoutExercis
 
 // Create adds a new exercise to a workout
 func (m WorkoutExerciseModel) Create(we *WorkoutExercise) error {
-	if we.WorkoutID < 1 || we.ExerciseID < 1 || we.Sets < 1 || we.Reps < 1 {
+	if we.WorkoutID < 1 || we.ExerciseID < 1 {
 		return ErrInvalidInput
 	}
+	if err := validateGroups([]WorkoutExercise{*we}); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	err = tx.QueryRow("SELECT user_id FROM workouts WHERE id = ?", we.WorkoutID).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRecordNotFound
+		}
+		return err
+	}
 
-	result, err := m.DB.Exec(`
-		INSERT INTO workout_exercises (workout_id, exercise_id, sets, reps, weight, notes)
-		VALUES (?, ?, ?, ?, ?, ?)`,
-		we.WorkoutID, we.ExerciseID, we.Sets, we.Reps, we.Weight, we.Notes,
+	if err := validateEntries(tx, userID, []WorkoutExercise{*we}); err != nil {
+		return err
+	}
+	normalizeWeight(we)
+
+	// New entries go to the end of the workout
+	err = tx.QueryRow(
+		"SELECT COALESCE(MAX(position) + 1, 0) FROM workout_exercises WHERE workout_id = ?",
+		we.WorkoutID,
+	).Scan(&we.Position)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO workout_exercises (
+			workout_id, exercise_id, sets, reps, weight, weight_input, weight_unit, rpe, notes,
+			position, group_id, group_type, duration_seconds, distance_meters
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		we.WorkoutID, we.ExerciseID, we.Sets, we.Reps, we.Weight, we.EnteredWeight, we.EnteredUnit, we.RPE, we.Notes,
+		we.Position, we.GroupID, nullString(we.GroupType), we.DurationSeconds, we.DistanceMeters,
 	)
 	if err != nil {
 		return err
@@ -102,20 +200,57 @@ func (m WorkoutExerciseModel) Create(we *WorkoutExercise) error {
 	}
 
 	we.ID = id
-	return nil
+	derivePace(we)
+
+	if err := validateWorkoutGroups(tx, we.WorkoutID); err != nil {
+		return err
+	}
+
+	if err := refreshPersonalRecords(tx, userID, []int64{we.ExerciseID}); err != nil {
+		return err
+	}
+	if err := evaluateGoals(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // Update modifies an existing workout exercise
 func (m WorkoutExerciseModel) Update(we *WorkoutExercise) error {
-	if we.ID < 1 || we.Sets < 1 || we.Reps < 1 {
+	if we.ID < 1 {
 		return ErrInvalidInput
 	}
+	if err := validateGroups([]WorkoutExercise{*we}); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, exerciseID, err := workoutExerciseOwner(tx, we.ID)
+	if err != nil {
+		return err
+	}
+
+	// The exercise of an entry cannot be changed, only what was logged for it
+	we.ExerciseID = exerciseID
+	if err := validateEntries(tx, userID, []WorkoutExercise{*we}); err != nil {
+		return err
+	}
+	normalizeWeight(we)
 
-	result, err := m.DB.Exec(`
+	result, err := tx.Exec(`
 		UPDATE workout_exercises 
-		SET sets = ?, reps = ?, weight = ?, notes = ?, updated_at = CURRENT_TIMESTAMP
+		SET sets = ?, reps = ?, weight = ?, weight_input = ?, weight_unit = ?, rpe = ?, notes = ?,
+		    group_id = ?, group_type = ?, duration_seconds = ?, distance_meters = ?,
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?`,
-		we.Sets, we.Reps, we.Weight, we.Notes, we.ID,
+		we.Sets, we.Reps, we.Weight, we.EnteredWeight, we.EnteredUnit, we.RPE, we.Notes,
+		we.GroupID, nullString(we.GroupType), we.DurationSeconds, we.DistanceMeters, we.ID,
 	)
 	if err != nil {
 		return err
@@ -129,7 +264,24 @@ func (m WorkoutExerciseModel) Update(we *WorkoutExercise) error {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	derivePace(we)
+
+	var workoutID int64
+	if err := tx.QueryRow("SELECT workout_id FROM workout_exercises WHERE id = ?", we.ID).Scan(&workoutID); err != nil {
+		return err
+	}
+	if err := validateWorkoutGroups(tx, workoutID); err != nil {
+		return err
+	}
+
+	if err := refreshPersonalRecords(tx, userID, []int64{exerciseID}); err != nil {
+		return err
+	}
+	if err := evaluateGoals(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // Delete removes an exercise from a workout
@@ -138,7 +290,24 @@ func (m WorkoutExerciseModel) Delete(id int64) error {
 		return ErrInvalidInput
 	}
 
-	result, err := m.DB.Exec("DELETE FROM workout_exercises WHERE id = ?", id)
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, exerciseID, err := workoutExerciseOwner(tx, id)
+	if err != nil {
+		return err
+	}
+
+	// Records pointing at this entry are rebuilt below
+	_, err = tx.Exec("DELETE FROM personal_records WHERE workout_exercise_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM workout_exercises WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
@@ -151,7 +320,11 @@ func (m WorkoutExerciseModel) Delete(id int64) error {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	if err := refreshPersonalRecords(tx, userID, []int64{exerciseID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // DeleteAllForWorkout removes all exercises from a specific workout
@@ -160,7 +333,23 @@ func (m WorkoutExerciseModel) DeleteAllForWorkout(workoutID int64) error {
 		return ErrInvalidInput
 	}
 
-	result, err := m.DB.Exec("DELETE FROM workout_exercises WHERE workout_id = ?", workoutID)
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, exerciseIDs, err := workoutExerciseIDs(tx, workoutID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM personal_records WHERE workout_id = ?", workoutID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM workout_exercises WHERE workout_id = ?", workoutID)
 	if err != nil {
 		return err
 	}
@@ -173,5 +362,72 @@ func (m WorkoutExerciseModel) DeleteAllForWorkout(workoutID int64) error {
 		return ErrRecordNotFound
 	}
 
-	return nil
+	if err := refreshPersonalRecords(tx, userID, exerciseIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// workoutExerciseOwner returns the user and exercise of a workout exercise entry
+func workoutExerciseOwner(q dbtx, id int64) (userID, exerciseID int64, err error) {
+	err = q.QueryRow(`
+		SELECT w.user_id, we.exercise_id
+		FROM workout_exercises we
+		JOIN workouts w ON we.workout_id = w.id
+		WHERE we.id = ?`, id,
+	).Scan(&userID, &exerciseID)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrRecordNotFound
+	}
+	return userID, exerciseID, err
+}
+
+// workoutExerciseIDs returns the owner of a workout and the distinct exercises it contains
+func workoutExerciseIDs(q dbtx, workoutID int64) (int64, []int64, error) {
+	var userID int64
+	err := q.QueryRow("SELECT user_id FROM workouts WHERE id = ?", workoutID).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, ErrRecordNotFound
+		}
+		return 0, nil, err
+	}
+
+	rows, err := q.Query("SELECT DISTINCT exercise_id FROM workout_exercises WHERE workout_id = ?", workoutID)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var exerciseIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, nil, err
+		}
+		exerciseIDs = append(exerciseIDs, id)
+	}
+
+	return userID, exerciseIDs, rows.Err()
+}
+
+// ExercisePerformance is a workout exercise entry along with the date of its workout
+type ExercisePerformance struct {
+	WorkoutExercise
+	WorkoutDate time.Time `json:"workout_date"`
+}
+
+// GetHistoryForUser retrieves every logged entry of an exercise by a user, oldest first
+func (m WorkoutExerciseModel) GetHistoryForUser(userID, exerciseID int64) ([]*ExercisePerformance, error) {
+	if userID < 1 || exerciseID < 1 {
+		return nil, ErrInvalidInput
+	}
+
+	return exerciseHistory(m.DB, userID, exerciseID)
+}
+
+// validRPE reports whether an optional RPE value is on the 1-10 scale
+func validRPE(rpe *float64) bool {
+	return rpe == nil || (*rpe >= 1 && *rpe <= 10)
 }
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"strings"
+	"time"
+)
+
+// How the conditions of a workout filter combine
+const (
+	MatchAll = "all" // Every condition must hold
+	MatchAny = "any" // At least one condition must hold
+)
+
+// WorkoutFilter narrows the workouts listed for a user. Each tag, exercise and
+// body part is a separate condition, as are the date range, name and minimum
+// volume; Match decides whether they are combined with AND or OR. Status
+// always applies.
+type WorkoutFilter struct {
+	Tags        []string
+	From        time.Time // Inclusive; zero for no lower bound
+	To          time.Time // Inclusive; zero for no upper bound
+	Name        string    // Case-insensitive substring of the workout name
+	ExerciseIDs []int64   // Workouts containing the exercise
+	BodyPartIDs []int64   // Workouts containing an exercise for the body part or one below it
+	MinVolume   *float64  // Minimum tonnage in kilograms
+	Status      string
+	Match       string // MatchAll (default) or MatchAny
+}
+
+// ValidMatch reports whether match is a known way of combining conditions
+func ValidMatch(match string) bool {
+	return match == MatchAll || match == MatchAny
+}
+
+// where builds the SQL conditions of the filter against workouts aliased as w
+func (filter WorkoutFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM workout_tags t WHERE t.workout_id = w.id AND t.tag = ?)")
+		args = append(args, tag)
+	}
+
+	// The two ends of the date range form a single condition
+	var dateRange []string
+	if !filter.From.IsZero() {
+		dateRange = append(dateRange, "date(w.date) >= ?")
+		args = append(args, filter.From.Format(DateFormat))
+	}
+	if !filter.To.IsZero() {
+		dateRange = append(dateRange, "date(w.date) <= ?")
+		args = append(args, filter.To.Format(DateFormat))
+	}
+	if len(dateRange) > 0 {
+		conditions = append(conditions, "("+strings.Join(dateRange, " AND ")+")")
+	}
+
+	if filter.Name != "" {
+		conditions = append(conditions, `w.name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(filter.Name)+"%")
+	}
+	for _, exerciseID := range filter.ExerciseIDs {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM workout_exercises we
+			WHERE we.workout_id = w.id AND we.exercise_id = ?)`)
+		args = append(args, exerciseID)
+	}
+	for _, bodyPartID := range filter.BodyPartIDs {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM workout_exercises we
+			JOIN exercises e ON e.id = we.exercise_id
+			WHERE we.workout_id = w.id AND e.body_part_id IN (`+bodyPartSubtree+`))`)
+		args = append(args, bodyPartID)
+	}
+	if filter.MinVolume != nil {
+		conditions = append(conditions, `(
+			SELECT COALESCE(SUM(we.sets * we.reps * we.weight), 0) FROM workout_exercises we
+			WHERE we.workout_id = w.id) >= ?`)
+		args = append(args, *filter.MinVolume)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	operator := " AND "
+	if filter.Match == MatchAny {
+		operator = " OR "
+	}
+	return " AND (" + strings.Join(conditions, operator) + ")", args
+}
+
+// escapeLike escapes the LIKE wildcards in s, using backslash as the escape
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
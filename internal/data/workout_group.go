@@ -0,0 +1,57 @@
+package data
+
+// Group types for exercises performed together within a workout
+const (
+	GroupSuperset = "superset"
+	GroupCircuit  = "circuit"
+	GroupGiantSet = "giant_set"
+)
+
+func validGroupType(groupType string) bool {
+	switch groupType {
+	case GroupSuperset, GroupCircuit, GroupGiantSet:
+		return true
+	}
+	return false
+}
+
+// validateGroups checks the grouping of a workout's entries, given in order.
+// A group ID always comes with a group type, every member of a group shares
+// the same type, and members of a group sit next to each other.
+func validateGroups(details []WorkoutExercise) error {
+	groupTypes := map[int64]string{}
+	var previous *int64
+
+	for _, detail := range details {
+		if (detail.GroupID == nil) != (detail.GroupType == "") {
+			return ErrInvalidGrouping
+		}
+		if detail.GroupID == nil {
+			previous = nil
+			continue
+		}
+		if !validGroupType(detail.GroupType) {
+			return ErrInvalidGrouping
+		}
+
+		id := *detail.GroupID
+		if groupType, seen := groupTypes[id]; seen {
+			if groupType != detail.GroupType || previous == nil || *previous != id {
+				return ErrInvalidGrouping
+			}
+		}
+		groupTypes[id] = detail.GroupType
+		previous = &id
+	}
+
+	return nil
+}
+
+// validateWorkoutGroups checks the stored grouping of a workout
+func validateWorkoutGroups(q dbtx, workoutID int64) error {
+	details, err := workoutDetails(q, workoutID)
+	if err != nil {
+		return err
+	}
+	return validateGroups(details)
+}
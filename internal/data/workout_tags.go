@@ -0,0 +1,84 @@
+package data
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxTagLength bounds a single workout tag, in characters
+const maxTagLength = 50
+
+// NormalizeTags trims, lowercases, de-duplicates and sorts tags, collapsing
+// runs of whitespace inside a tag to a single space. Blank and overlong tags
+// are invalid.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || len([]rune(tag)) > maxTagLength {
+			return nil, ErrInvalidInput
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// saveWorkoutTags replaces the tags of a workout
+func saveWorkoutTags(q dbtx, workoutID int64, tags []string) error {
+	_, err := q.Exec("DELETE FROM workout_tags WHERE workout_id = ?", workoutID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err := q.Exec("INSERT INTO workout_tags (workout_id, tag) VALUES (?, ?)", workoutID, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachTags loads the tags of each workout in one query
+func attachTags(q dbtx, workouts []*Workout) error {
+	if len(workouts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Workout, len(workouts))
+	ids := make([]int64, len(workouts))
+	for i, workout := range workouts {
+		byID[workout.ID] = workout
+		ids[i] = workout.ID
+	}
+
+	placeholders, args := inClause(ids)
+	rows, err := q.Query(`
+		SELECT workout_id, tag
+		FROM workout_tags
+		WHERE workout_id IN (`+placeholders+`)
+		ORDER BY workout_id, tag`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID int64
+		var tag string
+		if err := rows.Scan(&workoutID, &tag); err != nil {
+			return err
+		}
+		byID[workoutID].Tags = append(byID[workoutID].Tags, tag)
+	}
+
+	return rows.Err()
+}
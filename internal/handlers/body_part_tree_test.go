@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestBodyPartTree(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.CreateBodyPart(rr, httptest.NewRequest("POST", "/api/body-parts",
+		bytes.NewBufferString(`{"name": "Upper Chest", "parent_id": 1}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data bodyPartResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	upperChest := strconv.FormatInt(created.Data.ID, 10)
+
+	rr = httptest.NewRecorder()
+	h.CreateBodyPart(rr, httptest.NewRequest("POST", "/api/body-parts",
+		bytes.NewBufferString(`{"name": "Obliques", "parent_id": 999}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Regions contain groups, which contain sub-groups
+	rr = httptest.NewRecorder()
+	h.GetBodyPartTree(rr, httptest.NewRequest("GET", "/api/body-parts/tree", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var tree struct {
+		Data []data.BodyPart `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&tree); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	var roots []string
+	var upperBody *data.BodyPart
+	for i, root := range tree.Data {
+		roots = append(roots, root.Name)
+		if root.Name == "Upper Body" {
+			upperBody = &tree.Data[i]
+		}
+	}
+	if len(roots) != 3 || upperBody == nil {
+		t.Fatalf("expected the Core, Lower Body and Upper Body regions at the top: got %v", roots)
+	}
+	var chest *data.BodyPart
+	for _, child := range upperBody.Children {
+		if child.Name == "Chest" {
+			chest = child
+		}
+	}
+	if len(upperBody.Children) != 4 || chest == nil || len(chest.Children) != 1 || chest.Children[0].Name != "Upper Chest" {
+		t.Errorf("Upper Body should contain Chest, which contains Upper Chest: %+v", upperBody.Children)
+	}
+
+	// Listing a body part's exercises includes those of its sub-groups
+	rr = httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Low-to-High Fly", "body_part_id": `+upperChest+`}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	for _, query := range []string{"&body_part_id=1", "&body_part_id=" + strconv.FormatInt(upperBody.ID, 10)} {
+		names := map[string]bool{}
+		for _, name := range listExercises(t, h, query) {
+			names[name] = true
+		}
+		if !names["Low-to-High Fly"] || !names["Bench Press"] {
+			t.Errorf("%s should list the exercises of sub-groups: %v", query, names)
+		}
+	}
+
+	// A body part cannot be nested under itself or its descendants
+	update := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/body-parts/"+id, bytes.NewBufferString(body))
+		req = withURLParams(req, "id", id)
+		rr := httptest.NewRecorder()
+		h.UpdateBodyPart(rr, req)
+		return rr
+	}
+	upperBodyID := strconv.FormatInt(upperBody.ID, 10)
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+	}{
+		{"Under a descendant", upperBodyID, `{"name": "Upper Body", "parent_id": ` + upperChest + `}`, http.StatusBadRequest},
+		{"Under itself", "1", `{"name": "Chest", "parent_id": 1}`, http.StatusBadRequest},
+		{"Unknown parent", "1", `{"name": "Chest", "parent_id": 999}`, http.StatusBadRequest},
+		{"Duplicate name", "1", `{"name": "Back", "parent_id": ` + upperBodyID + `}`, http.StatusConflict},
+		{"Unknown body part", "999", `{"name": "Neck"}`, http.StatusNotFound},
+		{"Moved to the top", "6", `{"name": "Core", "parent_id": null}`, http.StatusOK},
+		{"Moved under a region", "6", `{"name": "Core", "parent_id": ` + upperBodyID + `}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := update(tt.id, tt.body); rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+
+	// Leaving the parent out, as clients from before the tree do, keeps it
+	rr = update("6", `{"name": "Midsection"}`)
+	var renamed struct {
+		Data bodyPartResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&renamed); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if stored, err := h.models.BodyParts.GetByID(6); err != nil || stored.Name != "Midsection" ||
+		stored.ParentID == nil || *stored.ParentID != upperBody.ID {
+		t.Errorf("rename without parent_id moved the body part: %+v, %v", stored, err)
+	}
+	if renamed.Data.ParentID == nil || *renamed.Data.ParentID != upperBody.ID {
+		t.Errorf("wrong parent in response: %+v", renamed.Data)
+	}
+
+	// Regions with sub-groups cannot be deleted
+	req := withURLParams(httptest.NewRequest("DELETE", "/api/body-parts/"+upperBodyID, nil), "id", upperBodyID)
+	rr = httptest.NewRecorder()
+	h.DeleteBodyPart(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+	}
+}
@@ -4,16 +4,39 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
 	"strconv"
 
+	"repup/internal/data"
+
 	"github.com/go-chi/chi/v5"
 )
 
 // bodyPartRequest represents the expected request body for creating/updating a body part
 type bodyPartRequest struct {
 	Name string `json:"name"`
+	// Omit for a top-level body part. On update, omitting it keeps the parent
+	// and null moves the body part to the top level.
+	ParentID optionalID `json:"parent_id"`
+}
+
+// optionalID is a nullable ID member that records whether it was sent at all,
+// telling an explicit null apart from an absent member
+type optionalID struct {
+	Set   bool
+	Value *int64
+}
+
+func (o *optionalID) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	return json.Unmarshal(b, &o.Value)
+}
+
+// bodyPartResponse is a body part with its place in the tree
+type bodyPartResponse struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parent_id"`
 }
 
 // ////////////////////////////////////////////////////////
@@ -28,15 +51,12 @@ func (h *Handlers) GetBodyPart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Query the database
-	var bodyPart struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
-	}
+	var bodyPart bodyPartResponse
 
 	err = h.db.QueryRow(
-		"SELECT id, name FROM body_parts WHERE id = ?",
+		"SELECT id, name, parent_id FROM body_parts WHERE id = ?",
 		id,
-	).Scan(&bodyPart.ID, &bodyPart.Name)
+	).Scan(&bodyPart.ID, &bodyPart.Name, &bodyPart.ParentID)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -50,42 +70,55 @@ func (h *Handlers) GetBodyPart(w http.ResponseWriter, r *http.Request) {
 	h.respondWithJSON(w, http.StatusOK, bodyPart)
 }
 
-// ListBodyParts handles GET requests for all body parts
+// ListBodyParts handles GET requests for body parts. parent_id lists the
+// sub-groups of one body part, top_level=true the regions, and q searches
+// names; results are paged with limit, sort and cursor.
 func (h *Handlers) ListBodyParts(w http.ResponseWriter, r *http.Request) {
-	// Query all body parts
-	rows, err := h.db.Query("SELECT id, name FROM body_parts ORDER BY name")
+	query := r.URL.Query()
+	filter := data.BodyPartFilter{Search: query.Get("q")}
+	if topLevel := query.Get("top_level"); topLevel != "" {
+		value, err := strconv.ParseBool(topLevel)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid top_level: must be true or false")
+			return
+		}
+		filter.TopLevel = value
+	}
+	if err := parseIDParam(query.Get("parent_id"), &filter.ParentID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid parent_id format")
+		return
+	}
+
+	list, err := parseListQuery(r)
 	if err != nil {
-		log.Printf("Database error in ListBodyParts: %v", err)
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer rows.Close() // Important: always close rows!
 
-	var bodyParts []struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
+	bodyParts, page, err := h.models.BodyParts.List(filter, list)
+	if err != nil {
+		h.respondWithListError(w, err, "name or id")
+		return
 	}
 
-	// Iterate through the rows
-	for rows.Next() {
-		var bp struct {
-			ID   int64  `json:"id"`
-			Name string `json:"name"`
-		}
-		if err := rows.Scan(&bp.ID, &bp.Name); err != nil {
-			h.respondWithError(w, http.StatusInternalServerError, "Row scanning error")
-			return
-		}
-		bodyParts = append(bodyParts, bp)
+	response := make([]bodyPartResponse, len(bodyParts))
+	for i, bodyPart := range bodyParts {
+		response[i] = bodyPartResponse{ID: bodyPart.ID, Name: bodyPart.Name, ParentID: bodyPart.ParentID}
 	}
 
-	// Check for errors from iterating over rows
-	if err := rows.Err(); err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Row iteration error")
+	h.respondWithPage(w, response, page)
+}
+
+// ///////////////////////////////////////////////////////////////////////
+// GetBodyPartTree handles GET requests for the body parts nested as a tree
+func (h *Handlers) GetBodyPartTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.models.BodyParts.Tree()
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, bodyParts)
+	h.respondWithJSON(w, http.StatusOK, tree)
 }
 
 // CreateBodyPart handles POST requests to create a new body part
@@ -102,11 +135,14 @@ func (h *Handlers) CreateBodyPart(w http.ResponseWriter, r *http.Request) {
 		h.respondWithError(w, http.StatusBadRequest, "Name is required")
 		return
 	}
+	if !h.validBodyPartParent(w, req.ParentID.Value) {
+		return
+	}
 
 	// Insert into database
 	result, err := h.db.Exec(
-		"INSERT INTO body_parts (name) VALUES (?)",
-		req.Name,
+		"INSERT INTO body_parts (name, parent_id) VALUES (?, ?)",
+		req.Name, req.ParentID.Value,
 	)
 	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Database error")
@@ -121,12 +157,10 @@ func (h *Handlers) CreateBodyPart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return the created body part
-	bodyPart := struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
-	}{
-		ID:   id,
-		Name: req.Name,
+	bodyPart := bodyPartResponse{
+		ID:       id,
+		Name:     req.Name,
+		ParentID: req.ParentID.Value,
 	}
 
 	h.respondWithJSON(w, http.StatusCreated, bodyPart)
@@ -156,49 +190,48 @@ func (h *Handlers) UpdateBodyPart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// First check if the record exists
-	var exists bool
-	err = h.db.QueryRow("SELECT EXISTS(SELECT 1 FROM body_parts WHERE id = ?)", id).Scan(&exists)
-	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
+	// Clients from before the tree leave the parent out, which keeps it
+	parentID := req.ParentID.Value
+	if !req.ParentID.Set {
+		existing, err := h.models.BodyParts.GetByID(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrInvalidInput):
+				h.respondWithError(w, http.StatusNotFound, "Body part not found")
+			default:
+				h.respondWithError(w, http.StatusInternalServerError, "Database error")
+			}
+			return
+		}
+		parentID = existing.ParentID
 	}
-
-	if !exists {
-		h.respondWithError(w, http.StatusNotFound, "Body part not found")
+	if !h.validBodyPartParent(w, parentID) {
 		return
 	}
 
-	// Update the record
-	result, err := h.db.Exec(
-		"UPDATE body_parts SET name = ? WHERE id = ?",
-		req.Name,
-		id,
-	)
+	// The model refuses to nest the body part under itself or a descendant
+	err = h.models.BodyParts.Update(&data.BodyPart{ID: id, Name: req.Name, ParentID: parentID})
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
-	}
-
-	// Check if the update was successful
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Error checking update status")
-		return
-	}
-
-	if rowsAffected == 0 {
-		h.respondWithError(w, http.StatusNotFound, "Body part not found")
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Body part not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid body part")
+		case errors.Is(err, data.ErrDuplicateRecord):
+			h.respondWithError(w, http.StatusConflict, "A body part with this name already exists")
+		case errors.Is(err, data.ErrBodyPartCycle):
+			h.respondWithError(w, http.StatusBadRequest, "A body part cannot be nested under itself or one of its sub-groups")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
 		return
 	}
 
 	// Return the updated body part
-	bodyPart := struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
-	}{
-		ID:   id,
-		Name: req.Name,
+	bodyPart := bodyPartResponse{
+		ID:       id,
+		Name:     req.Name,
+		ParentID: parentID,
 	}
 
 	h.respondWithJSON(w, http.StatusOK, bodyPart)
@@ -237,6 +270,43 @@ func (h *Handlers) DeleteBodyPart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sub-groups must be moved or deleted first
+	var childCount int
+	err = tx.QueryRow("SELECT COUNT(*) FROM body_parts WHERE parent_id = ?", id).Scan(&childCount)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	if childCount > 0 {
+		h.respondWithError(w, http.StatusConflict,
+			"Cannot delete body part: it has sub-groups")
+		return
+	}
+
+	// Its muscles go with it, unless an exercise works them
+	var muscleUses int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM exercise_muscles em
+		JOIN muscles m ON m.id = em.muscle_id
+		WHERE m.body_part_id = ?`, id).Scan(&muscleUses)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	if muscleUses > 0 {
+		h.respondWithError(w, http.StatusConflict,
+			"Cannot delete body part: its muscles are worked by existing exercises")
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM muscles WHERE body_part_id = ?", id)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
 	// Delete the body part
 	result, err := tx.Exec("DELETE FROM body_parts WHERE id = ?", id)
 	if err != nil {
@@ -266,6 +336,25 @@ func (h *Handlers) DeleteBodyPart(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// validBodyPartParent responds with 400 unless parentID is nil or names an
+// existing body part
+func (h *Handlers) validBodyPartParent(w http.ResponseWriter, parentID *int64) bool {
+	if parentID == nil {
+		return true
+	}
+
+	exists, err := h.bodyPartExists(*parentID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if !exists {
+		h.respondWithError(w, http.StatusBadRequest, "Parent body part not found")
+		return false
+	}
+	return true
+}
+
 // /////////////////////////////////////////////
 // Helper function to check if a body part exists
 func (h *Handlers) bodyPartExists(id int64) (bool, error) {
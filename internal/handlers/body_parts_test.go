@@ -26,14 +26,15 @@ func setupTestHandler(t *testing.T) *Handlers {
 	_, err = db.Exec(`
         CREATE TABLE IF NOT EXISTS body_parts (
             id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT NOT NULL
+            name TEXT NOT NULL,
+            parent_id INTEGER
         )
     `)
 	if err != nil {
 		t.Fatalf("Failed to create test table: %v", err)
 	}
 
-	return NewHandlers(db)
+	return NewHandlers(db, nil)
 }
 
 // cleanup function to clear the test database after each test
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"repup/internal/data"
+)
+
+// ///////////////////////////////////////////////////////////////////////////
+// Calendar handles GET requests for a month of training days with streaks and
+// adherence against the user's weekly target
+func (h *Handlers) Calendar(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	location, err := h.requestLocation(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	today := data.Today(location)
+
+	month := today
+	if m := query.Get("month"); m != "" {
+		month, err = time.Parse("2006-01", m)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid month format: use YYYY-MM")
+			return
+		}
+	}
+
+	settings, err := h.models.Users.GetSettings(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	calendar, err := h.models.Stats.Calendar(userID, month, today, settings.WeeklyTarget)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for i := range calendar.Days {
+		calendar.Days[i].Volume.Tonnage = data.ConvertMeasure(calendar.Days[i].Volume.Tonnage, unit)
+	}
+
+	h.respondWithJSON(w, http.StatusOK, envelope{
+		"timezone": location.String(),
+		"today":    today.Format(data.DateFormat),
+		"calendar": calendar,
+	})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+func TestCalendar(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Target two workouts a week
+	rr := httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1",
+		bytes.NewBufferString(`{"preferred_unit": "kg", "weekly_target": 2}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	// Updates that leave the target out keep it
+	rr = httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1",
+		bytes.NewBufferString(`{"preferred_unit": "kg"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if settings, err := h.models.Users.GetSettings(1); err != nil || settings.WeeklyTarget == nil || *settings.WeeklyTarget != 2 {
+		t.Fatalf("weekly target should be kept when omitted: %+v %v", settings, err)
+	}
+
+	// January 2024 starts on a Monday. Weeks 1, 3 and 4 meet the target; week
+	// 2 has one workout and a planned session that was skipped.
+	for _, day := range []int{1, 3, 8, 15, 17, 22, 24, 29} {
+		createTestWorkout(t, h, day, 1, 3, 5, 60)
+	}
+	createTestWorkout(t, h, 3, 5, 3, 5, 80) // Squats on the same day as bench
+	for _, day := range []int{10, 31} {
+		weight := 200.0
+		planned := &data.Workout{
+			UserID: 1,
+			Name:   "Planned",
+			Date:   time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			Status: data.WorkoutPlanned,
+			Details: []data.WorkoutExercise{
+				{ExerciseID: 1, Sets: 1, Reps: 1, Weight: &weight},
+			},
+		}
+		if err := h.models.Workouts.Create(planned); err != nil {
+			t.Fatalf("Failed to create workout: %v", err)
+		}
+	}
+
+	// Planned workouts do not set records
+	if record := currentRecords(t, h)[data.RecordMaxWeight]; record.Value != 60 {
+		t.Errorf("planned workout counted towards records: %+v", record)
+	}
+
+	today := time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+	target := 2
+	calendar, err := h.models.Stats.Calendar(1, today, today, &target)
+	if err != nil {
+		t.Fatalf("Failed to build calendar: %v", err)
+	}
+
+	if len(calendar.Days) != 31 || calendar.Month != "2024-01" {
+		t.Fatalf("wrong calendar month: %s with %d days", calendar.Month, len(calendar.Days))
+	}
+	statuses := map[int]string{1: data.DayTrained, 2: data.DayRest, 10: data.DayMissed, 31: data.DayPlanned}
+	for day, status := range statuses {
+		if got := calendar.Days[day-1].Status; got != status {
+			t.Errorf("wrong status for January %d: got %s want %s", day, got, status)
+		}
+	}
+
+	third := calendar.Days[2]
+	if len(third.BodyParts) != 2 || third.Volume.Sets != 6 || third.Volume.Tonnage != 3*5*60+3*5*80 {
+		t.Errorf("wrong summary for January 3: %+v", third)
+	}
+
+	if s := calendar.Streaks; s.Current != 2 || s.Longest != 2 {
+		t.Errorf("wrong streaks: %+v", s)
+	}
+
+	a := calendar.Adherence
+	if a == nil {
+		t.Fatal("adherence missing with a weekly target set")
+	}
+	if a.WeeksMet != 3 || a.WeeksEvaluated != 4 || a.Rate != 0.75 || a.Planned != 2 || a.Missed != 1 {
+		t.Errorf("wrong adherence: %+v", a)
+	}
+
+	// The endpoint reports the requested month
+	rr = httptest.NewRecorder()
+	h.Calendar(rr, httptest.NewRequest("GET", "/api/calendar?user_id=1&month=2024-01&tz=Europe/London", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var response struct {
+		Data struct {
+			Timezone string        `json:"timezone"`
+			Calendar data.Calendar `json:"calendar"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if response.Data.Timezone != "Europe/London" || response.Data.Calendar.Days[30].Status != data.DayMissed {
+		t.Errorf("wrong calendar response: %s, January 31 %s", response.Data.Timezone, response.Data.Calendar.Days[30].Status)
+	}
+
+	rr = httptest.NewRecorder()
+	h.Calendar(rr, httptest.NewRequest("GET", "/api/calendar?user_id=1&month=January", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// An explicit null clears the target
+	rr = httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1",
+		bytes.NewBufferString(`{"preferred_unit": "kg", "weekly_target": null}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if settings, err := h.models.Users.GetSettings(1); err != nil || settings.WeeklyTarget != nil {
+		t.Errorf("weekly target should be cleared by null: %+v %v", settings, err)
+	}
+}
@@ -7,14 +7,69 @@ import (
 	"net/http"
 	"strconv"
 
+	"repup/internal/data"
+
 	"github.com/go-chi/chi/v5"
 )
 
+// Page size limits for substitute suggestions
+const (
+	defaultSubstituteLimit = 10
+	maxSubstituteLimit     = 50
+)
+
 // exerciseRequest represents the expected request body for creating/updating an exercise
 type exerciseRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	BodyPartID  int64  `json:"body_part_id"`
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	BodyPartID      int64  `json:"body_part_id"`
+	TrackingType    string `json:"tracking_type"`    // Defaults to weight_reps
+	MovementPattern string `json:"movement_pattern"` // Optional, such as hinge or vertical_pull
+	VariationOf     *int64 `json:"variation_of"`     // Optional exercise this one is a variation of
+	Visibility      string `json:"visibility"`       // global, team or private; private when created, kept when left out
+
+	// Muscles worked by the exercise. When left out on create the body part's
+	// first muscle becomes the primary muscle; on update the muscles are kept.
+	Muscles []data.ExerciseMuscle `json:"muscles"`
+
+	// Equipment the exercise needs. Left out on update, it is kept.
+	EquipmentIDs []int64 `json:"equipment_ids"`
+
+	// Other names the exercise is searched by. Left out on update, they are kept.
+	Aliases []string `json:"aliases"`
+}
+
+// exerciseResponse is an exercise with its body part, the muscles it works,
+// the equipment it needs and its aliases
+type exerciseResponse struct {
+	ID              int64                 `json:"id"`
+	Name            string                `json:"name"`
+	Description     string                `json:"description"`
+	BodyPartID      int64                 `json:"body_part_id"`
+	TrackingType    string                `json:"tracking_type"`
+	MovementPattern string                `json:"movement_pattern,omitempty"`
+	VariationOf     *int64                `json:"variation_of,omitempty"`
+	OwnerUserID     *int64                `json:"owner_user_id,omitempty"`
+	Visibility      string                `json:"visibility"`
+	BodyPart        *exerciseBodyPart     `json:"body_part,omitempty"`
+	Muscles         []data.ExerciseMuscle `json:"muscles"`
+	Equipment       []data.Equipment      `json:"equipment"`
+	Aliases         []string              `json:"aliases"`
+}
+
+type exerciseBodyPart struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// substituteResponse is a suggested substitute with how closely it matches
+type substituteResponse struct {
+	exerciseResponse
+	Score            float64 `json:"score"`
+	MuscleOverlap    float64 `json:"muscle_overlap"`
+	SamePattern      bool    `json:"same_pattern"`
+	EquipmentOverlap float64 `json:"equipment_overlap"`
+	Variation        bool    `json:"variation"`
 }
 
 // ////////////////////////////////////////////////////////
@@ -28,128 +83,169 @@ func (h *Handlers) GetExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Query the database
-	var exercise struct {
-		ID          int64  `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		BodyPartID  int64  `json:"body_part_id"`
-		BodyPart    struct {
-			ID   int64  `json:"id"`
-			Name string `json:"name"`
-		} `json:"body_part"`
-	}
-
-	err = h.db.QueryRow(`
-        SELECT e.id, e.name, e.description, e.body_part_id, 
-               b.id, b.name
-        FROM exercises e
-        JOIN body_parts b ON e.body_part_id = b.id
-        WHERE e.id = ?`,
-		id,
-	).Scan(
-		&exercise.ID, &exercise.Name, &exercise.Description, &exercise.BodyPartID,
-		&exercise.BodyPart.ID, &exercise.BodyPart.Name,
-	)
+	exercise, ok := h.visibleExercise(w, r, id)
+	if !ok {
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, newExerciseResponse(exercise))
+}
 
+// ///////////////////////////////////////////////////////////////////////////
+// ListExercises handles GET requests for the global catalog and the user's
+// own exercises. The q parameter searches names and aliases; body_part_id,
+// variation_of and gym narrow the list to one body part and those below it,
+// the variations of one exercise or what one of the user's gyms can support.
+// Results are paged with limit, sort and cursor.
+func (h *Handlers) ListExercises(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			h.respondWithError(w, http.StatusNotFound, "Exercise not found")
-			return
-		}
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	filter := data.ExerciseFilter{UserID: userID, Search: query.Get("q")}
+
+	if err := parseIDParam(query.Get("body_part_id"), &filter.BodyPartID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid body_part_id format")
+		return
+	}
+	if err := parseIDParam(query.Get("variation_of"), &filter.VariationOf); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid variation_of format")
+		return
+	}
+
+	if err := parseIDParam(query.Get("gym"), &filter.GymID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid gym format")
+		return
+	}
+	if filter.GymID != 0 && !h.requireOwnedGym(w, r, filter.GymID) {
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, exercise)
+	list, err := parseListQuery(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	exercises, page, err := h.models.Exercises.List(filter, list)
+	if err != nil {
+		h.respondWithListError(w, err, "name or id")
+		return
+	}
+
+	response := make([]*exerciseResponse, len(exercises))
+	for i, exercise := range exercises {
+		response[i] = newExerciseResponse(exercise)
+	}
+
+	h.respondWithPage(w, response, page)
 }
 
-// ////////////////////////////////////////////////////
-// ListExercises handles GET requests for all exercises
-func (h *Handlers) ListExercises(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.db.Query(`
-        SELECT e.id, e.name, e.description, e.body_part_id,
-               b.id, b.name
-        FROM exercises e
-        JOIN body_parts b ON e.body_part_id = b.id
-        ORDER BY e.name`)
+// ///////////////////////////////////////////////////////////////////////////
+// ExerciseSubstitutes handles GET requests for alternatives to an exercise,
+// ranked by muscle overlap, movement pattern and equipment. The gym
+// parameter limits them to exercises performable in one of the user's gyms.
+func (h *Handlers) ExerciseSubstitutes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
 		return
 	}
-	defer rows.Close()
-
-	var exercises []struct {
-		ID          int64  `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		BodyPartID  int64  `json:"body_part_id"`
-		BodyPart    struct {
-			ID   int64  `json:"id"`
-			Name string `json:"name"`
-		} `json:"body_part"`
-	}
-
-	for rows.Next() {
-		var exercise struct {
-			ID          int64  `json:"id"`
-			Name        string `json:"name"`
-			Description string `json:"description"`
-			BodyPartID  int64  `json:"body_part_id"`
-			BodyPart    struct {
-				ID   int64  `json:"id"`
-				Name string `json:"name"`
-			} `json:"body_part"`
-		}
 
-		if err := rows.Scan(
-			&exercise.ID, &exercise.Name, &exercise.Description, &exercise.BodyPartID,
-			&exercise.BodyPart.ID, &exercise.BodyPart.Name,
-		); err != nil {
-			h.respondWithError(w, http.StatusInternalServerError, "Row scanning error")
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultSubstituteLimit
+	if err := parseIntParam(query.Get("limit"), &limit); err != nil || limit < 1 || limit > maxSubstituteLimit {
+		h.respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 50")
+		return
+	}
+
+	var gymID int64
+	if err := parseIDParam(query.Get("gym"), &gymID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid gym format")
+		return
+	}
+	if gymID != 0 && !h.requireOwnedGym(w, r, gymID) {
+		return
+	}
+
+	substitutes, err := h.models.Exercises.Substitutes(id, userID, gymID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) || errors.Is(err, data.ErrInvalidInput) {
+			h.respondWithError(w, http.StatusNotFound, "Exercise not found")
 			return
 		}
-		exercises = append(exercises, exercise)
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
 	}
 
-	if err := rows.Err(); err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Row iteration error")
-		return
+	if len(substitutes) > limit {
+		substitutes = substitutes[:limit]
+	}
+	response := make([]substituteResponse, len(substitutes))
+	for i, s := range substitutes {
+		response[i] = substituteResponse{
+			exerciseResponse: *newExerciseResponse(s.Exercise),
+			Score:            s.Score,
+			MuscleOverlap:    s.MuscleOverlap,
+			SamePattern:      s.SamePattern,
+			EquipmentOverlap: s.EquipmentOverlap,
+			Variation:        s.Variation,
+		}
 	}
 
-	h.respondWithJSON(w, http.StatusOK, exercises)
+	h.respondWithJSON(w, http.StatusOK, response)
 }
 
 // //////////////////////////////////////////////////////////////
-// CreateExercise handles POST requests to create a new exercise
+// CreateExercise handles POST requests to create a new exercise owned by
+// the user
 func (h *Handlers) CreateExercise(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req exerciseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// Validate input
-	if req.Name == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Name is required")
+	if req.Visibility == "" {
+		req.Visibility = data.VisibilityPrivate
+	}
+	if !h.validateExerciseRequest(w, &req, 0, userID) {
 		return
 	}
 
-	// Check if body part exists
-	exists, err := h.bodyPartExists(req.BodyPartID)
+	// Begin transaction
+	tx, err := h.db.Begin()
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
-	}
-	if !exists {
-		h.respondWithError(w, http.StatusBadRequest, "Body part not found")
+		h.respondWithError(w, http.StatusInternalServerError, "Transaction error")
 		return
 	}
+	defer tx.Rollback()
 
 	// Insert into database
-	result, err := h.db.Exec(
-		"INSERT INTO exercises (name, description, body_part_id) VALUES (?, ?, ?)",
-		req.Name, req.Description, req.BodyPartID,
+	movementPattern := sql.NullString{String: req.MovementPattern, Valid: req.MovementPattern != ""}
+	result, err := tx.Exec(`
+		INSERT INTO exercises (
+			name, description, body_part_id, tracking_type, movement_pattern, variation_of,
+			owner_user_id, visibility
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Name, req.Description, req.BodyPartID, req.TrackingType, movementPattern, req.VariationOf,
+		userID, req.Visibility,
 	)
 	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Database error")
@@ -162,24 +258,36 @@ func (h *Handlers) CreateExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.models.Muscles.SetForExercise(tx, id, req.BodyPartID, req.Muscles); err != nil {
+		h.respondWithMuscleError(w, err)
+		return
+	}
+	if err := h.models.Equipment.SetForExercise(tx, id, req.EquipmentIDs); err != nil {
+		h.respondWithEquipmentError(w, err)
+		return
+	}
+	if err := h.models.Exercises.SetAliases(tx, id, req.Aliases); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Transaction commit error")
+		return
+	}
+
 	// Return the created exercise
-	exercise := struct {
-		ID          int64  `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		BodyPartID  int64  `json:"body_part_id"`
-	}{
-		ID:          id,
-		Name:        req.Name,
-		Description: req.Description,
-		BodyPartID:  req.BodyPartID,
-	}
-
-	h.respondWithJSON(w, http.StatusCreated, exercise)
+	exercise, err := h.models.Exercises.GetByID(id)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, newExerciseResponse(exercise))
 }
 
 // ///////////////////////////////////////////////////////////////////
-// UpdateExercise handles PUT requests to update an existing exercise
+// UpdateExercise handles PUT requests to update an exercise the user owns
 func (h *Handlers) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -188,15 +296,26 @@ func (h *Handlers) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	existing, ok := h.ownedExercise(w, r, id)
+	if !ok {
+		return
+	}
+
 	var req exerciseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// Validate input
-	if req.Name == "" {
-		h.respondWithError(w, http.StatusBadRequest, "Name is required")
+	if req.Visibility == "" {
+		req.Visibility = existing.Visibility
+	}
+	// Clients from before tracking types would otherwise turn timed exercises
+	// back into weighted ones
+	if req.TrackingType == "" {
+		req.TrackingType = existing.TrackingType
+	}
+	if !h.validateExerciseRequest(w, &req, id, *existing.OwnerUserID) {
 		return
 	}
 
@@ -208,33 +327,15 @@ func (h *Handlers) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// Check if exercise exists
-	var exists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM exercises WHERE id = ?)", id).Scan(&exists)
-	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
-	}
-	if !exists {
-		h.respondWithError(w, http.StatusNotFound, "Exercise not found")
-		return
-	}
-
-	// Check if body part exists
-	exists, err = h.bodyPartExists(req.BodyPartID)
-	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
-		return
-	}
-	if !exists {
-		h.respondWithError(w, http.StatusBadRequest, "Body part not found")
-		return
-	}
-
 	// Update the record
-	result, err := tx.Exec(
-		"UPDATE exercises SET name = ?, description = ?, body_part_id = ? WHERE id = ?",
-		req.Name, req.Description, req.BodyPartID, id,
+	movementPattern := sql.NullString{String: req.MovementPattern, Valid: req.MovementPattern != ""}
+	result, err := tx.Exec(`
+		UPDATE exercises
+		SET name = ?, description = ?, body_part_id = ?, tracking_type = ?,
+		    movement_pattern = ?, variation_of = ?, visibility = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		req.Name, req.Description, req.BodyPartID, req.TrackingType, movementPattern, req.VariationOf,
+		req.Visibility, id,
 	)
 	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Database error")
@@ -252,29 +353,44 @@ func (h *Handlers) UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		h.respondWithError(w, http.StatusNotFound, "Exercise not found")
 		return
 	}
+
+	// Muscles, equipment and aliases are only replaced when given
+	if req.Muscles != nil {
+		if err := h.models.Muscles.SetForExercise(tx, id, req.BodyPartID, req.Muscles); err != nil {
+			h.respondWithMuscleError(w, err)
+			return
+		}
+	}
+	if req.EquipmentIDs != nil {
+		if err := h.models.Equipment.SetForExercise(tx, id, req.EquipmentIDs); err != nil {
+			h.respondWithEquipmentError(w, err)
+			return
+		}
+	}
+	if req.Aliases != nil {
+		if err := h.models.Exercises.SetAliases(tx, id, req.Aliases); err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Transaction commit error")
 		return
 	}
 
 	// Return the updated exercise
-	exercise := struct {
-		ID          int64  `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		BodyPartID  int64  `json:"body_part_id"`
-	}{
-		ID:          id,
-		Name:        req.Name,
-		Description: req.Description,
-		BodyPartID:  req.BodyPartID,
-	}
-
-	h.respondWithJSON(w, http.StatusOK, exercise)
+	exercise, err := h.models.Exercises.GetByID(id)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, newExerciseResponse(exercise))
 }
 
 // ///////////////////////////////////////////////////////////////////
-// DeleteExercise handles DELETE requests to remove an exercise
+// DeleteExercise handles DELETE requests to remove an exercise the user owns
 func (h *Handlers) DeleteExercise(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -283,6 +399,17 @@ func (h *Handlers) DeleteExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.ownedExercise(w, r, id); !ok {
+		return
+	}
+
+	// Remember the media files, which are removed once the records are gone
+	media, err := h.models.Media.GetByExercise(id)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
 	// Begin transaction
 	tx, err := h.db.Begin()
 	if err != nil {
@@ -305,7 +432,12 @@ func (h *Handlers) DeleteExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Delete the exercise
+	// Delete the exercise with its muscles, equipment, aliases and media; its
+	// variations become standalone exercises
+	if err := h.models.Exercises.DeleteDetails(tx, id); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
 	result, err := tx.Exec("DELETE FROM exercises WHERE id = ?", id)
 	if err != nil {
 		h.respondWithError(w, http.StatusInternalServerError, "Database error")
@@ -328,5 +460,163 @@ func (h *Handlers) DeleteExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.deleteMediaBlobs(r.Context(), media...)
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// validateExerciseRequest checks and normalizes an exercise request from the
+// exercise's owner, responding with an error when it is invalid. id is 0
+// when creating.
+func (h *Handlers) validateExerciseRequest(w http.ResponseWriter, req *exerciseRequest, id, ownerUserID int64) bool {
+	if req.Name == "" {
+		h.respondWithError(w, http.StatusBadRequest, "Name is required")
+		return false
+	}
+	// Only a new exercise arrives without one; updates keep the stored type
+	if req.TrackingType == "" {
+		req.TrackingType = data.TrackingWeightReps
+	}
+	if !data.ValidTrackingType(req.TrackingType) {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid tracking_type")
+		return false
+	}
+	if req.MovementPattern != "" && !data.ValidMovementPattern(req.MovementPattern) {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid movement_pattern")
+		return false
+	}
+	if !data.ValidVisibility(req.Visibility) {
+		h.respondWithError(w, http.StatusBadRequest, "visibility must be global, team or private")
+		return false
+	}
+	if req.Aliases != nil {
+		aliases, err := data.NormalizeAliases(req.Aliases)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Aliases must not be blank or longer than 100 characters")
+			return false
+		}
+		req.Aliases = aliases
+	}
+
+	// Check if body part exists
+	exists, err := h.bodyPartExists(req.BodyPartID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if !exists {
+		h.respondWithError(w, http.StatusBadRequest, "Body part not found")
+		return false
+	}
+
+	if req.VariationOf != nil {
+		if err := h.models.Exercises.ValidateVariation(id, *req.VariationOf, &ownerUserID); err != nil {
+			if errors.Is(err, data.ErrInvalidInput) {
+				h.respondWithError(w, http.StatusBadRequest,
+					"Invalid variation_of: it must name another visible exercise that is not a variation of this one")
+				return false
+			}
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+			return false
+		}
+	}
+
+	return true
+}
+
+// visibleExercise loads an exercise the current user can see, responding with
+// 404 when they cannot
+func (h *Handlers) visibleExercise(w http.ResponseWriter, r *http.Request, id int64) (*data.Exercise, bool) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	exercise, err := h.models.Exercises.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusNotFound, "Exercise not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, false
+	}
+	visible, err := h.models.Exercises.VisibleTo(id, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return nil, false
+	}
+	if !visible {
+		h.respondWithError(w, http.StatusNotFound, "Exercise not found")
+		return nil, false
+	}
+
+	return exercise, true
+}
+
+// ownedExercise loads an exercise the current user may change, responding
+// with 404 when they cannot see it and 403 when they can but do not own it
+func (h *Handlers) ownedExercise(w http.ResponseWriter, r *http.Request, id int64) (*data.Exercise, bool) {
+	exercise, ok := h.visibleExercise(w, r, id)
+	if !ok {
+		return nil, false
+	}
+
+	userID, _ := h.currentUserID(r)
+	if !exercise.OwnedBy(userID) {
+		h.respondWithError(w, http.StatusForbidden, "Only the owner of an exercise can change it")
+		return nil, false
+	}
+
+	return exercise, true
+}
+
+// newExerciseResponse presents an exercise with its body part
+func newExerciseResponse(exercise *data.Exercise) *exerciseResponse {
+	response := &exerciseResponse{
+		ID:              exercise.ID,
+		Name:            exercise.Name,
+		Description:     exercise.Description,
+		BodyPartID:      exercise.BodyPartID,
+		TrackingType:    exercise.TrackingType,
+		MovementPattern: exercise.MovementPattern,
+		VariationOf:     exercise.VariationOf,
+		OwnerUserID:     exercise.OwnerUserID,
+		Visibility:      exercise.Visibility,
+		BodyPart:        &exerciseBodyPart{ID: exercise.BodyPartID, Name: exercise.BodyPartName},
+		Muscles:         exercise.Muscles,
+		Equipment:       exercise.Equipment,
+		Aliases:         exercise.Aliases,
+	}
+	if response.Muscles == nil {
+		response.Muscles = []data.ExerciseMuscle{}
+	}
+	if response.Equipment == nil {
+		response.Equipment = []data.Equipment{}
+	}
+	if response.Aliases == nil {
+		response.Aliases = []string{}
+	}
+	return response
+}
+
+func (h *Handlers) respondWithMuscleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrInvalidInput):
+		h.respondWithError(w, http.StatusBadRequest,
+			"Invalid muscles: each must exist once with a valid role and a contribution between 0 and 1, and one must be primary")
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+	}
+}
+
+func (h *Handlers) respondWithEquipmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrInvalidInput):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid equipment_ids: each must name catalog equipment once")
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// listExercises lists the exercises user 1 can see with extra query
+// parameters and returns their names
+func listExercises(t *testing.T, h *Handlers, query string) []string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	h.ListExercises(rr, httptest.NewRequest("GET", "/api/exercises?user_id=1"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var response struct {
+		Data []exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	names := []string{}
+	for _, exercise := range response.Data {
+		names = append(names, exercise.Name)
+	}
+	return names
+}
+
+func TestExerciseSearchByAlias(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"rdl", "Romanian Deadlift"},
+		{"flat%20bench", "Bench Press"},
+		{"CHIN-UP", "Pull-ups"},
+		{"ohp", "Shoulder Press"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			names := listExercises(t, h, "&q="+tt.query)
+			if len(names) != 1 || names[0] != tt.expected {
+				t.Errorf("searching %q found %v, want %s", tt.query, names, tt.expected)
+			}
+		})
+	}
+
+	// Wildcards in the search are literal
+	if names := listExercises(t, h, "&q=%25"); len(names) != 0 {
+		t.Errorf("a literal %% should match nothing: %v", names)
+	}
+
+	if names := listExercises(t, h, "&variation_of=1"); len(names) != 1 || names[0] != "Incline Dumbbell Press" {
+		t.Errorf("wrong bench press variations: %v", names)
+	}
+}
+
+func TestExerciseAliasesAndVariations(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	create := func(body string) (*httptest.ResponseRecorder, exerciseResponse) {
+		rr := httptest.NewRecorder()
+		h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1", bytes.NewBufferString(body)))
+		var response struct {
+			Data exerciseResponse `json:"data"`
+		}
+		if rr.Code == http.StatusCreated {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+		}
+		return rr, response.Data
+	}
+
+	rr, exercise := create(`{
+		"name": "Sumo Deadlift", "body_part_id": 2, "movement_pattern": "hinge", "variation_of": 3,
+		"aliases": ["  Sumo  DL ", "sumo dl"]
+	}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if len(exercise.Aliases) != 1 || exercise.Aliases[0] != "Sumo DL" {
+		t.Errorf("aliases not normalized: %v", exercise.Aliases)
+	}
+	if exercise.VariationOf == nil || *exercise.VariationOf != 3 || exercise.MovementPattern != "hinge" {
+		t.Errorf("wrong variation: %+v", exercise)
+	}
+	id := strconv.FormatInt(exercise.ID, 10)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"unknown pattern", `{"name": "Hip Thrust", "body_part_id": 3, "movement_pattern": "thrust"}`},
+		{"unknown parent", `{"name": "Hip Thrust", "body_part_id": 3, "variation_of": 999}`},
+		{"blank alias", `{"name": "Hip Thrust", "body_part_id": 3, "aliases": [" "]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr, _ := create(tt.body); rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+		})
+	}
+
+	// An exercise cannot become a variation of its own variation
+	rr, deficit := create(`{"name": "Deficit Sumo Deadlift", "body_part_id": 2, "variation_of": ` + id + `}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	req := httptest.NewRequest("PUT", "/api/exercises/"+id+"?user_id=1", bytes.NewBufferString(
+		`{"name": "Sumo Deadlift", "body_part_id": 2, "variation_of": `+strconv.FormatInt(deficit.ID, 10)+`}`))
+	rr = httptest.NewRecorder()
+	h.UpdateExercise(rr, withURLParams(req, "id", id))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	// Updating without aliases keeps them
+	req = httptest.NewRequest("PUT", "/api/exercises/"+id+"?user_id=1",
+		bytes.NewBufferString(`{"name": "Sumo Deadlift", "body_part_id": 2}`))
+	rr = httptest.NewRecorder()
+	h.UpdateExercise(rr, withURLParams(req, "id", id))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if names := listExercises(t, h, "&q=sumo%20dl"); len(names) != 1 {
+		t.Errorf("alias should be kept: %v", names)
+	}
+
+	// Deleting a lift detaches its variations
+	rr = httptest.NewRecorder()
+	h.DeleteExercise(rr, withURLParams(httptest.NewRequest("DELETE", "/api/exercises/"+id+"?user_id=1", nil), "id", id))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if names := listExercises(t, h, "&q=sumo"); len(names) != 1 || names[0] != "Deficit Sumo Deadlift" {
+		t.Errorf("deleted exercise still found: %v", names)
+	}
+}
+
+func TestExerciseSubstitutes(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	substitutes := func(query string) (*httptest.ResponseRecorder, []substituteResponse) {
+		req := httptest.NewRequest("GET", "/api/exercises/1/substitutes"+query, nil)
+		rr := httptest.NewRecorder()
+		h.ExerciseSubstitutes(rr, withURLParams(req, "id", "1"))
+
+		var response struct {
+			Data []substituteResponse `json:"data"`
+		}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+		}
+		return rr, response.Data
+	}
+
+	// The incline press shares the most with the bench press, then push-ups
+	rr, ranked := substitutes("?user_id=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if len(ranked) < 2 || ranked[0].Name != "Incline Dumbbell Press" || ranked[1].Name != "Push-ups" {
+		t.Fatalf("wrong ranking: %+v", ranked)
+	}
+	if !ranked[0].Variation || !ranked[0].SamePattern || ranked[0].EquipmentOverlap != 0.5 || ranked[0].MuscleOverlap != 2 {
+		t.Errorf("wrong incline press match: %+v", ranked[0])
+	}
+	for i := 1; i < len(ranked); i++ {
+		if ranked[i].Score > ranked[i-1].Score {
+			t.Errorf("substitutes not ordered by score: %+v", ranked)
+		}
+		if ranked[i].TrackingType == "distance_duration" {
+			t.Errorf("%s is not logged like the bench press", ranked[i].Name)
+		}
+	}
+
+	// Without a bench at the gym, push-ups come first
+	gym := createGym(t, h, "Home", "Dumbbell")
+	rr, ranked = substitutes("?user_id=1&gym=" + strconv.FormatInt(gym.ID, 10))
+	if rr.Code != http.StatusOK || len(ranked) == 0 || ranked[0].Name != "Push-ups" {
+		t.Errorf("wrong substitutes at home: %v %+v", rr.Code, ranked)
+	}
+
+	if _, ranked = substitutes("?user_id=1&limit=1"); len(ranked) != 1 {
+		t.Errorf("limit not applied: %+v", ranked)
+	}
+	if rr, _ = substitutes("?user_id=1&limit=0"); rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr, _ = substitutes("?user_id=2&gym=" + strconv.FormatInt(gym.ID, 10)); rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	req := httptest.NewRequest("GET", "/api/exercises/999/substitutes?user_id=1", nil)
+	rr = httptest.NewRecorder()
+	h.ExerciseSubstitutes(rr, withURLParams(req, "id", "999"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
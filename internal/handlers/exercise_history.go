@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Page size limits for exercise history
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// ///////////////////////////////////////////////////////////////////////////////
+// ExerciseHistory handles GET requests for a user's performances of an exercise
+func (h *Handlers) ExerciseHistory(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, offset := defaultHistoryLimit, 0
+	if err := parseIntParam(r.URL.Query().Get("limit"), &limit); err != nil || limit < 1 || limit > maxHistoryLimit {
+		h.respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+	if err := parseIntParam(r.URL.Query().Get("offset"), &offset); err != nil || offset < 0 {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+
+	exercise, ok := h.visibleExercise(w, r, exerciseID)
+	if !ok {
+		return
+	}
+
+	// The trend is computed over the full history so it does not depend on
+	// the page being viewed
+	sessions, err := h.models.WorkoutExercises.GetSessions(userID, exerciseID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	// Newest first
+	total := len(sessions)
+	page := []*data.ExerciseSession{}
+	for i := total - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, sessions[i])
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentSessions(page, unit)
+
+	h.respondWithJSON(w, http.StatusOK, envelope{
+		"exercise": exercise,
+		"sessions": page,
+		"pagination": envelope{
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
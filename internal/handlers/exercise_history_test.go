@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestExerciseHistory(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	for day, weight := range map[int]float64{1: 100, 4: 105, 8: 110} {
+		createTestWorkout(t, h, day, 5, 3, 5, weight)
+	}
+
+	req := httptest.NewRequest("GET", "/api/exercises/5/history?user_id=1&limit=2", nil)
+	req = withURLParams(req, "id", "5")
+	rr := httptest.NewRecorder()
+
+	h.ExerciseHistory(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response struct {
+		Data struct {
+			Sessions   []data.ExerciseSession `json:"sessions"`
+			Pagination struct {
+				Total int `json:"total"`
+			} `json:"pagination"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	sessions := response.Data.Sessions
+	if response.Data.Pagination.Total != 3 || len(sessions) != 2 {
+		t.Fatalf("wrong page: got %v of %v sessions", len(sessions), response.Data.Pagination.Total)
+	}
+	if sessions[0].Date.Day() != 8 || sessions[1].Date.Day() != 4 {
+		t.Errorf("sessions not newest first: %v, %v", sessions[0].Date, sessions[1].Date)
+	}
+
+	latest := sessions[0]
+	if latest.Volume != 1650 || *latest.BestSet.Weight != 110 {
+		t.Errorf("wrong session summary: volume %v, best set %v", latest.Volume, *latest.BestSet.Weight)
+	}
+	if want := data.EpleyOneRepMax(110, 5); latest.EstimatedOneRepMax != want {
+		t.Errorf("wrong estimated 1RM: got %v want %v", latest.EstimatedOneRepMax, want)
+	}
+	if latest.Trend == nil || *latest.Trend >= latest.EstimatedOneRepMax || *latest.Trend <= *sessions[1].Trend {
+		t.Errorf("trend should rise but lag behind the latest estimate: %v", latest.Trend)
+	}
+}
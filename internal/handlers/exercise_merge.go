@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mergeResponse is the target of a merge with what moved to it
+type mergeResponse struct {
+	Exercise *exerciseResponse `json:"exercise"`
+	Moved    *data.MergeResult `json:"moved"`
+}
+
+// /////////////////////////////////////////////////////////////////////////////
+// MergeExercise handles admin POST requests folding a duplicate exercise into
+// another, keeping every workout, record and goal that used it
+func (h *Handlers) MergeExercise(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	sourceID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+	targetID, err := strconv.ParseInt(chi.URLParam(r, "targetId"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid target ID format")
+		return
+	}
+	if sourceID == targetID {
+		h.respondWithError(w, http.StatusBadRequest, "An exercise cannot be merged into itself")
+		return
+	}
+
+	// Admins see every exercise, so visibility does not hide either one
+	source, err := h.models.Exercises.GetByID(sourceID)
+	if err != nil {
+		h.respondWithMergeError(w, err)
+		return
+	}
+	target, err := h.models.Exercises.GetByID(targetID)
+	if err != nil {
+		h.respondWithMergeError(w, err)
+		return
+	}
+
+	// Moved entries must still make sense for the target
+	if source.TrackingType != target.TrackingType {
+		h.respondWithError(w, http.StatusConflict, "Exercises with different tracking types cannot be merged")
+		return
+	}
+	// Whoever used the source must be able to see the target
+	if target.Visibility != data.VisibilityGlobal &&
+		(source.Visibility == data.VisibilityGlobal || target.OwnerUserID == nil || !source.OwnedBy(*target.OwnerUserID) ||
+			(source.Visibility == data.VisibilityTeam && target.Visibility == data.VisibilityPrivate)) {
+		h.respondWithError(w, http.StatusConflict,
+			"Exercises can only be merged into a global exercise or one with the same owner that is seen at least as widely")
+		return
+	}
+
+	moved, err := h.models.Exercises.Merge(sourceID, targetID)
+	if err != nil {
+		h.respondWithMergeError(w, err)
+		return
+	}
+
+	merged, err := h.models.Exercises.GetByID(targetID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, mergeResponse{
+		Exercise: newExerciseResponse(merged),
+		Moved:    moved,
+	})
+}
+
+// requireAdmin responds with 403 unless the current user is an admin
+func (h *Handlers) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	user, err := h.models.Users.GetByID(userID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if user == nil || !user.IsAdmin {
+		h.respondWithError(w, http.StatusForbidden, "Admin access required")
+		return false
+	}
+
+	return true
+}
+
+func (h *Handlers) respondWithMergeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrInvalidInput):
+		h.respondWithError(w, http.StatusNotFound, "Exercise not found")
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+	}
+}
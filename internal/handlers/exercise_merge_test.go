@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestMergeExercise(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Flat Barbell Bench", "body_part_id": 1, "visibility": "global", "aliases": ["BB Bench"]}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	duplicate := created.Data.ID
+
+	createTestWorkout(t, h, 1, 1, 3, 5, 100)
+	heavier := createTestWorkout(t, h, 2, duplicate, 3, 5, 110)
+	if _, err := h.db.Exec(`
+		INSERT INTO goals (user_id, goal_type, exercise_id, target_value)
+		VALUES (1, ?, ?, 120)`, data.GoalOneRepMax, duplicate); err != nil {
+		t.Fatalf("Failed to insert goal: %v", err)
+	}
+	// Only the duplicate's history reaches this goal on the target
+	result, err := h.db.Exec(`
+		INSERT INTO goals (user_id, goal_type, exercise_id, target_value)
+		VALUES (1, ?, 1, 105)`, data.GoalLiftWeight)
+	if err != nil {
+		t.Fatalf("Failed to insert goal: %v", err)
+	}
+	targetGoal, _ := result.LastInsertId()
+
+	merge := func(sourceID, targetID int64, userID string) *httptest.ResponseRecorder {
+		source, target := strconv.FormatInt(sourceID, 10), strconv.FormatInt(targetID, 10)
+		req := httptest.NewRequest("POST", "/api/exercises/"+source+"/merge-into/"+target+"?user_id="+userID, nil)
+		req = withURLParams(req, "id", source, "targetId", target)
+		rr := httptest.NewRecorder()
+		h.MergeExercise(rr, req)
+		return rr
+	}
+
+	// Only admins may merge
+	if rr := merge(duplicate, 1, "1"); rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+	if _, err := h.db.Exec("UPDATE users SET is_admin = 1 WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to grant admin: %v", err)
+	}
+
+	if rr := merge(duplicate, duplicate, "1"); rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := merge(duplicate, 9999, "1"); rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	// Plank is tracked by duration
+	if rr := merge(duplicate, 9, "1"); rr.Code != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+	}
+
+	rr = merge(duplicate, 1, "1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var merged struct {
+		Data struct {
+			Exercise exerciseResponse `json:"exercise"`
+			Moved    data.MergeResult `json:"moved"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&merged); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	moved := merged.Data.Moved
+	if moved.WorkoutExercises != 1 || moved.Goals != 1 || moved.Aliases != 2 || moved.RecordsRebuilt != 1 {
+		t.Errorf("unexpected merge counts: %+v", moved)
+	}
+	aliases := map[string]bool{}
+	for _, alias := range merged.Data.Exercise.Aliases {
+		aliases[alias] = true
+	}
+	if !aliases["Flat Barbell Bench"] || !aliases["BB Bench"] {
+		t.Errorf("the source's names should become aliases of the target: %v", merged.Data.Exercise.Aliases)
+	}
+
+	// The source is gone, and its history counts towards the target
+	if _, err := h.models.Exercises.GetByID(duplicate); err != data.ErrRecordNotFound {
+		t.Errorf("the source exercise should be deleted: %v", err)
+	}
+	workout, err := h.models.Workouts.GetByID(heavier.ID)
+	if err != nil {
+		t.Fatalf("Failed to load workout: %v", err)
+	}
+	if workout.Details[0].ExerciseID != 1 {
+		t.Errorf("workout entry should point at the target: got exercise %v", workout.Details[0].ExerciseID)
+	}
+	records := currentRecords(t, h)
+	if got := records[data.RecordMaxWeight]; got.Value != 110 || got.WorkoutID != heavier.ID {
+		t.Errorf("max weight record should come from the merged history: %+v", got)
+	}
+	goal, err := h.models.Goals.GetByID(targetGoal)
+	if err != nil {
+		t.Fatalf("Failed to load goal: %v", err)
+	}
+	if goal.Status != data.GoalAchieved || goal.AchievedWorkoutID == nil || *goal.AchievedWorkoutID != heavier.ID {
+		t.Errorf("goal should be achieved by the merged history: %+v", goal)
+	}
+	var orphaned int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM personal_records WHERE exercise_id = ?", duplicate).Scan(&orphaned); err != nil || orphaned != 0 {
+		t.Errorf("records of the source should be gone: %v records, %v", orphaned, err)
+	}
+
+	found := false
+	for _, name := range listExercises(t, h, "&q=flat+barbell") {
+		found = found || name == "Bench Press"
+	}
+	if !found {
+		t.Error("the target should be found by the source's name")
+	}
+
+	// A private exercise's names stay private when it is merged into a global one
+	rr = httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Secret Bench", "body_part_id": 1, "aliases": ["Bench Day Special"]}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	rr = merge(created.Data.ID, 1, "1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&merged); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	for _, alias := range merged.Data.Exercise.Aliases {
+		if alias == "Secret Bench" || alias == "Bench Day Special" {
+			t.Errorf("a private exercise's name became a global alias: %v", merged.Data.Exercise.Aliases)
+		}
+	}
+	if merged.Data.Moved.Aliases != 0 {
+		t.Errorf("no aliases should move from a private exercise: %+v", merged.Data.Moved)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+func TestExerciseOwnership(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Landmine Press", "body_part_id": 4}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if created.Data.Visibility != data.VisibilityPrivate || created.Data.OwnerUserID == nil || *created.Data.OwnerUserID != 1 {
+		t.Fatalf("new exercises should be private to their creator: %+v", created.Data)
+	}
+	id := strconv.FormatInt(created.Data.ID, 10)
+
+	send := func(method, userID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/exercises/"+id+"?user_id="+userID, bytes.NewBufferString(body))
+		req = withURLParams(req, "id", id)
+		rr := httptest.NewRecorder()
+		switch method {
+		case "GET":
+			h.GetExercise(rr, req)
+		case "PUT":
+			h.UpdateExercise(rr, req)
+		case "DELETE":
+			h.DeleteExercise(rr, req)
+		}
+		return rr
+	}
+	contains := func(names []string, name string) bool {
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A private exercise is hidden from everyone else
+	if rr := send("GET", "2", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr := send("PUT", "2", `{"name": "Mine", "body_part_id": 4}`); rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	rr = httptest.NewRecorder()
+	h.ListExercises(rr, httptest.NewRequest("GET", "/api/exercises?user_id=2", nil))
+	var listed struct {
+		Data []exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	for _, exercise := range listed.Data {
+		if exercise.Name == "Landmine Press" {
+			t.Error("another user's private exercise was listed")
+		}
+	}
+	if !contains(listExercises(t, h, ""), "Landmine Press") {
+		t.Error("the owner should see their private exercise")
+	}
+
+	// Nor can it be logged by anyone else
+	weight := 20.0
+	workout := &data.Workout{UserID: 2, Name: "Shoulders", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Details: []data.WorkoutExercise{{ExerciseID: created.Data.ID, Sets: 3, Reps: 8, Weight: &weight}}}
+	if err := h.models.Workouts.Create(workout); err != data.ErrInvalidInput {
+		t.Errorf("logging another user's private exercise: got %v want %v", err, data.ErrInvalidInput)
+	}
+	goal := &data.Goal{UserID: 2, GoalType: data.GoalLiftWeight, ExerciseID: &created.Data.ID, TargetValue: 60}
+	if err := h.models.Goals.Create(goal); err != data.ErrInvalidInput {
+		t.Errorf("setting a goal on another user's private exercise: got %v want %v", err, data.ErrInvalidInput)
+	}
+
+	// Made global, others can see it but not change it
+	if rr := send("PUT", "1", `{"name": "Landmine Press", "body_part_id": 4, "visibility": "global"}`); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if rr := send("GET", "2", ""); rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr := send("PUT", "2", `{"name": "Mine", "body_part_id": 4}`); rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+	if rr := send("DELETE", "2", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	// Leaving visibility out keeps it
+	rr = send("PUT", "1", `{"name": "Landmine Press", "body_part_id": 4, "description": "Half-kneeling"}`)
+	var updated struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if updated.Data.Visibility != data.VisibilityGlobal {
+		t.Errorf("visibility should be kept: %+v", updated.Data)
+	}
+
+	if rr := send("PUT", "1", `{"name": "Landmine Press", "body_part_id": 4, "visibility": "friends"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Shared with a team, teammates can see and log it but not change it
+	if _, err := h.db.Exec(`
+		INSERT INTO users (email, name, oauth_provider, oauth_id)
+		VALUES ('teammate@example.com', 'Teammate', 'google', 'teammate'),
+		       ('stranger@example.com', 'Stranger', 'google', 'stranger')`); err != nil {
+		t.Fatalf("Failed to insert users: %v", err)
+	}
+	if _, err := h.db.Exec(`
+		INSERT INTO teams (name) VALUES ('Barbell Club');
+		INSERT INTO team_members (team_id, user_id) VALUES (1, 1), (1, 2)`); err != nil {
+		t.Fatalf("Failed to insert team: %v", err)
+	}
+	if rr := send("PUT", "1", `{"name": "Landmine Press", "body_part_id": 4, "visibility": "team"}`); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if rr := send("GET", "2", ""); rr.Code != http.StatusOK {
+		t.Errorf("teammate: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr := send("GET", "3", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("outside the team: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr := send("PUT", "2", `{"name": "Mine", "body_part_id": 4}`); rr.Code != http.StatusForbidden {
+		t.Errorf("teammate changing it: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+	workout.ID = 0
+	if err := h.models.Workouts.Create(workout); err != nil {
+		t.Errorf("a teammate should be able to log it: %v", err)
+	} else if err := h.models.Workouts.Delete(workout.ID); err != nil {
+		t.Fatalf("Failed to delete workout: %v", err)
+	}
+	workout = &data.Workout{UserID: 3, Name: "Shoulders", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Details: []data.WorkoutExercise{{ExerciseID: created.Data.ID, Sets: 3, Reps: 8, Weight: &weight}}}
+	if err := h.models.Workouts.Create(workout); err != data.ErrInvalidInput {
+		t.Errorf("logging outside the team: got %v want %v", err, data.ErrInvalidInput)
+	}
+
+	// The built-in catalog belongs to no one
+	req := httptest.NewRequest("DELETE", "/api/exercises/8?user_id=1", nil)
+	rr = httptest.NewRecorder()
+	h.DeleteExercise(rr, withURLParams(req, "id", "8"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	if rr := send("DELETE", "1", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// goalRequest represents the expected request body for creating/updating a goal
+type goalRequest struct {
+	GoalType    string  `json:"goal_type"` // lift_weight, e1rm, frequency or relative_strength
+	ExerciseID  *int64  `json:"exercise_id"`
+	TargetValue float64 `json:"target_value"`
+	Unit        string  `json:"unit"`     // Unit of weight targets; defaults to the user's preferred unit
+	Deadline    string  `json:"deadline"` // Optional, format: "2006-01-02"
+	Notes       string  `json:"notes"`
+}
+
+// toGoal validates the request and converts it to a goal with weights in kg
+func (h *Handlers) toGoal(req goalRequest, userID int64) (*data.Goal, error) {
+	if !data.ValidGoalType(req.GoalType) {
+		return nil, errors.New("goal_type must be lift_weight, e1rm, frequency or relative_strength")
+	}
+
+	goal := &data.Goal{
+		UserID:      userID,
+		GoalType:    req.GoalType,
+		ExerciseID:  req.ExerciseID,
+		TargetValue: req.TargetValue,
+		Notes:       req.Notes,
+	}
+
+	if data.GoalIsWeight(req.GoalType) {
+		unit, err := h.entryUnit(req.Unit, userID)
+		if err != nil {
+			return nil, err
+		}
+		goal.TargetValue = data.ToKilograms(req.TargetValue, unit)
+	}
+
+	if req.Deadline != "" {
+		deadline, err := time.Parse(data.DateFormat, req.Deadline)
+		if err != nil {
+			return nil, errors.New("Invalid deadline date format")
+		}
+		goal.Deadline = &deadline
+	}
+
+	return goal, nil
+}
+
+// ////////////////////////////////////////////////////
+// ListGoals handles GET requests for a user's goals
+func (h *Handlers) ListGoals(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	goals, err := h.models.Goals.GetAll(userID, r.URL.Query().Get("status"))
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidInput) {
+			h.respondWithError(w, http.StatusBadRequest, "status must be active or achieved")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	for _, goal := range goals {
+		presentGoal(goal, unit)
+	}
+
+	h.respondWithJSON(w, http.StatusOK, goals)
+}
+
+// /////////////////////////////////////////////
+// GetGoal handles GET requests for a single goal
+func (h *Handlers) GetGoal(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+
+	unit, err := h.displayUnit(r, goal.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentGoal(goal, unit)
+	h.respondWithJSON(w, http.StatusOK, goal)
+}
+
+// /////////////////////////////////////////////////
+// CreateGoal handles POST requests to set a new goal
+func (h *Handlers) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req goalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	goal, err := h.toGoal(req, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.models.Goals.Create(goal)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	presentGoal(goal, unit)
+	h.respondWithJSON(w, http.StatusCreated, goal)
+}
+
+// /////////////////////////////////////////////////////
+// UpdateGoal handles PUT requests to update an existing goal
+func (h *Handlers) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+	unit, err := h.displayUnit(r, existing.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req goalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	goal, err := h.toGoal(req, existing.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	goal.ID = existing.ID
+
+	err = h.models.Goals.Update(goal)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Goal not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	presentGoal(goal, unit)
+	h.respondWithJSON(w, http.StatusOK, goal)
+}
+
+// /////////////////////////////////////////////////
+// DeleteGoal handles DELETE requests to remove a goal
+func (h *Handlers) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.models.Goals.Delete(goal.UserID, goal.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Goal not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedGoal loads the goal named in the URL, responding with an error and
+// returning false when it is missing or belongs to another user
+func (h *Handlers) ownedGoal(w http.ResponseWriter, r *http.Request) (*data.Goal, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return nil, false
+	}
+
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	goal, err := h.models.Goals.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Goal not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, false
+	}
+	if goal.UserID != userID {
+		h.respondWithError(w, http.StatusNotFound, "Goal not found")
+		return nil, false
+	}
+
+	return goal, true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestGoals(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	createGoal := func(body string) (*httptest.ResponseRecorder, data.Goal) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.CreateGoal(rr, httptest.NewRequest("POST", "/api/goals?user_id=1", bytes.NewBufferString(body)))
+		var response struct {
+			Data data.Goal `json:"data"`
+		}
+		if rr.Code == http.StatusCreated {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+		}
+		return rr, response.Data
+	}
+	getGoal := func(id int64) data.Goal {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", fmt.Sprintf("/api/goals/%d?user_id=1", id), nil)
+		h.GetGoal(rr, withURLParams(req, "id", fmt.Sprint(id)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var response struct {
+			Data data.Goal `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		return response.Data
+	}
+
+	invalid := []string{
+		`{"goal_type": "lift_weight", "target_value": 100}`,
+		`{"goal_type": "frequency", "exercise_id": 1, "target_value": 4}`,
+		`{"goal_type": "lift_weight", "exercise_id": 999, "target_value": 100}`,
+		`{"goal_type": "sleep", "target_value": 8}`,
+		`{"goal_type": "e1rm", "exercise_id": 1, "target_value": 0}`,
+	}
+	for _, body := range invalid {
+		if rr, _ := createGoal(body); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	_, bench := createGoal(`{"goal_type": "lift_weight", "exercise_id": 1, "target_value": 100, "deadline": "2024-02-01"}`)
+	_, weekly := createGoal(`{"goal_type": "frequency", "target_value": 1}`)
+	if bench.Status != data.GoalActive || weekly.Status != data.GoalActive {
+		t.Fatalf("new goals should be active: %+v %+v", bench, weekly)
+	}
+	// Frequency goals count workouts from the day they were set; backdate this
+	// one to before the January workouts below
+	if _, err := h.db.Exec("UPDATE goals SET created_at = '2023-12-31 12:00:00' WHERE id = ?", weekly.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 80, 85, 90 kg a week apart: on trend to reach 100 kg two weeks after the last
+	createTestWorkout(t, h, 1, 1, 3, 5, 80)
+	createTestWorkout(t, h, 8, 1, 3, 5, 85)
+	createTestWorkout(t, h, 15, 1, 3, 5, 90)
+
+	goal := getGoal(bench.ID)
+	if goal.Status != data.GoalActive || goal.Progress == nil || goal.Progress.Current != 90 || goal.Progress.Percent != 90 {
+		t.Fatalf("wrong progress: %+v %+v", goal, goal.Progress)
+	}
+	if p := goal.Progress; p.ProjectedOn == nil || *p.ProjectedOn != "2024-01-29" || p.OnTrack == nil || !*p.OnTrack {
+		t.Errorf("wrong projection: %+v", p)
+	}
+
+	// Crossing the threshold marks the goal achieved by that workout
+	workout := createTestWorkout(t, h, 22, 1, 1, 1, 100)
+	goal = getGoal(bench.ID)
+	if goal.Status != data.GoalAchieved || goal.AchievedWorkoutID == nil || *goal.AchievedWorkoutID != workout.ID {
+		t.Errorf("goal should be achieved by workout %d: %+v", workout.ID, goal)
+	}
+	if goal.AchievedOn == nil || goal.AchievedOn.Format(data.DateFormat) != "2024-01-22" {
+		t.Errorf("wrong achieved date: %v", goal.AchievedOn)
+	}
+
+	// Four workouts within four weeks averages one a week
+	if goal := getGoal(weekly.ID); goal.Status != data.GoalAchieved {
+		t.Errorf("frequency goal should be achieved: %+v", goal)
+	}
+
+	// The same history does not meet a frequency goal set after it
+	if _, late := createGoal(`{"goal_type": "frequency", "target_value": 1}`); late.Status != data.GoalActive || late.Progress == nil || late.Progress.Current != 0 {
+		t.Errorf("earlier workouts should not count towards a new frequency goal: %+v %+v", late, late.Progress)
+	}
+
+	// Targets in pounds are stored in kg and shown in the requested unit
+	_, heavy := createGoal(`{"goal_type": "e1rm", "exercise_id": 1, "target_value": 315, "unit": "lb"}`)
+	if heavy.Unit != "kg" || heavy.TargetValue < 142.8 || heavy.TargetValue > 142.9 {
+		t.Errorf("wrong target in kg: %v %s", heavy.TargetValue, heavy.Unit)
+	}
+
+	// Goals already met by the history are achieved when created
+	_, relative := createGoal(`{"goal_type": "relative_strength", "exercise_id": 1, "target_value": 1.2}`)
+	if relative.Status != data.GoalActive {
+		t.Errorf("relative strength goal needs a body weight: %+v", relative)
+	}
+	rr := httptest.NewRecorder()
+	h.CreateMeasurement(rr, httptest.NewRequest("POST", "/api/measurements?user_id=1",
+		bytes.NewBufferString(`{"metric": "body_weight", "value": 80, "measured_on": "2024-01-01"}`)))
+	_, relative = createGoal(`{"goal_type": "relative_strength", "exercise_id": 1, "target_value": 1.25}`)
+	if relative.Status != data.GoalAchieved {
+		t.Errorf("100 kg at 80 kg body weight should meet 1.25x: %+v", relative)
+	}
+
+	// Goals of other users are hidden
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/goals/%d?user_id=2", bench.ID), nil)
+	h.GetGoal(rr, withURLParams(req, "id", fmt.Sprint(bench.ID)))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// gymRequest represents the expected request body for creating/updating a gym profile
+type gymRequest struct {
+	Name         string  `json:"name"`
+	EquipmentIDs []int64 `json:"equipment_ids"` // Equipment available at the gym
+}
+
+// ///////////////////////////////////////////////////////
+// ListEquipment handles GET requests for the equipment catalog
+func (h *Handlers) ListEquipment(w http.ResponseWriter, r *http.Request) {
+	catalog, err := h.models.Equipment.GetAll()
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, catalog)
+}
+
+// ///////////////////////////////////////////////////
+// ListGyms handles GET requests for a user's gym profiles
+func (h *Handlers) ListGyms(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	gyms, err := h.models.Gyms.GetAll(userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, gyms)
+}
+
+// /////////////////////////////////////////////////////
+// GetGym handles GET requests for a single gym profile
+func (h *Handlers) GetGym(w http.ResponseWriter, r *http.Request) {
+	gym, ok := h.ownedGymParam(w, r)
+	if !ok {
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, gym)
+}
+
+// ///////////////////////////////////////////////////////
+// CreateGym handles POST requests to create a gym profile
+func (h *Handlers) CreateGym(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req gymRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		h.respondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	gym := &data.GymProfile{
+		UserID:       userID,
+		Name:         strings.TrimSpace(req.Name),
+		EquipmentIDs: req.EquipmentIDs,
+	}
+	if err := h.models.Gyms.Create(gym); err != nil {
+		h.respondWithGymError(w, err)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, gym)
+}
+
+// ///////////////////////////////////////////////////////////////////
+// UpdateGym handles PUT requests to rename a gym profile and replace
+// its equipment
+func (h *Handlers) UpdateGym(w http.ResponseWriter, r *http.Request) {
+	gym, ok := h.ownedGymParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req gymRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		h.respondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	gym.Name = strings.TrimSpace(req.Name)
+	gym.EquipmentIDs = req.EquipmentIDs
+	if err := h.models.Gyms.Update(gym); err != nil {
+		h.respondWithGymError(w, err)
+		return
+	}
+
+	updated, err := h.models.Gyms.GetByID(gym.ID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, updated)
+}
+
+// ///////////////////////////////////////////////////////
+// DeleteGym handles DELETE requests to remove a gym profile
+func (h *Handlers) DeleteGym(w http.ResponseWriter, r *http.Request) {
+	gym, ok := h.ownedGymParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.models.Gyms.Delete(gym.ID); err != nil {
+		h.respondWithGymError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedGymParam loads the gym profile named by the id URL parameter, responding
+// with 404 when it belongs to another user
+func (h *Handlers) ownedGymParam(w http.ResponseWriter, r *http.Request) (*data.GymProfile, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return nil, false
+	}
+
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	return h.ownedGym(w, id, userID)
+}
+
+// requireOwnedGym checks that the gym belongs to the current user, responding
+// with an error when it does not
+func (h *Handlers) requireOwnedGym(w http.ResponseWriter, r *http.Request, gymID int64) bool {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	_, ok := h.ownedGym(w, gymID, userID)
+	return ok
+}
+
+// ownedGym loads a gym profile, responding with 404 when it belongs to
+// another user
+func (h *Handlers) ownedGym(w http.ResponseWriter, id, userID int64) (*data.GymProfile, bool) {
+	gym, err := h.models.Gyms.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusNotFound, "Gym not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, false
+	}
+	if gym.UserID != userID {
+		h.respondWithError(w, http.StatusNotFound, "Gym not found")
+		return nil, false
+	}
+
+	return gym, true
+}
+
+func (h *Handlers) respondWithGymError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Gym not found")
+	case errors.Is(err, data.ErrDuplicateRecord):
+		h.respondWithError(w, http.StatusConflict, "A gym with this name already exists")
+	case errors.Is(err, data.ErrInvalidInput):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid equipment_ids: each must name catalog equipment once")
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+	}
+}
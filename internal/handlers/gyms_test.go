@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"repup/internal/data"
+)
+
+// createGym creates a gym profile for user 1 with the named equipment
+func createGym(t *testing.T, h *Handlers, name string, equipment ...string) data.GymProfile {
+	t.Helper()
+
+	ids := []int64{}
+	for _, e := range equipment {
+		var id int64
+		if err := h.db.QueryRow("SELECT id FROM equipment WHERE name = ?", e).Scan(&id); err != nil {
+			t.Fatalf("Failed to find equipment %s: %v", e, err)
+		}
+		ids = append(ids, id)
+	}
+	body, _ := json.Marshal(gymRequest{Name: name, EquipmentIDs: ids})
+
+	rr := httptest.NewRecorder()
+	h.CreateGym(rr, httptest.NewRequest("POST", "/api/gyms?user_id=1", bytes.NewBuffer(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	var response struct {
+		Data data.GymProfile `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	return response.Data
+}
+
+func TestGymProfiles(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	gym := createGym(t, h, "Home", "Dumbbell", "Pull-up Bar")
+	if len(gym.Equipment) != 2 || gym.Equipment[0].Name != "Dumbbell" {
+		t.Errorf("wrong equipment: %+v", gym.Equipment)
+	}
+	id := strconv.FormatInt(gym.ID, 10)
+
+	tests := []struct {
+		name           string
+		method         string
+		userID         string
+		body           string
+		expectedStatus int
+	}{
+		{"duplicate name", "POST", "1", `{"name": "Home"}`, http.StatusConflict},
+		{"missing name", "POST", "1", `{"equipment_ids": [1]}`, http.StatusBadRequest},
+		{"unknown equipment", "POST", "1", `{"name": "Hotel", "equipment_ids": [999]}`, http.StatusBadRequest},
+		{"repeated equipment", "POST", "1", `{"name": "Hotel", "equipment_ids": [1, 1]}`, http.StatusBadRequest},
+		{"update", "PUT", "1", `{"name": "Garage", "equipment_ids": [1]}`, http.StatusOK},
+		{"update other user's gym", "PUT", "2", `{"name": "Mine"}`, http.StatusNotFound},
+		{"get other user's gym", "GET", "2", "", http.StatusNotFound},
+		{"delete other user's gym", "DELETE", "2", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/gyms?user_id="+tt.userID, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			switch tt.method {
+			case "POST":
+				h.CreateGym(rr, req)
+			case "PUT":
+				h.UpdateGym(rr, withURLParams(req, "id", id))
+			case "GET":
+				h.GetGym(rr, withURLParams(req, "id", id))
+			case "DELETE":
+				h.DeleteGym(rr, withURLParams(req, "id", id))
+			}
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	h.ListGyms(rr, httptest.NewRequest("GET", "/api/gyms?user_id=1", nil))
+	var listed struct {
+		Data []data.GymProfile `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(listed.Data) != 1 || listed.Data[0].Name != "Garage" || len(listed.Data[0].Equipment) != 1 {
+		t.Errorf("wrong gyms: %+v", listed.Data)
+	}
+
+	rr = httptest.NewRecorder()
+	h.DeleteGym(rr, withURLParams(httptest.NewRequest("DELETE", "/api/gyms/"+id+"?user_id=1", nil), "id", id))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+}
+
+func TestListExercisesByGym(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	gym := createGym(t, h, "Home", "Dumbbell", "Pull-up Bar")
+
+	list := func(query string) (*httptest.ResponseRecorder, map[string]exerciseResponse) {
+		rr := httptest.NewRecorder()
+		h.ListExercises(rr, httptest.NewRequest("GET", "/api/exercises"+query, nil))
+
+		var response struct {
+			Data []exerciseResponse `json:"data"`
+		}
+		byName := map[string]exerciseResponse{}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			for _, e := range response.Data {
+				byName[e.Name] = e
+			}
+		}
+		return rr, byName
+	}
+
+	_, all := list("?user_id=1")
+	if len(all["Bench Press"].Equipment) != 2 {
+		t.Errorf("bench press should need a barbell and a bench: %+v", all["Bench Press"].Equipment)
+	}
+
+	rr, home := list("?user_id=1&gym=" + strconv.FormatInt(gym.ID, 10))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	for _, name := range []string{"Push-ups", "Pull-ups", "Bicep Curls", "Plank"} {
+		if _, ok := home[name]; !ok {
+			t.Errorf("%s should be performable at home", name)
+		}
+	}
+	for _, name := range []string{"Bench Press", "Squats", "Rowing"} {
+		if _, ok := home[name]; ok {
+			t.Errorf("%s should not be performable at home", name)
+		}
+	}
+
+	// Another user's gym is not found
+	rr, _ = list("?user_id=2&gym=" + strconv.FormatInt(gym.ID, 10))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	rr, _ = list("?user_id=1&gym=abc")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDuplicateWorkoutForGym(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	gym := createGym(t, h, "Home", "Dumbbell")
+
+	// Bench press (1) needs a barbell; bicep curls (7) only a dumbbell
+	body := `{
+		"user_id": 1, "name": "Upper", "date": "2024-06-04", "status": "completed",
+		"details": [
+			{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 100},
+			{"exercise_id": 7, "sets": 3, "reps": 10, "weight": 15}
+		]
+	}`
+	rr := httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	id := strconv.FormatInt(created.Data.ID, 10)
+
+	copyBody := `{"date": "2024-06-11", "gym_id": ` + strconv.FormatInt(gym.ID, 10) + `}`
+	req := httptest.NewRequest("POST", "/api/workouts/"+id+"/duplicate?user_id=1", bytes.NewBufferString(copyBody))
+	rr = httptest.NewRecorder()
+	h.DuplicateWorkout(rr, withURLParams(req, "id", id))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var copied struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&copied); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	// Bench press becomes push-ups, the closest match needing no equipment,
+	// without carrying its weight over
+	swapped := copied.Data.Details[0]
+	if swapped.ExerciseID != 2 || swapped.Weight != nil || swapped.Sets != 3 || swapped.Notes != "In place of Bench Press" {
+		t.Errorf("bench press not swapped: %+v", swapped)
+	}
+	kept := copied.Data.Details[1]
+	if kept.ExerciseID != 7 || kept.Weight == nil || *kept.Weight != 15 {
+		t.Errorf("bicep curls should be kept: %+v", kept)
+	}
+
+	// Repeating for another user's gym is not found
+	req = httptest.NewRequest("POST", "/api/workouts/repeat-last?user_id=2&gym="+strconv.FormatInt(gym.ID, 10), nil)
+	rr = httptest.NewRecorder()
+	h.CreateWorkout(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/workouts",
+		bytes.NewBufferString(`{"user_id": 2, "name": "Legs", "date": "2024-06-04", "details": [{"exercise_id": 5, "sets": 1, "reps": 5}]}`)))
+	h.RepeatLastWorkout(rr, req)
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "Gym not found") {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+
+	// Gym IDs must be positive
+	for _, gymID := range []string{"0", "-1", "abc"} {
+		rr = httptest.NewRecorder()
+		h.RepeatLastWorkout(rr, httptest.NewRequest("POST", "/api/workouts/repeat-last?user_id=1&gym="+gymID, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("gym %s: handler returned wrong status code: got %v want %v", gymID, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -3,22 +3,40 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"repup/internal/data"
+	"repup/internal/storage"
+	"strconv"
 )
 
 // Handlers holds our handler dependencies
 type Handlers struct {
 	db     *sql.DB
 	models data.Models // Add this line
+	store  storage.Store
 }
 
-// NewHandlers creates a new Handlers instance
-func NewHandlers(db *sql.DB) *Handlers {
+// NewHandlers creates a new Handlers instance. store keeps uploaded media.
+func NewHandlers(db *sql.DB, store storage.Store) *Handlers {
 	return &Handlers{
-		db: db,
+		db:    db,
+		store: store,
 		models: data.Models{
-			Workouts: &data.WorkoutModel{DB: db},
+			Workouts:         &data.WorkoutModel{DB: db},
+			WorkoutExercises: &data.WorkoutExerciseModel{DB: db},
+			BodyParts:        &data.BodyPartModel{DB: db},
+			Exercises:        &data.ExerciseModel{DB: db},
+			Muscles:          &data.MuscleModel{DB: db},
+			Equipment:        &data.EquipmentModel{DB: db},
+			Media:            &data.MediaModel{DB: db},
+			Gyms:             &data.GymModel{DB: db},
+			Users:            &data.UserModel{DB: db},
+			PersonalRecords:  &data.PersonalRecordModel{DB: db},
+			Stats:            &data.StatsModel{DB: db},
+			Measurements:     &data.MeasurementModel{DB: db},
+			Inventories:      &data.InventoryModel{DB: db},
+			Goals:            &data.GoalModel{DB: db},
 		},
 	}
 }
@@ -35,6 +53,27 @@ func (h *Handlers) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// currentUserID returns the ID of the requesting user. It prefers the user set
+// in the request context by the auth middleware and falls back to the user_id
+// query parameter until authentication is enforced on every route.
+func (h *Handlers) currentUserID(r *http.Request) (int64, error) {
+	if user, ok := r.Context().Value(data.UserContextKey).(*data.User); ok {
+		return user.ID, nil
+	}
+
+	userIDStr := r.URL.Query().Get("user_id")
+	if userIDStr == "" {
+		return 0, errors.New("user_id query parameter is required")
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID < 1 {
+		return 0, errors.New("Invalid user_id format")
+	}
+
+	return userID, nil
+}
+
 // envelope is a generic response wrapper
 type envelope map[string]interface{}
 
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"repup/internal/data"
+)
+
+// inventoryRequest represents the expected request body for saving an equipment inventory
+type inventoryRequest struct {
+	Unit              string           `json:"unit"` // Defaults to the user's preferred unit
+	Bars              []data.Bar       `json:"bars"`
+	Plates            []data.PlatePair `json:"plates"`
+	CollarWeight      float64          `json:"collar_weight"`
+	DumbbellIncrement float64          `json:"dumbbell_increment"`
+}
+
+// //////////////////////////////////////////////////////////////
+// GetInventory handles GET requests for the user's equipment inventory
+func (h *Handlers) GetInventory(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	inv, err := h.models.Inventories.Get(userID, h.preferredUnit(userID))
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, inv)
+}
+
+// ////////////////////////////////////////////////////////////////////
+// UpdateInventory handles PUT requests to replace the user's equipment inventory
+func (h *Handlers) UpdateInventory(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req inventoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	inv := &data.Inventory{
+		UserID:            userID,
+		Unit:              req.Unit,
+		Bars:              req.Bars,
+		Plates:            req.Plates,
+		CollarWeight:      req.CollarWeight,
+		DumbbellIncrement: req.DumbbellIncrement,
+	}
+	if inv.Unit == "" {
+		inv.Unit = h.preferredUnit(userID)
+	}
+
+	err = h.models.Inventories.Save(inv)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest,
+				"Invalid inventory: 1-10 bars of up to 200 are required, up to 20 unique plate weights from 0.01 to 100 with 1-20 pairs each, and the dumbbell increment must be positive")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, inv)
+}
+
+// ///////////////////////////////////////////////////////////////////////////
+// PlateCalculator handles GET requests for the plates to load for a target weight
+func (h *Handlers) PlateCalculator(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	target, err := strconv.ParseFloat(query.Get("target"), 64)
+	if err != nil || math.IsNaN(target) || math.IsInf(target, 0) || target <= 0 || target > data.MaxPlateTarget {
+		h.respondWithError(w, http.StatusBadRequest, "target must be a positive weight up to 10000")
+		return
+	}
+
+	inv, err := h.models.Inventories.Get(userID, h.preferredUnit(userID))
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	// The target is in the inventory's unit unless another is given
+	if unit := query.Get("unit"); unit != "" && unit != inv.Unit {
+		if !data.ValidUnit(unit) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid unit: must be kg or lb")
+			return
+		}
+		target = data.FromKilograms(data.ToKilograms(target, unit), inv.Unit)
+	}
+
+	bar := inv.Bars[0]
+	if barWeight := query.Get("bar"); barWeight != "" {
+		weight, err := strconv.ParseFloat(barWeight, 64)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid bar weight")
+			return
+		}
+		found := false
+		for _, b := range inv.Bars {
+			if b.Weight == weight {
+				bar, found = b, true
+				break
+			}
+		}
+		if !found {
+			h.respondWithError(w, http.StatusBadRequest, "No bar of that weight in the inventory")
+			return
+		}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, inv.LoadBar(target, bar))
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestPlateCalculator(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	plates := func(query string) data.PlateLoad {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.PlateCalculator(rr, httptest.NewRequest("GET", "/api/tools/plates?user_id=1&"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var response struct {
+			Data data.PlateLoad `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		return response.Data
+	}
+
+	// The default kg inventory: 20 kg bar, 25 down to 1.25 kg plates
+	load := plates("target=102.5")
+	if !load.Exact || load.Achieved != 102.5 {
+		t.Fatalf("wrong load for 102.5: %+v", load)
+	}
+	want := []data.PlateCount{{Weight: 25, Count: 1}, {Weight: 15, Count: 1}, {Weight: 1.25, Count: 1}}
+	if len(load.PerSide) != len(want) {
+		t.Fatalf("wrong plates per side: got %+v want %+v", load.PerSide, want)
+	}
+	for i := range want {
+		if load.PerSide[i] != want[i] {
+			t.Errorf("wrong plates per side: got %+v want %+v", load.PerSide, want)
+		}
+	}
+
+	// A home gym with a 15 kg bar, 2.5 kg collars and only a few plates
+	body := `{"unit": "kg", "bars": [{"name": "Technique bar", "weight": 15}],
+		"plates": [{"weight": 10, "pairs": 2}, {"weight": 5, "pairs": 1}], "collar_weight": 2.5, "dumbbell_increment": 2.5}`
+	rr := httptest.NewRecorder()
+	h.UpdateInventory(rr, httptest.NewRequest("PUT", "/api/inventory?user_id=1", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	// Targets must be finite and small enough to count in plate units
+	for _, target := range []string{"0", "-5", "NaN", "Inf", "1e300", "abc"} {
+		rr := httptest.NewRecorder()
+		h.PlateCalculator(rr, httptest.NewRequest("GET", "/api/tools/plates?user_id=1&target="+target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("target %s: handler returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	// 20 kg empty, loads go up in steps of 10 to 70 kg
+	if load := plates("target=47"); load.Exact || load.Achieved != 50 {
+		t.Errorf("wrong nearest load for 47: %+v", load)
+	}
+	if load := plates("target=45"); load.Achieved != 40 {
+		t.Errorf("ties should go to the lighter load: %+v", load)
+	}
+	if load := plates("target=200"); load.Achieved != 70 {
+		t.Errorf("wrong load beyond the inventory: %+v", load)
+	}
+	if load := plates("target=10"); load.Achieved != 20 || len(load.PerSide) != 0 {
+		t.Errorf("wrong load below the empty bar: %+v", load)
+	}
+
+	// Invalid inventories are rejected, including plates too light to load and
+	// weights heavy enough to blow up the plate table
+	for _, invalid := range []string{
+		`{"bars": [], "dumbbell_increment": 2}`,
+		`{"bars": [{"name": "Bar", "weight": 20}], "plates": [{"weight": 0.004, "pairs": 1}], "dumbbell_increment": 2}`,
+		`{"bars": [{"name": "Bar", "weight": 20}], "plates": [{"weight": 5, "pairs": 1}, {"weight": 5.001, "pairs": 1}], "dumbbell_increment": 2}`,
+		`{"bars": [{"name": "Bar", "weight": 20}], "plates": [{"weight": 1000000, "pairs": 20}], "dumbbell_increment": 2}`,
+		`{"bars": [{"name": "Bar", "weight": 1000000}], "dumbbell_increment": 2}`,
+		`{"bars": [{"name": "Bar", "weight": 20}], "plates": [{"weight": 20, "pairs": 21}], "dumbbell_increment": 2}`,
+	} {
+		rr = httptest.NewRecorder()
+		h.UpdateInventory(rr, httptest.NewRequest("PUT", "/api/inventory?user_id=1", bytes.NewBufferString(invalid)))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", invalid, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	// Suggestions round to what the inventory can load: 50 + 2.5 becomes 50
+	createTestWorkout(t, h, 1, 1, 3, 5, 50)
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/exercises/1/next-suggestion?user_id=1&strategy=linear", nil)
+	h.NextSuggestion(rr, withURLParams(req, "id", "1"))
+	var suggestion struct {
+		Data data.Suggestion `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&suggestion); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if suggestion.Data.Weight == nil || *suggestion.Data.Weight != 50 {
+		t.Errorf("wrong suggested weight: got %v want 50", suggestion.Data.Weight)
+	}
+
+	// However heavy the target, only so much is considered per side
+	body = `{"unit": "kg", "bars": [{"name": "Olympic bar", "weight": 20}],
+		"plates": [{"weight": 100, "pairs": 20}, {"weight": 0.01, "pairs": 20}], "dumbbell_increment": 2}`
+	rr = httptest.NewRecorder()
+	h.UpdateInventory(rr, httptest.NewRequest("PUT", "/api/inventory?user_id=1", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if load := plates("target=10000"); load.Achieved > 2020 {
+		t.Errorf("load beyond the per-side cap: %+v", load)
+	}
+}
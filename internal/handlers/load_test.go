@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestLoadStats(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Four steady weeks of three 1000 kg sessions, then a week of daily 3000 kg sessions
+	for week := 0; week < 4; week++ {
+		for _, day := range []int{1, 3, 5} {
+			createTestWorkout(t, h, week*7+day, 1, 2, 5, 100)
+		}
+	}
+	for day := 29; day <= 35; day++ {
+		createTestWorkout(t, h, day, 1, 6, 5, 100)
+	}
+
+	load := func(query string) data.LoadReport {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.LoadStats(rr, httptest.NewRequest("GET", "/api/stats/load?user_id=1&"+query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var response struct {
+			Data struct {
+				Results data.LoadReport `json:"results"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		return response.Data.Results
+	}
+
+	// At the end of the steady block acute and chronic loads match
+	steady := load("from=2024-01-28&to=2024-01-28")
+	if len(steady.Days) != 1 {
+		t.Fatalf("wrong number of days: got %d want 1", len(steady.Days))
+	}
+	day := steady.Days[0]
+	if day.ACWR == nil || math.Abs(*day.ACWR-1) > 1e-9 || len(day.Flags) != 0 || steady.DeloadAdvised {
+		t.Errorf("wrong steady state: %+v", day)
+	}
+	if day.Monotony == nil || math.Abs(*day.Monotony-0.866) > 0.001 {
+		t.Errorf("wrong monotony: %v", day.Monotony)
+	}
+
+	// A week at triple the volume spikes the ratio and advises a deload
+	spike := load("from=2024-02-01&to=2024-02-04")
+	if len(spike.Days) != 4 {
+		t.Fatalf("wrong number of days: got %d want 4", len(spike.Days))
+	}
+	last := spike.Days[3]
+	if last.Load != 3000 || last.Acute != 3000 || math.Abs(last.Chronic-30000.0/28) > 1e-9 {
+		t.Errorf("wrong loads: %+v", last)
+	}
+	if len(last.Flags) != 1 || last.Flags[0] != data.FlagACWRSpike || !spike.DeloadAdvised {
+		t.Errorf("expected an ACWR spike and deload: %+v %v", last, spike.DeloadReasons)
+	}
+
+	// Raising the threshold clears the flag
+	if relaxed := load("from=2024-02-04&to=2024-02-04&acwr_max=3"); relaxed.DeloadAdvised {
+		t.Errorf("deload advised above a raised threshold: %v", relaxed.DeloadReasons)
+	}
+
+	// Too little history to trust the ratio
+	if early := load("from=2024-01-10&to=2024-01-10"); early.ChronicHistory || len(early.Days[0].Flags) != 0 {
+		t.Errorf("ratio flagged without four weeks of history: %+v", early)
+	}
+
+	// The range is bounded like the other statistics
+	rr := httptest.NewRecorder()
+	h.LoadStats(rr, httptest.NewRequest("GET", "/api/stats/load?user_id=1&from=1000-01-01&to=2024-01-10", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Thresholds must be finite
+	for _, thresholds := range []string{"acwr_max=NaN", "acwr_min=nan", "monotony_max=NaN", "acwr_max=Inf"} {
+		rr = httptest.NewRecorder()
+		h.LoadStats(rr, httptest.NewRequest("GET", "/api/stats/load?user_id=1&from=2024-02-04&to=2024-02-04&"+thresholds, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", thresholds, rr.Code, http.StatusBadRequest)
+		}
+	}
+	if err := (data.LoadThresholds{ACWRMax: 1.5, ACWRMin: 0.8, MonotonyMax: math.NaN()}).Validate(); err == nil {
+		t.Error("NaN monotony threshold should be invalid")
+	}
+
+	// Session RPE must be on the 1-10 scale
+	rr = httptest.NewRecorder()
+	body := `{"user_id": 1, "name": "Hard", "date": "2024-02-05", "session_rpe": 11, "details": []}`
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Session RPE scales the load relative to RPE 7
+	body = `{"user_id": 1, "name": "Hard", "date": "2024-02-10", "session_rpe": 8.75, "details": [
+		{"exercise_id": 1, "sets": 2, "reps": 5, "weight": 100}
+	]}`
+	rr = httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if hard := load("from=2024-02-10&to=2024-02-10"); hard.Days[0].Load != 1250 {
+		t.Errorf("wrong RPE-scaled load: got %v want 1250", hard.Days[0].Load)
+	}
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Moving average window for measurement trends, in days
+const (
+	defaultTrendWindow = 7
+	maxTrendWindow     = 90
+)
+
+// measurementRequest represents the expected request body for creating/updating a measurement
+type measurementRequest struct {
+	Metric     string  `json:"metric"`
+	Value      float64 `json:"value"`
+	Unit       string  `json:"unit"`        // Defaults to the preferred unit for body weight and the canonical unit otherwise
+	MeasuredOn string  `json:"measured_on"` // Format: "2006-01-02"
+	Notes      string  `json:"notes"`
+}
+
+// toMeasurement validates the request and converts it to a measurement in canonical units
+func (h *Handlers) toMeasurement(req measurementRequest, userID int64) (*data.Measurement, error) {
+	if !data.ValidMetric(req.Metric) {
+		return nil, errors.New("Unknown metric")
+	}
+
+	measuredOn, err := time.Parse(data.DateFormat, req.MeasuredOn)
+	if err != nil {
+		return nil, errors.New("Invalid measured_on date format")
+	}
+
+	unit := req.Unit
+	if unit == "" && req.Metric == data.MetricBodyWeight {
+		unit = h.preferredUnit(userID)
+	}
+	value, err := data.ToMetricUnit(req.Metric, req.Value, unit)
+	if err != nil {
+		return nil, errors.New("Invalid unit for metric")
+	}
+
+	return &data.Measurement{
+		UserID:     userID,
+		Metric:     req.Metric,
+		Value:      value,
+		MeasuredOn: measuredOn,
+		Notes:      req.Notes,
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////
+// ListMeasurements handles GET requests for a user's measurements
+func (h *Handlers) ListMeasurements(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseMeasurementFilter(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	measurements, err := h.models.Measurements.GetAll(userID, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidInput) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, measurement := range measurements {
+		presentMeasurement(measurement, unit)
+	}
+
+	h.respondWithJSON(w, http.StatusOK, measurements)
+}
+
+// /////////////////////////////////////////////////////////
+// GetMeasurement handles GET requests for a single measurement
+func (h *Handlers) GetMeasurement(w http.ResponseWriter, r *http.Request) {
+	measurement, ok := h.ownedMeasurement(w, r)
+	if !ok {
+		return
+	}
+
+	unit, err := h.displayUnit(r, measurement.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentMeasurement(measurement, unit)
+	h.respondWithJSON(w, http.StatusOK, measurement)
+}
+
+// //////////////////////////////////////////////////////////////
+// CreateMeasurement handles POST requests to log a new measurement
+func (h *Handlers) CreateMeasurement(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req measurementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	measurement, err := h.toMeasurement(req, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.models.Measurements.Create(measurement)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidInput) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	presentMeasurement(measurement, unit)
+	h.respondWithJSON(w, http.StatusCreated, measurement)
+}
+
+// ///////////////////////////////////////////////////////////////////////
+// UpdateMeasurement handles PUT requests to update an existing measurement
+func (h *Handlers) UpdateMeasurement(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.ownedMeasurement(w, r)
+	if !ok {
+		return
+	}
+	unit, err := h.displayUnit(r, existing.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req measurementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	measurement, err := h.toMeasurement(req, existing.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	measurement.ID = existing.ID
+
+	err = h.models.Measurements.Update(measurement)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Measurement not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	presentMeasurement(measurement, unit)
+	h.respondWithJSON(w, http.StatusOK, measurement)
+}
+
+// ///////////////////////////////////////////////////////////////
+// DeleteMeasurement handles DELETE requests to remove a measurement
+func (h *Handlers) DeleteMeasurement(w http.ResponseWriter, r *http.Request) {
+	measurement, ok := h.ownedMeasurement(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.models.Measurements.Delete(measurement.UserID, measurement.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Measurement not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ///////////////////////////////////////////////////////////////////////////
+// MeasurementTrend handles GET requests for the moving average of a metric
+func (h *Handlers) MeasurementTrend(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseMeasurementFilter(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Metric == "" {
+		h.respondWithError(w, http.StatusBadRequest, "metric query parameter is required")
+		return
+	}
+
+	window := defaultTrendWindow
+	if err := parseIntParam(r.URL.Query().Get("window"), &window); err != nil || window < 1 || window > maxTrendWindow {
+		h.respondWithError(w, http.StatusBadRequest, "window must be between 1 and 90 days")
+		return
+	}
+
+	// Load the days before the range too so the first averages are complete
+	from := filter.From
+	if !from.IsZero() {
+		filter.From = from.AddDate(0, 0, 1-window)
+	}
+
+	measurements, err := h.models.Measurements.GetAll(userID, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidInput) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	points := []data.TrendPoint{}
+	for _, point := range data.MeasurementTrend(measurements, window) {
+		if !from.IsZero() && point.Date < from.Format(data.DateFormat) {
+			continue
+		}
+		if filter.Metric == data.MetricBodyWeight {
+			point.Value = data.ConvertMeasure(point.Value, unit)
+			point.MovingAverage = data.ConvertMeasure(point.MovingAverage, unit)
+		}
+		points = append(points, point)
+	}
+
+	metricUnit := data.MetricUnit(filter.Metric)
+	if filter.Metric == data.MetricBodyWeight {
+		metricUnit = unit
+	}
+
+	h.respondWithJSON(w, http.StatusOK, envelope{
+		"metric": filter.Metric,
+		"unit":   metricUnit,
+		"window": window,
+		"points": points,
+	})
+}
+
+// ownedMeasurement loads the measurement named in the URL, responding with an
+// error and returning false when it is missing or belongs to another user
+func (h *Handlers) ownedMeasurement(w http.ResponseWriter, r *http.Request) (*data.Measurement, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return nil, false
+	}
+
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	measurement, err := h.models.Measurements.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Measurement not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, false
+	}
+	if measurement.UserID != userID {
+		h.respondWithError(w, http.StatusNotFound, "Measurement not found")
+		return nil, false
+	}
+
+	return measurement, true
+}
+
+// parseMeasurementFilter reads the metric, from and to query parameters
+func parseMeasurementFilter(r *http.Request) (data.MeasurementFilter, error) {
+	query := r.URL.Query()
+	filter := data.MeasurementFilter{Metric: query.Get("metric")}
+	if filter.Metric != "" && !data.ValidMetric(filter.Metric) {
+		return filter, errors.New("Unknown metric")
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(data.DateFormat, from); err != nil {
+			return filter, errors.New("Invalid from date format")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(data.DateFormat, to); err != nil {
+			return filter, errors.New("Invalid to date format")
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestMeasurements(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.CreateMeasurement(rr, httptest.NewRequest("POST", "/api/measurements?user_id=1", bytes.NewBufferString(body)))
+		return rr
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Body weight", `{"metric": "body_weight", "value": 80, "measured_on": "2024-01-01"}`, http.StatusCreated},
+		{"Body weight in pounds", `{"metric": "body_weight", "value": 176.4, "unit": "lb", "measured_on": "2024-01-03"}`, http.StatusCreated},
+		{"Waist in inches", `{"metric": "waist", "value": 32, "unit": "in", "measured_on": "2024-01-03"}`, http.StatusCreated},
+		{"Unknown metric", `{"metric": "shoe_size", "value": 44, "measured_on": "2024-01-03"}`, http.StatusBadRequest},
+		{"Wrong unit for metric", `{"metric": "waist", "value": 32, "unit": "lb", "measured_on": "2024-01-03"}`, http.StatusBadRequest},
+		{"Body fat over 100%", `{"metric": "body_fat", "value": 120, "measured_on": "2024-01-03"}`, http.StatusBadRequest},
+		{"Missing date", `{"metric": "body_fat", "value": 15}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := create(tt.body); rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+					rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+
+	// Tape measurements are stored in centimeters
+	rr := httptest.NewRecorder()
+	h.ListMeasurements(rr, httptest.NewRequest("GET", "/api/measurements?user_id=1&metric=waist", nil))
+	var list struct {
+		Data []data.Measurement `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].Value != 81.28 || list.Data[0].Unit != "cm" {
+		t.Fatalf("wrong waist measurements: %+v", list.Data)
+	}
+
+	// Another user's measurement is not visible
+	id := fmt.Sprint(list.Data[0].ID)
+	rr = httptest.NewRecorder()
+	h.GetMeasurement(rr, withURLParams(httptest.NewRequest("GET", "/api/measurements/"+id+"?user_id=2", nil), "id", id))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	rr = httptest.NewRecorder()
+	h.DeleteMeasurement(rr, withURLParams(httptest.NewRequest("DELETE", "/api/measurements/"+id+"?user_id=1", nil), "id", id))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+
+	// Moving average over a 3 day window: 80, 80 (176.4 lb), then 77 alone
+	create(`{"metric": "body_weight", "value": 77, "measured_on": "2024-01-06"}`)
+
+	rr = httptest.NewRecorder()
+	h.MeasurementTrend(rr, httptest.NewRequest("GET", "/api/measurements/trend?user_id=1&metric=body_weight&window=3", nil))
+	var trend struct {
+		Data struct {
+			Unit   string            `json:"unit"`
+			Points []data.TrendPoint `json:"points"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&trend); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(trend.Data.Points) != 3 {
+		t.Fatalf("wrong number of trend points: got %d want 3", len(trend.Data.Points))
+	}
+	if p := trend.Data.Points[1]; p.Samples != 2 || p.MovingAverage < 79.99 || p.MovingAverage > 80.01 {
+		t.Errorf("wrong moving average on %s: %+v", p.Date, p)
+	}
+	if p := trend.Data.Points[2]; p.Samples != 1 || p.MovingAverage != 77 {
+		t.Errorf("wrong moving average on %s: %+v", p.Date, p)
+	}
+
+	// Relative strength uses the body weight closest to each session: the
+	// session on the 5th is nearer the 77 kg weigh-in on the 6th
+	createTestWorkout(t, h, 2, 1, 1, 1, 100)
+	createTestWorkout(t, h, 5, 1, 1, 1, 154)
+
+	rr = httptest.NewRecorder()
+	h.RelativeStrengthStats(rr, httptest.NewRequest("GET",
+		"/api/stats/relative-strength?user_id=1&exercise_id=1&from=2024-01-01&to=2024-01-31", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var strength struct {
+		Data struct {
+			Results []data.RelativeStrength `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&strength); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(strength.Data.Results) != 2 {
+		t.Fatalf("wrong number of sessions: got %d want 2", len(strength.Data.Results))
+	}
+	if got := strength.Data.Results[0].BestWeightMultiple; got != 1.25 {
+		t.Errorf("wrong multiple for first session: got %v want 1.25", got)
+	}
+	if got := strength.Data.Results[1].BestWeightMultiple; got != 2 {
+		t.Errorf("wrong multiple for second session: got %v want 2", got)
+	}
+}
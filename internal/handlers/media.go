@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"repup/internal/data"
+	"repup/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	maxImageBytes = 10 << 20 // Largest image upload
+	maxVideoBytes = 50 << 20 // Largest video upload
+
+	// thumbnailSide is the longest side of an image thumbnail, in pixels
+	thumbnailSide = 320
+
+	defaultMediaURLTTL = 15 * time.Minute
+	minMediaURLTTL     = time.Minute
+	maxMediaURLTTL     = 24 * time.Hour
+)
+
+// mediaType describes an accepted upload content type
+type mediaType struct {
+	kind string
+	ext  string
+}
+
+// mediaTypes are the content types accepted for upload, as sniffed from the
+// file itself
+var mediaTypes = map[string]mediaType{
+	"image/jpeg": {data.MediaImage, ".jpg"},
+	"image/png":  {data.MediaImage, ".png"},
+	"image/gif":  {data.MediaImage, ".gif"},
+	"image/webp": {data.MediaImage, ".webp"},
+	"video/mp4":  {data.MediaVideo, ".mp4"},
+	"video/webm": {data.MediaVideo, ".webm"},
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// UploadExerciseMedia handles multipart POST requests attaching an image or
+// video, sent in the file field, to an exercise
+func (h *Handlers) UploadExerciseMedia(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if _, ok := h.ownedExercise(w, r, exerciseID); !ok {
+		return
+	}
+	if !h.requireStore(w) {
+		return
+	}
+	userID, _ := h.currentUserID(r)
+
+	// Leave room for the multipart envelope around the largest file
+	r.Body = http.MaxBytesReader(w, r.Body, maxVideoBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large")
+			return
+		}
+		h.respondWithError(w, http.StatusBadRequest, "A multipart file field is required")
+		return
+	}
+	defer file.Close()
+
+	// Trust the file's contents rather than the type the client claims
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		h.respondWithError(w, http.StatusBadRequest, "Could not read file")
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	mt, ok := mediaTypes[contentType]
+	if !ok {
+		h.respondWithError(w, http.StatusUnsupportedMediaType,
+			"Unsupported file type: must be a JPEG, PNG, GIF or WebP image or an MP4 or WebM video")
+		return
+	}
+
+	limit := int64(maxImageBytes)
+	if mt.kind == data.MediaVideo {
+		limit = maxVideoBytes
+	}
+	if header.Size > limit {
+		h.respondWithError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File is too large: %ss may be at most %d MB", mt.kind, limit>>20))
+		return
+	}
+	if header.Size == 0 {
+		h.respondWithError(w, http.StatusBadRequest, "File is empty")
+		return
+	}
+
+	media := &data.Media{
+		ExerciseID:  exerciseID,
+		UserID:      userID,
+		Kind:        mt.kind,
+		ContentType: contentType,
+		SizeBytes:   header.Size,
+	}
+
+	// The standard library cannot decode WebP, so those images go without a
+	// thumbnail
+	var thumbnail []byte
+	if mt.kind == data.MediaImage && contentType != "image/webp" {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Could not read file")
+			return
+		}
+		thumb, dims, err := storage.Thumbnail(file, thumbnailSide)
+		if err != nil {
+			if errors.Is(err, storage.ErrImageTooLarge) {
+				h.respondWithError(w, http.StatusBadRequest, "Image dimensions are too large")
+				return
+			}
+			h.respondWithError(w, http.StatusBadRequest, "Invalid image")
+			return
+		}
+		thumbnail = thumb
+		width, height := dims.X, dims.Y
+		media.Width, media.Height = &width, &height
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Could not read file")
+		return
+	}
+
+	prefix := fmt.Sprintf("exercises/%d", exerciseID)
+	media.StorageKey, err = storage.NewKey(prefix, mt.ext)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Storage error")
+		return
+	}
+	if err := h.store.Put(r.Context(), media.StorageKey, file, header.Size, contentType); err != nil {
+		log.Printf("Storage error in UploadExerciseMedia: %v", err)
+		h.respondWithError(w, http.StatusInternalServerError, "Storage error")
+		return
+	}
+
+	if thumbnail != nil {
+		media.ThumbnailKey, err = storage.NewKey(prefix+"/thumbnails", ".jpg")
+		if err == nil {
+			err = h.store.Put(r.Context(), media.ThumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg")
+		}
+		if err != nil {
+			log.Printf("Storage error in UploadExerciseMedia: %v", err)
+			h.deleteMediaBlobs(r.Context(), media)
+			h.respondWithError(w, http.StatusInternalServerError, "Storage error")
+			return
+		}
+	}
+
+	if err := h.models.Media.Create(media); err != nil {
+		h.deleteMediaBlobs(r.Context(), media)
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	if err := h.signMedia(media, defaultMediaURLTTL); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Storage error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusCreated, media)
+}
+
+// ///////////////////////////////////////////////////////////////////////////
+// ListExerciseMedia handles GET requests for the media of an exercise, with
+// download links valid for expires_in seconds
+func (h *Handlers) ListExerciseMedia(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if _, ok := h.visibleExercise(w, r, exerciseID); !ok {
+		return
+	}
+	if !h.requireStore(w) {
+		return
+	}
+	ttl, ok := h.mediaURLTTL(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.models.Media.GetByExercise(exerciseID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	for _, media := range items {
+		if err := h.signMedia(media, ttl); err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Storage error")
+			return
+		}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, items)
+}
+
+// /////////////////////////////////////////////////////////////////////////
+// GetExerciseMedia handles GET requests for a single media item of an exercise
+func (h *Handlers) GetExerciseMedia(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if _, ok := h.visibleExercise(w, r, exerciseID); !ok {
+		return
+	}
+	if !h.requireStore(w) {
+		return
+	}
+	ttl, ok := h.mediaURLTTL(w, r)
+	if !ok {
+		return
+	}
+
+	media, ok := h.exerciseMediaParam(w, r, exerciseID)
+	if !ok {
+		return
+	}
+
+	if err := h.signMedia(media, ttl); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Storage error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, media)
+}
+
+// ///////////////////////////////////////////////////////////////////////////
+// DeleteExerciseMedia handles DELETE requests for a media item of an exercise
+func (h *Handlers) DeleteExerciseMedia(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if _, ok := h.ownedExercise(w, r, exerciseID); !ok {
+		return
+	}
+
+	media, ok := h.exerciseMediaParam(w, r, exerciseID)
+	if !ok {
+		return
+	}
+
+	if err := h.models.Media.Delete(media.ID); err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			h.respondWithError(w, http.StatusNotFound, "Media not found")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.deleteMediaBlobs(r.Context(), media)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// exerciseMediaParam loads the media item named by the mediaId URL parameter,
+// responding with 404 unless it belongs to the exercise
+func (h *Handlers) exerciseMediaParam(w http.ResponseWriter, r *http.Request, exerciseID int64) (*data.Media, bool) {
+	mediaID, err := strconv.ParseInt(chi.URLParam(r, "mediaId"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid media ID format")
+		return nil, false
+	}
+
+	media, err := h.models.Media.GetByID(mediaID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusNotFound, "Media not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, false
+	}
+	if media.ExerciseID != exerciseID {
+		h.respondWithError(w, http.StatusNotFound, "Media not found")
+		return nil, false
+	}
+
+	return media, true
+}
+
+// requireStore responds with 503 when no blob store is configured
+func (h *Handlers) requireStore(w http.ResponseWriter) bool {
+	if h.store == nil {
+		h.respondWithError(w, http.StatusServiceUnavailable, "Media storage is not configured")
+		return false
+	}
+	return true
+}
+
+// mediaURLTTL reads how long download links stay valid from the expires_in
+// query parameter, in seconds
+func (h *Handlers) mediaURLTTL(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	seconds := int(defaultMediaURLTTL / time.Second)
+	if err := parseIntParam(r.URL.Query().Get("expires_in"), &seconds); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid expires_in")
+		return 0, false
+	}
+
+	ttl := time.Duration(seconds) * time.Second
+	if ttl < minMediaURLTTL || ttl > maxMediaURLTTL {
+		h.respondWithError(w, http.StatusBadRequest, "expires_in must be between 60 and 86400 seconds")
+		return 0, false
+	}
+	return ttl, true
+}
+
+// signMedia sets the download links of a media item, valid for ttl
+func (h *Handlers) signMedia(media *data.Media, ttl time.Duration) error {
+	url, err := h.store.SignedURL(media.StorageKey, ttl)
+	if err != nil {
+		return err
+	}
+	media.URL = url
+
+	if media.ThumbnailKey != "" {
+		media.ThumbnailURL, err = h.store.SignedURL(media.ThumbnailKey, ttl)
+		if err != nil {
+			return err
+		}
+	}
+
+	expiresAt := time.Now().UTC().Add(ttl).Truncate(time.Second)
+	media.ExpiresAt = &expiresAt
+	return nil
+}
+
+// deleteMediaBlobs removes the files of media items whose records are gone.
+// Failures only leave orphaned blobs behind, so they are logged, not returned.
+func (h *Handlers) deleteMediaBlobs(ctx context.Context, items ...*data.Media) {
+	if h.store == nil {
+		return
+	}
+	for _, media := range items {
+		for _, key := range []string{media.StorageKey, media.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+			if err := h.store.Delete(ctx, key); err != nil {
+				log.Printf("Storage error deleting %s: %v", key, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"repup/internal/data"
+	"repup/internal/storage"
+)
+
+// uploadMedia posts content as the file field of a multipart form to an
+// exercise's media
+func uploadMedia(t *testing.T, h *Handlers, exerciseID, userID string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/exercises/"+exerciseID+"/media?user_id="+userID, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req = withURLParams(req, "id", exerciseID)
+	rr := httptest.NewRecorder()
+	h.UploadExerciseMedia(rr, req)
+	return rr
+}
+
+// fetchSigned downloads a signed link through the test store
+func fetchSigned(t *testing.T, h *Handlers, link string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	if !strings.HasPrefix(link, testFilesURL+"/") {
+		t.Fatalf("signed link %q does not point at the test store", link)
+	}
+	req := httptest.NewRequest("GET", strings.TrimPrefix(link, testFilesURL), nil)
+	rr := httptest.NewRecorder()
+	h.store.(http.Handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExerciseMedia(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Sled Push", "body_part_id": 5, "visibility": "global"}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	id := strconv.FormatInt(created.Data.ID, 10)
+
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 640; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var pngFile bytes.Buffer
+	if err := png.Encode(&pngFile, img); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+
+	rr = uploadMedia(t, h, id, "1", pngFile.Bytes())
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var uploaded struct {
+		Data data.Media `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&uploaded); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	media := uploaded.Data
+	if media.Kind != data.MediaImage || media.ContentType != "image/png" || media.SizeBytes != int64(pngFile.Len()) {
+		t.Errorf("unexpected media: %+v", media)
+	}
+	if media.Width == nil || *media.Width != 640 || media.Height == nil || *media.Height != 480 {
+		t.Errorf("image dimensions were not recorded: %+v", media)
+	}
+	if media.URL == "" || media.ThumbnailURL == "" || media.ExpiresAt == nil {
+		t.Fatalf("upload should return signed links: %+v", media)
+	}
+
+	// The signed links download the original and a JPEG thumbnail
+	if rr := fetchSigned(t, h, media.URL); rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), pngFile.Bytes()) {
+		t.Errorf("original download failed: status %v, %d bytes", rr.Code, rr.Body.Len())
+	}
+	rr = fetchSigned(t, h, media.ThumbnailURL)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("thumbnail download returned status %v", rr.Code)
+	}
+	thumb, err := jpeg.DecodeConfig(rr.Body)
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if thumb.Width != thumbnailSide || thumb.Height != 240 {
+		t.Errorf("thumbnail is %dx%d, want %dx240", thumb.Width, thumb.Height, thumbnailSide)
+	}
+	if rr := fetchSigned(t, h, strings.Replace(media.URL, "signature=", "signature=0", 1)); rr.Code != http.StatusForbidden {
+		t.Errorf("tampered link returned status %v, want %v", rr.Code, http.StatusForbidden)
+	}
+
+	// Anyone who can see the exercise can list its media
+	req := httptest.NewRequest("GET", "/api/exercises/"+id+"/media?user_id=2&expires_in=120", nil)
+	req = withURLParams(req, "id", id)
+	rr = httptest.NewRecorder()
+	h.ListExerciseMedia(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var listed struct {
+		Data []data.Media `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(listed.Data) != 1 || listed.Data[0].ID != media.ID || listed.Data[0].URL == "" {
+		t.Errorf("unexpected media list: %+v", listed.Data)
+	}
+
+	req = httptest.NewRequest("GET", "/api/exercises/"+id+"/media?user_id=1&expires_in=10", nil)
+	req = withURLParams(req, "id", id)
+	rr = httptest.NewRecorder()
+	h.ListExerciseMedia(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Only the owner may upload or delete
+	if rr := uploadMedia(t, h, id, "2", pngFile.Bytes()); rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	// Content is sniffed, and each kind has a size limit
+	if rr := uploadMedia(t, h, id, "1", []byte("just some notes")); rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnsupportedMediaType)
+	}
+	oversized := append(pngFile.Bytes()[:8:8], make([]byte, maxImageBytes)...)
+	if rr := uploadMedia(t, h, id, "1", oversized); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+	if rr := uploadMedia(t, h, id, "1", pngFile.Bytes()[:64]); rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	mediaID := strconv.FormatInt(media.ID, 10)
+	deleteMedia := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/api/exercises/"+id+"/media/"+mediaID+"?user_id="+userID, nil)
+		req = withURLParams(req, "id", id, "mediaId", mediaID)
+		rr := httptest.NewRecorder()
+		h.DeleteExerciseMedia(rr, req)
+		return rr
+	}
+	if rr := deleteMedia("2"); rr.Code != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+	if rr := deleteMedia("1"); rr.Code != http.StatusNoContent {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+	if rr := fetchSigned(t, h, media.URL); rr.Code != http.StatusNotFound {
+		t.Errorf("deleted media is still served: status %v", rr.Code)
+	}
+	if rr := deleteMedia("1"); rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestExerciseMediaWithoutStore(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// The default deployment has no signing secret, so no store can be built
+	store, err := storage.New(storage.Config{LocalDir: t.TempDir()})
+	if err == nil {
+		t.Fatal("a local store without a signing secret should not be built")
+	}
+	h = NewHandlers(h.db, store)
+
+	rr := httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Sled Push", "body_part_id": 5}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	id := strconv.FormatInt(created.Data.ID, 10)
+
+	if rr := uploadMedia(t, h, id, "1", []byte("fake image")); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("upload: got status %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	rr = httptest.NewRecorder()
+	h.ListExerciseMedia(rr, withURLParams(httptest.NewRequest("GET", "/api/exercises/"+id+"/media?user_id=1", nil), "id", id))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("list: got status %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	rr = httptest.NewRecorder()
+	h.GetExerciseMedia(rr, withURLParams(httptest.NewRequest("GET", "/api/exercises/"+id+"/media/1?user_id=1", nil), "id", id, "mediaId", "1"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("get: got status %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+// mergePatchContentType is the media type of RFC 7396 JSON merge patches
+const mergePatchContentType = "application/merge-patch+json"
+
+// applyMergePatch applies an RFC 7396 merge patch to a decoded JSON document:
+// members of a patch object replace those of the target, null members remove
+// them, and any other patch value, arrays included, replaces the target whole
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = applyMergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
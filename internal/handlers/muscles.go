@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ///////////////////////////////////////////////////////////////////////////
+// ListMuscles handles GET requests for muscles, optionally those of one body
+// part given by the body_part_id query parameter
+func (h *Handlers) ListMuscles(w http.ResponseWriter, r *http.Request) {
+	var bodyPartID int64
+	if value := r.URL.Query().Get("body_part_id"); value != "" {
+		var err error
+		bodyPartID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || bodyPartID < 1 {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid body_part_id format")
+			return
+		}
+	}
+
+	muscles, err := h.models.Muscles.GetAll(bodyPartID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, muscles)
+}
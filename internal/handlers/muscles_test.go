@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+// exerciseMuscles returns the muscles of an exercise keyed by name
+func exerciseMuscles(t *testing.T, h *Handlers, id int64) map[string]data.ExerciseMuscle {
+	t.Helper()
+
+	idStr := strconv.FormatInt(id, 10)
+	rr := httptest.NewRecorder()
+	h.GetExercise(rr, withURLParams(httptest.NewRequest("GET", "/api/exercises/"+idStr+"?user_id=1", nil), "id", idStr))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var response struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	muscles := map[string]data.ExerciseMuscle{}
+	for _, muscle := range response.Data.Muscles {
+		muscles[muscle.Name] = muscle
+	}
+	return muscles
+}
+
+func TestExerciseMuscles(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Migrated data credits the deadlift to more than the back
+	deadlift := exerciseMuscles(t, h, 3)
+	if deadlift["Glutes"].Role != data.RolePrimary || *deadlift["Trapezius"].Contribution != 0.5 {
+		t.Errorf("wrong deadlift muscles: %+v", deadlift)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ListMuscles(rr, httptest.NewRequest("GET", "/api/muscles?body_part_id=3", nil))
+	var muscles struct {
+		Data []data.Muscle `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&muscles); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	ids := map[string]int64{}
+	for _, muscle := range muscles.Data {
+		ids[muscle.Name] = muscle.ID
+	}
+	if len(muscles.Data) != 4 || ids["Glutes"] == 0 {
+		t.Fatalf("wrong leg muscles: %+v", muscles.Data)
+	}
+	glutes, hamstrings := strconv.FormatInt(ids["Glutes"], 10), strconv.FormatInt(ids["Hamstrings"], 10)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Default muscle", `{"name": "Leg Press", "body_part_id": 3}`, http.StatusCreated},
+		{"Explicit muscles", `{"name": "Hip Thrust", "body_part_id": 3, "muscles": [
+			{"muscle_id": ` + glutes + `, "role": "primary"},
+			{"muscle_id": ` + hamstrings + `, "role": "secondary", "contribution": 0.25}]}`, http.StatusCreated},
+		{"No primary muscle", `{"name": "Bad", "body_part_id": 3, "muscles": [
+			{"muscle_id": ` + glutes + `, "role": "secondary"}]}`, http.StatusBadRequest},
+		{"Unknown muscle", `{"name": "Bad", "body_part_id": 3, "muscles": [
+			{"muscle_id": 999, "role": "primary"}]}`, http.StatusBadRequest},
+		{"Contribution over one", `{"name": "Bad", "body_part_id": 3, "muscles": [
+			{"muscle_id": ` + glutes + `, "role": "primary", "contribution": 2}]}`, http.StatusBadRequest},
+		{"Unknown role", `{"name": "Bad", "body_part_id": 3, "muscles": [
+			{"muscle_id": ` + glutes + `, "role": "main"}]}`, http.StatusBadRequest},
+	}
+	created := map[string]int64{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1", bytes.NewBufferString(tt.body)))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if rr.Code == http.StatusCreated {
+				var response struct {
+					Data exerciseResponse `json:"data"`
+				}
+				if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+					t.Fatalf("Failed to decode response body: %v", err)
+				}
+				created[response.Data.Name] = response.Data.ID
+			}
+		})
+	}
+
+	if got := exerciseMuscles(t, h, created["Leg Press"]); len(got) != 1 || got["Quadriceps"].Role != data.RolePrimary {
+		t.Errorf("expected the body part's first muscle as primary: %+v", got)
+	}
+	if got := exerciseMuscles(t, h, created["Hip Thrust"]); *got["Hamstrings"].Contribution != 0.25 {
+		t.Errorf("wrong contribution: %+v", got)
+	}
+
+	// Updating without muscles keeps them
+	hipThrust := strconv.FormatInt(created["Hip Thrust"], 10)
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/exercises/"+hipThrust+"?user_id=1", bytes.NewBufferString(`{"name": "Barbell Hip Thrust", "body_part_id": 3}`))
+	h.UpdateExercise(rr, withURLParams(req, "id", hipThrust))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if got := exerciseMuscles(t, h, created["Hip Thrust"]); len(got) != 2 {
+		t.Errorf("muscles should be kept: %+v", got)
+	}
+
+	// Sets are credited fractionally: a 3x5 deadlift at 100 gives the
+	// trapezius half of each set, and a hip thrust a quarter to the hamstrings
+	weight := 100.0
+	workout := &data.Workout{UserID: 1, Name: "Pull", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Details: []data.WorkoutExercise{
+			{ExerciseID: 3, Sets: 3, Reps: 5, Weight: &weight},
+			{ExerciseID: created["Hip Thrust"], Sets: 4, Reps: 10, Weight: &weight},
+		}}
+	if err := h.models.Workouts.Create(workout); err != nil {
+		t.Fatalf("Failed to create workout: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	h.VolumeStats(rr, httptest.NewRequest("GET", "/api/stats/volume?user_id=1&from=2024-01-01&to=2024-01-07&group_by=muscle", nil))
+	var stats struct {
+		Data struct {
+			Results []data.VolumeSeries `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	totals := map[string]data.VolumeTotals{}
+	for _, series := range stats.Data.Results {
+		totals[series.Name] = series.Totals
+	}
+	expected := map[string]data.VolumeTotals{
+		"Glutes":     {Sets: 7, Reps: 55, Tonnage: 5500},
+		"Trapezius":  {Sets: 1.5, Reps: 7.5, Tonnage: 750},
+		"Hamstrings": {Sets: 4, Reps: 25, Tonnage: 2500},
+	}
+	for name, want := range expected {
+		if totals[name] != want {
+			t.Errorf("wrong %s volume: got %+v want %+v", name, totals[name], want)
+		}
+	}
+
+	// A body part whose muscles are worked by another body part's exercise
+	// cannot be deleted; once unused, its muscles are deleted with it
+	if _, err := h.db.Exec(`
+		INSERT INTO body_parts (name) VALUES ('Neck');
+		INSERT INTO muscles (name, body_part_id) SELECT 'Neck Flexors', id FROM body_parts WHERE name = 'Neck';
+		INSERT INTO exercise_muscles (exercise_id, muscle_id, role)
+		SELECT 8, id, 'stabilizer' FROM muscles WHERE name = 'Neck Flexors'`); err != nil {
+		t.Fatalf("Failed to insert neck muscles: %v", err)
+	}
+	var neck string
+	if err := h.db.QueryRow("SELECT id FROM body_parts WHERE name = 'Neck'").Scan(&neck); err != nil {
+		t.Fatalf("Failed to find body part: %v", err)
+	}
+
+	rr = httptest.NewRecorder()
+	h.DeleteBodyPart(rr, withURLParams(httptest.NewRequest("DELETE", "/api/body-parts/"+neck, nil), "id", neck))
+	if rr.Code != http.StatusConflict {
+		t.Errorf("deleting a body part with used muscles: got %v want %v", rr.Code, http.StatusConflict)
+	}
+
+	if _, err := h.db.Exec("DELETE FROM exercise_muscles WHERE exercise_id = 8 AND role = 'stabilizer'"); err != nil {
+		t.Fatalf("Failed to unmap muscle: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	h.DeleteBodyPart(rr, withURLParams(httptest.NewRequest("DELETE", "/api/body-parts/"+neck, nil), "id", neck))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("deleting a body part with unused muscles: got %v want %v: %s", rr.Code, http.StatusNoContent, rr.Body.String())
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"repup/internal/openapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestRoutesMatchOpenAPIDocument fails when a route is added or removed
+// without updating the document, or the document describes a route that is
+// not served
+func TestRoutesMatchOpenAPIDocument(t *testing.T) {
+	h := setupSchemaHandler(t)
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Failed to load the OpenAPI document: %v", err)
+	}
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	var served []string
+	err = chi.Walk(r, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		served = append(served, method+" "+route)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk the routes: %v", err)
+	}
+	sort.Strings(served)
+
+	documented := spec.Operations()
+	if strings.Join(served, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("routes and OpenAPI document differ\nserved:\n  %s\ndocumented:\n  %s",
+			strings.Join(served, "\n  "), strings.Join(documented, "\n  "))
+	}
+}
+
+// TestResponsesMatchOpenAPIDocument drives every operation through the router
+// with request and response validation on, so a handler whose output drifts
+// from the document fails here
+func TestResponsesMatchOpenAPIDocument(t *testing.T) {
+	h := setupSchemaHandler(t)
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatalf("Failed to load the OpenAPI document: %v", err)
+	}
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r, spec.Middleware(openapi.Options{
+		ValidateResponses: true,
+		ResponseError: func(r *http.Request, err error) {
+			t.Errorf("%s %s: %v", r.Method, r.URL, err)
+		},
+	}))
+
+	covered := map[string]bool{}
+	send := func(req *http.Request, want int) map[string]interface{} {
+		t.Helper()
+		if operation, ok := spec.Match(req.Method, req.URL.Path); ok {
+			covered[operation] = true
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Fatalf("%s %s: got status %d want %d: %s", req.Method, req.URL, rr.Code, want, rr.Body.String())
+		}
+		var body map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		return body
+	}
+	call := func(method, target, body string, want int) map[string]interface{} {
+		t.Helper()
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, target, reader)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return send(req, want)
+	}
+	// created returns the ID of the resource in a response
+	created := func(body map[string]interface{}) string {
+		t.Helper()
+		resource, _ := body["data"].(map[string]interface{})
+		id, ok := resource["id"].(float64)
+		if !ok {
+			t.Fatalf("response has no ID: %v", body)
+		}
+		return strconv.FormatInt(int64(id), 10)
+	}
+
+	call("GET", "/api/openapi.json", "", http.StatusOK)
+
+	call("PUT", "/api/settings?user_id=1", `{"preferred_unit": "kg", "weekly_target": 3, "timezone": "UTC"}`, http.StatusOK)
+	call("GET", "/api/settings?user_id=1", "", http.StatusOK)
+
+	// Catalog
+	call("GET", "/api/body-parts?limit=3", "", http.StatusOK)
+	call("GET", "/api/body-parts/9999", "", http.StatusNotFound)
+	bodyPart := created(call("POST", "/api/body-parts", `{"name": "Forearms", "parent_id": 5}`, http.StatusCreated))
+	call("GET", "/api/body-parts/tree", "", http.StatusOK)
+	call("GET", "/api/body-parts/"+bodyPart, "", http.StatusOK)
+	call("PUT", "/api/body-parts/"+bodyPart, `{"name": "Grip", "parent_id": 5}`, http.StatusOK)
+	call("DELETE", "/api/body-parts/"+bodyPart, "", http.StatusNoContent)
+	call("GET", "/api/muscles?body_part_id=1", "", http.StatusOK)
+	call("GET", "/api/equipment", "", http.StatusOK)
+
+	gym := created(call("POST", "/api/gyms?user_id=1", `{"name": "Garage", "equipment_ids": [1]}`, http.StatusCreated))
+	call("GET", "/api/gyms?user_id=1", "", http.StatusOK)
+	call("GET", "/api/gyms/"+gym+"?user_id=1", "", http.StatusOK)
+	call("PUT", "/api/gyms/"+gym+"?user_id=1", `{"name": "Garage", "equipment_ids": [1, 2]}`, http.StatusOK)
+
+	exercise := created(call("POST", "/api/exercises?user_id=1",
+		`{"name": "Sled Push", "body_part_id": 3, "visibility": "global", "aliases": ["Prowler"]}`, http.StatusCreated))
+	call("GET", "/api/exercises?user_id=1&limit=5", "", http.StatusOK)
+	call("GET", "/api/exercises/"+exercise+"?user_id=1", "", http.StatusOK)
+	call("PUT", "/api/exercises/"+exercise+"?user_id=1",
+		`{"name": "Sled Push", "body_part_id": 3, "movement_pattern": "locomotion", "muscles": [{"muscle_id": 1, "role": "primary", "contribution": null}]}`, http.StatusOK)
+	call("GET", "/api/exercises/1/substitutes?user_id=1&limit=3", "", http.StatusOK)
+
+	var pngFile bytes.Buffer
+	if err := png.Encode(&pngFile, image.NewRGBA(image.Rect(0, 0, 64, 48))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	part, _ := form.CreateFormFile("file", "sled.png")
+	part.Write(pngFile.Bytes())
+	form.Close()
+	req := httptest.NewRequest("POST", "/api/exercises/"+exercise+"/media?user_id=1", &upload)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	media := created(send(req, http.StatusCreated))
+	call("GET", "/api/exercises/"+exercise+"/media?user_id=1", "", http.StatusOK)
+	call("GET", "/api/exercises/"+exercise+"/media/"+media+"?user_id=1&expires_in=60", "", http.StatusOK)
+	call("DELETE", "/api/exercises/"+exercise+"/media/"+media+"?user_id=1", "", http.StatusNoContent)
+	call("DELETE", "/api/exercises/"+exercise+"?user_id=1", "", http.StatusNoContent)
+
+	// Training log
+	call("POST", "/api/workouts",
+		`{"user_id": 1, "name": "Push", "date": "2024-03-01", "tags": ["push"], "session_rpe": 8,
+          "details": [{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 100}, {"exercise_id": 9, "sets": 3, "duration_seconds": 60}]}`, http.StatusCreated)
+	workout := created(call("POST", "/api/workouts",
+		`{"user_id": 1, "name": "Push", "date": "2024-03-04", "started_at": "2024-03-04T18:00:00Z", "ended_at": "2024-03-04T19:00:00Z",
+          "details": [{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 105, "rpe": 8, "group_id": 1, "group_type": "superset"},
+                      {"exercise_id": 4, "sets": 3, "reps": 8, "group_id": 1, "group_type": "superset"}]}`, http.StatusCreated))
+	call("GET", "/api/workouts?user_id=1&tag=push&limit=1", "", http.StatusOK)
+	call("GET", "/api/workouts/"+workout, "", http.StatusOK)
+	call("PUT", "/api/workouts/"+workout+"?user_id=1",
+		`{"user_id": 1, "name": "Push", "date": "2024-03-04", "notes": "Felt strong",
+          "details": [{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 105}, {"exercise_id": 4, "sets": 3, "reps": 8}]}`, http.StatusOK)
+	req = httptest.NewRequest("PATCH", "/api/workouts/"+workout+"?user_id=1", strings.NewReader(`{"notes": null, "tags": ["heavy"]}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	details := send(req, http.StatusOK)["data"].(map[string]interface{})["details"].([]interface{})
+	first := strconv.FormatInt(int64(details[0].(map[string]interface{})["id"].(float64)), 10)
+	second := strconv.FormatInt(int64(details[1].(map[string]interface{})["id"].(float64)), 10)
+	call("PUT", "/api/workouts/"+workout+"/order?user_id=1", `{"workout_exercise_ids": [`+second+`, `+first+`]}`, http.StatusOK)
+
+	call("GET", "/api/workouts/"+workout+"/exercises?user_id=1", "", http.StatusOK)
+	entry := created(call("POST", "/api/workouts/"+workout+"/exercises?user_id=1", `{"exercise_id": 7, "sets": 2, "reps": 12, "weight": 15}`, http.StatusCreated))
+	call("GET", "/api/workouts/"+workout+"/exercises/"+entry+"?user_id=1", "", http.StatusOK)
+	call("PUT", "/api/workouts/"+workout+"/exercises/"+entry+"?user_id=1", `{"sets": 3, "reps": 10, "weight": 15}`, http.StatusOK)
+	call("DELETE", "/api/workouts/"+workout+"/exercises/"+entry+"?user_id=1", "", http.StatusNoContent)
+
+	copied := created(call("POST", "/api/workouts/"+workout+"/duplicate?user_id=1", `{"date": "2024-03-08"}`, http.StatusCreated))
+	call("POST", "/api/workouts/repeat-last?user_id=1&date=2024-03-11", "", http.StatusCreated)
+	call("DELETE", "/api/workouts/"+copied, "", http.StatusNoContent)
+
+	call("GET", "/api/exercises/1/next-suggestion?user_id=1", "", http.StatusOK)
+	call("GET", "/api/exercises/1/history?user_id=1&limit=5", "", http.StatusOK)
+	call("GET", "/api/records?user_id=1", "", http.StatusOK)
+	call("GET", "/api/records/history?user_id=1&exercise_id=1", "", http.StatusOK)
+
+	// Body and progress
+	measurement := created(call("POST", "/api/measurements?user_id=1", `{"metric": "body_weight", "value": 80, "measured_on": "2024-03-01"}`, http.StatusCreated))
+	call("POST", "/api/measurements?user_id=1", `{"metric": "body_weight", "value": 79.5, "unit": "kg", "measured_on": "2024-03-05"}`, http.StatusCreated)
+	call("GET", "/api/measurements?user_id=1&metric=body_weight", "", http.StatusOK)
+	call("GET", "/api/measurements/trend?user_id=1&metric=body_weight&window=7", "", http.StatusOK)
+	call("GET", "/api/measurements/"+measurement+"?user_id=1", "", http.StatusOK)
+	call("PUT", "/api/measurements/"+measurement+"?user_id=1", `{"metric": "body_weight", "value": 80.5, "measured_on": "2024-03-01", "notes": "Morning"}`, http.StatusOK)
+
+	stats := "user_id=1&from=2024-02-01&to=2024-03-31"
+	call("GET", "/api/stats/volume?"+stats+"&group_by=body_part&depth=0", "", http.StatusOK)
+	call("GET", "/api/stats/volume?"+stats+"&group_by=muscle&bucket=month", "", http.StatusOK)
+	call("GET", "/api/stats/frequency?"+stats, "", http.StatusOK)
+	call("GET", "/api/stats/relative-strength?"+stats+"&exercise_id=1", "", http.StatusOK)
+	call("GET", "/api/stats/load?"+stats, "", http.StatusOK)
+	call("GET", "/api/calendar?user_id=1&month=2024-03", "", http.StatusOK)
+
+	goal := created(call("POST", "/api/goals?user_id=1", `{"goal_type": "lift_weight", "exercise_id": 1, "target_value": 140, "deadline": "2030-01-01"}`, http.StatusCreated))
+	call("POST", "/api/goals?user_id=1", `{"goal_type": "frequency", "target_value": 2}`, http.StatusCreated)
+	call("GET", "/api/goals?user_id=1", "", http.StatusOK)
+	call("GET", "/api/goals/"+goal+"?user_id=1", "", http.StatusOK)
+	call("PUT", "/api/goals/"+goal+"?user_id=1", `{"goal_type": "lift_weight", "exercise_id": 1, "target_value": 150}`, http.StatusOK)
+	call("DELETE", "/api/goals/"+goal+"?user_id=1", "", http.StatusNoContent)
+
+	call("GET", "/api/inventory?user_id=1", "", http.StatusOK)
+	call("PUT", "/api/inventory?user_id=1",
+		`{"unit": "kg", "bars": [{"name": "Olympic", "weight": 20}], "plates": [{"weight": 20, "pairs": 2}, {"weight": 5, "pairs": 2}],
+          "collar_weight": 0, "dumbbell_increment": 2}`, http.StatusOK)
+	call("GET", "/api/tools/plates?user_id=1&target=105", "", http.StatusOK)
+
+	// Admin maintenance
+	if _, err := h.db.Exec("UPDATE users SET is_admin = 1 WHERE id = 1"); err != nil {
+		t.Fatalf("Failed to grant admin: %v", err)
+	}
+	duplicate := created(call("POST", "/api/exercises?user_id=1", `{"name": "Flat Bench", "body_part_id": 1}`, http.StatusCreated))
+	call("POST", "/api/exercises/"+duplicate+"/merge-into/1?user_id=1", "", http.StatusOK)
+
+	call("DELETE", "/api/measurements/"+measurement+"?user_id=1", "", http.StatusNoContent)
+	call("DELETE", "/api/workouts/"+workout, "", http.StatusNoContent)
+	call("DELETE", "/api/gyms/"+gym+"?user_id=1", "", http.StatusNoContent)
+
+	// The validator turns these away before the handlers see them
+	for _, rejected := range []map[string]interface{}{
+		call("GET", "/api/workouts", "", http.StatusBadRequest),
+		call("POST", "/api/workouts", `{"user_id": 1, "name": "Push", "date": "yesterday"}`, http.StatusBadRequest),
+	} {
+		if message, _ := rejected["error"].(string); !strings.HasPrefix(message, "Invalid request: ") {
+			t.Errorf("request was not rejected by the validator: %v", rejected)
+		}
+	}
+
+	for _, operation := range spec.Operations() {
+		if !covered[operation] {
+			t.Errorf("%s is not exercised against the document", operation)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"repup/internal/data"
+)
+
+// parseListQuery reads the limit, sort and cursor parameters shared by list
+// endpoints. limit defaults to data.DefaultPageSize.
+func parseListQuery(r *http.Request) (data.ListQuery, error) {
+	query := r.URL.Query()
+	list := data.ListQuery{
+		Limit:  data.DefaultPageSize,
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+
+	if err := parseIntParam(query.Get("limit"), &list.Limit); err != nil || list.Limit < 1 || list.Limit > data.MaxPageSize {
+		return list, fmt.Errorf("limit must be between 1 and %d", data.MaxPageSize)
+	}
+	return list, nil
+}
+
+// respondWithPage sends one page of a list. next_cursor and has_more sit
+// beside data in the envelope so clients can fetch the following page.
+func (h *Handlers) respondWithPage(w http.ResponseWriter, items interface{}, page *data.Page) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	env := envelope{
+		"data":        items,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.HasMore,
+	}
+	if err := json.NewEncoder(w).Encode(env); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// respondWithListError maps the errors of a paged query. sorts lists the
+// sort fields the endpoint accepts.
+func (h *Handlers) respondWithListError(w http.ResponseWriter, err error, sorts string) {
+	switch {
+	case errors.Is(err, data.ErrInvalidSort):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid sort: must be "+sorts+", optionally prefixed with -")
+	case errors.Is(err, data.ErrInvalidCursor):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+	case errors.Is(err, data.ErrInvalidInput):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid filter")
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+// pageResponse is one page of a list endpoint
+type pageResponse struct {
+	Data       []json.RawMessage `json:"data"`
+	NextCursor *string           `json:"next_cursor"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// fetchPages follows next_cursor from the first page of a list endpoint to
+// the last, returning the items in order
+func fetchPages(t *testing.T, handler http.HandlerFunc, path string) []json.RawMessage {
+	t.Helper()
+
+	var items []json.RawMessage
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 50 {
+			t.Fatalf("%s: too many pages", path)
+		}
+		target := path
+		if cursor != "" {
+			target += "&cursor=" + url.QueryEscape(cursor)
+		}
+
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: handler returned wrong status code: got %v want %v: %s", target, rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var page pageResponse
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		items = append(items, page.Data...)
+
+		if page.HasMore != (page.NextCursor != nil) {
+			t.Fatalf("%s: has_more %v disagrees with next_cursor %v", target, page.HasMore, page.NextCursor)
+		}
+		if !page.HasMore {
+			return items
+		}
+		cursor = *page.NextCursor
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Two workouts share a day, so the ID breaks the tie
+	names := []string{"Echo", "Alpha", "Delta", "Charlie", "Bravo", "Foxtrot"}
+	days := []int{1, 2, 3, 3, 4, 5}
+	for i, name := range names {
+		workout := &data.Workout{UserID: 1, Name: name, Date: time.Date(2024, 1, days[i], 0, 0, 0, 0, time.UTC)}
+		if err := h.models.Workouts.Create(workout); err != nil {
+			t.Fatalf("Failed to create workout: %v", err)
+		}
+	}
+
+	workoutNames := func(items []json.RawMessage) []string {
+		var result []string
+		for _, item := range items {
+			var workout data.Workout
+			if err := json.Unmarshal(item, &workout); err != nil {
+				t.Fatalf("Failed to decode workout: %v", err)
+			}
+			result = append(result, workout.Name)
+		}
+		return result
+	}
+	equal := func(got, want []string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"Most recent first", "limit=2", []string{"Foxtrot", "Bravo", "Charlie", "Delta", "Alpha", "Echo"}},
+		{"By name", "limit=4&sort=name", []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo", "Foxtrot"}},
+		{"By name descending", "limit=5&sort=-name", []string{"Foxtrot", "Echo", "Delta", "Charlie", "Bravo", "Alpha"}},
+		{"Oldest first with a filter", "limit=1&sort=date&from=2024-01-03", []string{"Delta", "Charlie", "Bravo", "Foxtrot"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := workoutNames(fetchPages(t, h.ListWorkouts, "/api/workouts?user_id=1&"+tt.query))
+			if !equal(got, tt.want) {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A cursor only continues the sort it was issued for
+	rr := httptest.NewRecorder()
+	h.ListWorkouts(rr, httptest.NewRequest("GET", "/api/workouts?user_id=1&limit=2&sort=name", nil))
+	var first pageResponse
+	if err := json.NewDecoder(rr.Body).Decode(&first); err != nil || first.NextCursor == nil {
+		t.Fatalf("expected a next cursor: %v", err)
+	}
+	invalid := []string{
+		"limit=0",
+		"limit=500",
+		"sort=notes",
+		"cursor=not-a-cursor",
+		"sort=-date&cursor=" + url.QueryEscape(*first.NextCursor),
+	}
+	for _, query := range invalid {
+		rr := httptest.NewRecorder()
+		h.ListWorkouts(rr, httptest.NewRequest("GET", "/api/workouts?user_id=1&"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	// Paging through the catalog yields the same exercises as one big page
+	all := fetchPages(t, h.ListExercises, "/api/exercises?user_id=1&limit=200")
+	paged := fetchPages(t, h.ListExercises, "/api/exercises?user_id=1&limit=3")
+	if len(all) < 10 || len(paged) != len(all) {
+		t.Fatalf("paging returned %d exercises, one page %d", len(paged), len(all))
+	}
+	for i := range all {
+		if string(all[i]) != string(paged[i]) {
+			t.Errorf("exercise %d differs between paged and unpaged lists", i)
+		}
+	}
+
+	// Body parts filter by their place in the tree
+	bodyPartNames := func(query string) []string {
+		var result []string
+		for _, item := range fetchPages(t, h.ListBodyParts, "/api/body-parts?limit=2&"+query) {
+			var bodyPart bodyPartResponse
+			if err := json.Unmarshal(item, &bodyPart); err != nil {
+				t.Fatalf("Failed to decode body part: %v", err)
+			}
+			result = append(result, bodyPart.Name)
+		}
+		return result
+	}
+	if got := bodyPartNames("top_level=true"); !equal(got, []string{"Core", "Lower Body", "Upper Body"}) {
+		t.Errorf("wrong top-level body parts: %v", got)
+	}
+	if got := bodyPartNames("parent_id=7&sort=-name"); !equal(got, []string{"Shoulders", "Chest", "Back", "Arms"}) {
+		t.Errorf("wrong sub-groups of Upper Body: %v", got)
+	}
+	if got := bodyPartNames("q=body"); !equal(got, []string{"Lower Body", "Upper Body"}) {
+		t.Errorf("wrong search results: %v", got)
+	}
+}
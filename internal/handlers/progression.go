@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ///////////////////////////////////////////////////////////////////////////
+// NextSuggestion handles GET requests for the recommended next session of an exercise
+func (h *Handlers) NextSuggestion(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	strategy := query.Get("strategy")
+	if strategy == "" {
+		strategy = data.StrategyDoubleProgression
+	}
+	if _, ok := data.GetProgressionStrategy(strategy); !ok {
+		h.respondWithError(w, http.StatusBadRequest, "Unknown progression strategy")
+		return
+	}
+
+	// Weights in the suggestion are expressed in the user's unit, so the
+	// default increment follows it
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cfg := data.DefaultProgressionConfig()
+	cfg.Increment = data.DefaultProgressionIncrement(unit)
+	if err := parseIntParam(query.Get("rep_min"), &cfg.RepMin); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid rep_min")
+		return
+	}
+	if err := parseIntParam(query.Get("rep_max"), &cfg.RepMax); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid rep_max")
+		return
+	}
+	if err := parseFloatParam(query.Get("increment"), &cfg.Increment); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid increment")
+		return
+	}
+	if err := parseFloatParam(query.Get("target_rpe"), &cfg.TargetRPE); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid target_rpe")
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid progression parameters")
+		return
+	}
+
+	loading := query.Get("loading")
+	if loading != "" && !data.ValidLoading(loading) {
+		h.respondWithError(w, http.StatusBadRequest, "loading must be barbell, dumbbell or none")
+		return
+	}
+
+	exercise, ok := h.visibleExercise(w, r, exerciseID)
+	if !ok {
+		return
+	}
+
+	history, err := h.models.WorkoutExercises.GetHistoryForUser(userID, exerciseID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	presentPerformances(history, unit)
+
+	suggestion, err := data.SuggestNext(strategy, history, cfg)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		return
+	}
+
+	// Round to a weight the user's equipment can actually load
+	if suggestion.Weight != nil {
+		if loading == "" {
+			loading = data.DefaultLoading(exercise.TrackingType)
+		}
+		inv, err := h.models.Inventories.Get(userID, unit)
+		if err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if loadable := inv.RoundToLoadable(*suggestion.Weight, unit, loading); loadable != *suggestion.Weight {
+			suggestion.Weight = &loadable
+			suggestion.Rationale += fmt.Sprintf(" Rounded to %g, the nearest weight your equipment can load.", loadable)
+		}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, suggestion)
+}
+
+// parseIntParam parses an optional integer query parameter into dst, leaving
+// dst untouched when the parameter is empty
+func parseIntParam(value string, dst *int) error {
+	if value == "" {
+		return nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*dst = v
+	return nil
+}
+
+// parseFloatParam parses an optional float query parameter into dst, leaving
+// dst untouched when the parameter is empty. NaN and infinities are rejected.
+func parseFloatParam(value string, dst *float64) error {
+	if value == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return errors.New("not a finite number")
+	}
+	*dst = v
+	return nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+func TestNextSuggestion(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Two bench press sessions: 3x10 @ 60 then 3x12 @ 60 at RPE 8
+	rpe := 8.0
+	for i, reps := range []int{10, 12} {
+		weight := 60.0
+		workout := &data.Workout{
+			UserID: 1,
+			Name:   "Push",
+			Date:   time.Date(2024, 3, 1+i*3, 0, 0, 0, 0, time.UTC),
+			Details: []data.WorkoutExercise{
+				{ExerciseID: 1, Sets: 3, Reps: reps, Weight: &weight, RPE: &rpe},
+			},
+		}
+		if err := h.models.Workouts.Create(workout); err != nil {
+			t.Fatalf("Failed to create workout: %v", err)
+		}
+	}
+
+	// A deadlift session with a warm-up and one set short of the others:
+	// 1x5 @ 60, then 2x8 and 1x6 @ 100
+	warmUp, working := 60.0, 100.0
+	deadlifts := &data.Workout{
+		UserID: 1,
+		Name:   "Pull",
+		Date:   time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		Details: []data.WorkoutExercise{
+			{ExerciseID: 3, Sets: 1, Reps: 5, Weight: &warmUp},
+			{ExerciseID: 3, Sets: 2, Reps: 8, Weight: &working},
+			{ExerciseID: 3, Sets: 1, Reps: 6, Weight: &working},
+		},
+	}
+	if err := h.models.Workouts.Create(deadlifts); err != nil {
+		t.Fatalf("Failed to create workout: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		exerciseID     string
+		query          string
+		expectedStatus int
+		expectedReps   int
+		expectedWeight float64
+	}{
+		{
+			name:           "Double progression adds weight at top of range",
+			exerciseID:     "1",
+			query:          "user_id=1",
+			expectedStatus: http.StatusOK,
+			expectedReps:   8,
+			expectedWeight: 62.5,
+		},
+		{
+			name:           "Double progression with wider range adds reps",
+			exerciseID:     "1",
+			query:          "user_id=1&rep_max=15",
+			expectedStatus: http.StatusOK,
+			expectedReps:   13,
+			expectedWeight: 60,
+		},
+		{
+			name:           "Double progression waits for every working set",
+			exerciseID:     "3",
+			query:          "user_id=1&rep_min=6&rep_max=8",
+			expectedStatus: http.StatusOK,
+			expectedReps:   7,
+			expectedWeight: 100,
+		},
+		{
+			name:           "Linear progression",
+			exerciseID:     "1",
+			query:          "user_id=1&strategy=linear&increment=5",
+			expectedStatus: http.StatusOK,
+			expectedReps:   12,
+			expectedWeight: 65,
+		},
+		{
+			name:           "RPE autoregulation",
+			exerciseID:     "1",
+			query:          "user_id=1&strategy=rpe&rep_min=5&rep_max=5&target_rpe=8",
+			expectedStatus: http.StatusOK,
+			expectedReps:   5,
+			expectedWeight: 72.5,
+		},
+		{
+			name:           "Unknown strategy",
+			exerciseID:     "1",
+			query:          "user_id=1&strategy=magic",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-finite target RPE",
+			exerciseID:     "1",
+			query:          "user_id=1&strategy=rpe&target_rpe=NaN",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Infinite increment",
+			exerciseID:     "1",
+			query:          "user_id=1&strategy=linear&increment=Inf",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Increment too large",
+			exerciseID:     "1",
+			query:          "user_id=1&strategy=linear&increment=1e308",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing user",
+			exerciseID:     "1",
+			query:          "",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent exercise",
+			exerciseID:     "999",
+			query:          "user_id=1",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/exercises/"+tt.exerciseID+"/next-suggestion?"+tt.query, nil)
+			req = withURLParams(req, "id", tt.exerciseID)
+			rr := httptest.NewRecorder()
+
+			h.NextSuggestion(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.expectedStatus, rr.Body.String())
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data data.Suggestion `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+
+			if response.Data.Reps != tt.expectedReps {
+				t.Errorf("handler returned wrong reps: got %v want %v", response.Data.Reps, tt.expectedReps)
+			}
+			if response.Data.Weight == nil || *response.Data.Weight != tt.expectedWeight {
+				t.Errorf("handler returned wrong weight: got %v want %v", response.Data.Weight, tt.expectedWeight)
+			}
+			if response.Data.Rationale == "" {
+				t.Error("handler returned no rationale")
+			}
+		})
+	}
+}
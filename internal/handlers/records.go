@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"repup/internal/data"
+)
+
+// ////////////////////////////////////////////////////////////////
+// ListRecords handles GET requests for a user's standing personal records
+func (h *Handlers) ListRecords(w http.ResponseWriter, r *http.Request) {
+	h.listRecords(w, r, h.models.PersonalRecords.GetCurrent)
+}
+
+// ////////////////////////////////////////////////////////////////////////
+// ListRecordHistory handles GET requests for every record a user has set
+func (h *Handlers) ListRecordHistory(w http.ResponseWriter, r *http.Request) {
+	h.listRecords(w, r, h.models.PersonalRecords.GetHistory)
+}
+
+func (h *Handlers) listRecords(w http.ResponseWriter, r *http.Request,
+	fetch func(int64, data.RecordFilter) ([]*data.PersonalRecord, error)) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var filter data.RecordFilter
+	if exerciseIDStr := r.URL.Query().Get("exercise_id"); exerciseIDStr != "" {
+		filter.ExerciseID, err = strconv.ParseInt(exerciseIDStr, 10, 64)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid exercise_id format")
+			return
+		}
+	}
+	filter.RecordType = r.URL.Query().Get("type")
+
+	records, err := fetch(userID, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidInput) {
+			h.respondWithError(w, http.StatusBadRequest, "Invalid record type")
+			return
+		}
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentRecords(records, unit)
+	h.respondWithJSON(w, http.StatusOK, records)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+// createTestWorkout inserts a single-exercise workout for user 1
+func createTestWorkout(t *testing.T, h *Handlers, day int, exerciseID int64, sets, reps int, weight float64) *data.Workout {
+	t.Helper()
+
+	workout := &data.Workout{
+		UserID: 1,
+		Name:   "Session",
+		Date:   time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		Details: []data.WorkoutExercise{
+			{ExerciseID: exerciseID, Sets: sets, Reps: reps, Weight: &weight},
+		},
+	}
+	if err := h.models.Workouts.Create(workout); err != nil {
+		t.Fatalf("Failed to create workout: %v", err)
+	}
+	return workout
+}
+
+// currentRecords returns the standing records for user 1 keyed by record type
+func currentRecords(t *testing.T, h *Handlers) map[string]data.PersonalRecord {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/records?user_id=1&exercise_id=1", nil)
+	rr := httptest.NewRecorder()
+	h.ListRecords(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response struct {
+		Data []data.PersonalRecord `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	records := map[string]data.PersonalRecord{}
+	for _, record := range response.Data {
+		key := record.RecordType
+		if record.RecordType == data.RecordRepsAtWeight {
+			key += "@" + strconv.FormatFloat(*record.Weight, 'f', -1, 64)
+		}
+		records[key] = record
+	}
+	return records
+}
+
+func TestPersonalRecords(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	first := createTestWorkout(t, h, 1, 1, 3, 5, 100)
+	second := createTestWorkout(t, h, 3, 1, 3, 8, 100)
+
+	if len(second.Details[0].Records) == 0 {
+		t.Fatal("expected the second workout to set records")
+	}
+
+	records := currentRecords(t, h)
+	if got := records[data.RecordMaxWeight].WorkoutID; got != first.ID {
+		t.Errorf("max weight record should stay with the first workout: got workout %v", got)
+	}
+	if got := records[data.RecordRepsAtWeight+"@100"].Value; got != 8 {
+		t.Errorf("wrong reps at 100: got %v want 8", got)
+	}
+	if got := records[data.RecordSessionVolume].Value; got != 2400 {
+		t.Errorf("wrong session volume record: got %v want 2400", got)
+	}
+	if got := records[data.RecordEpleyOneRM].Value; got < 126.6 || got > 126.7 {
+		t.Errorf("wrong Epley 1RM record: got %v", got)
+	}
+	if got := records[data.RecordBrzyckiOneRM].PreviousValue; got == nil {
+		t.Error("expected the Brzycki record to note the value it beat")
+	}
+
+	// Editing the second workout down re-evaluates its records
+	lighter := 60.0
+	second.Details = []data.WorkoutExercise{{ExerciseID: 1, Sets: 3, Reps: 8, Weight: &lighter}}
+	if err := h.models.Workouts.Update(second); err != nil {
+		t.Fatalf("Failed to update workout: %v", err)
+	}
+	records = currentRecords(t, h)
+	if got := records[data.RecordSessionVolume].WorkoutID; got != first.ID {
+		t.Errorf("volume record should revert to the first workout: got workout %v", got)
+	}
+	if _, ok := records[data.RecordRepsAtWeight+"@60"]; !ok {
+		t.Error("expected a reps record at the new weight")
+	}
+
+	// Deleting the first workout hands every record to the remaining one
+	if err := h.models.Workouts.Delete(first.ID); err != nil {
+		t.Fatalf("Failed to delete workout: %v", err)
+	}
+	records = currentRecords(t, h)
+	for recordType, record := range records {
+		if record.WorkoutID != second.ID {
+			t.Errorf("%s record points at deleted workout %v", recordType, record.WorkoutID)
+		}
+	}
+
+	// Workout responses mark the sets that set records
+	req := httptest.NewRequest("GET", "/api/workouts/"+strconv.FormatInt(second.ID, 10), nil)
+	req = withURLParams(req, "id", strconv.FormatInt(second.ID, 10))
+	rr := httptest.NewRecorder()
+	h.GetWorkout(rr, req)
+
+	var response struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(response.Data.Details) != 1 || len(response.Data.Details[0].Records) == 0 {
+		t.Errorf("expected workout details to be marked with records: %+v", response.Data.Details)
+	}
+}
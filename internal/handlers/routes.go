@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+
+	"repup/internal/auth"
+	"repup/internal/openapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RegisterRoutes mounts the API under /api. middlewares run for every API
+// request ahead of authentication, such as the OpenAPI validator. Every route
+// here must be described in internal/openapi/openapi.json; a test keeps the
+// two in step.
+func (h *Handlers) RegisterRoutes(r chi.Router, middlewares ...func(http.Handler) http.Handler) {
+	r.Route("/api", func(r chi.Router) {
+		r.Use(middlewares...)
+
+		// Public routes
+		//r.Post("/auth/google", handlers.GoogleAuth)
+		//r.Get("/auth/google/callback", handlers.GoogleCallback)
+		r.Get("/openapi.json", h.OpenAPIDocument)
+
+		// Protected routes
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireAuth)
+
+			// Body Parts
+			r.Route("/body-parts", func(r chi.Router) {
+				r.Get("/", h.ListBodyParts)
+				r.Post("/", h.CreateBodyPart)
+				r.Get("/tree", h.GetBodyPartTree)
+				r.Get("/{id}", h.GetBodyPart)
+				r.Put("/{id}", h.UpdateBodyPart)
+				r.Delete("/{id}", h.DeleteBodyPart)
+			})
+
+			r.Get("/muscles", h.ListMuscles)
+
+			r.Route("/exercises", func(r chi.Router) {
+				r.Get("/", h.ListExercises)
+				r.Post("/", h.CreateExercise)
+				r.Get("/{id}", h.GetExercise)
+				r.Put("/{id}", h.UpdateExercise)
+				r.Delete("/{id}", h.DeleteExercise)
+				r.Get("/{id}/next-suggestion", h.NextSuggestion)
+				r.Get("/{id}/history", h.ExerciseHistory)
+				r.Get("/{id}/substitutes", h.ExerciseSubstitutes)
+				r.Post("/{id}/merge-into/{targetId}", h.MergeExercise)
+
+				r.Get("/{id}/media", h.ListExerciseMedia)
+				r.Post("/{id}/media", h.UploadExerciseMedia)
+				r.Get("/{id}/media/{mediaId}", h.GetExerciseMedia)
+				r.Delete("/{id}/media/{mediaId}", h.DeleteExerciseMedia)
+			})
+
+			r.Get("/equipment", h.ListEquipment)
+
+			r.Route("/gyms", func(r chi.Router) {
+				r.Get("/", h.ListGyms)
+				r.Post("/", h.CreateGym)
+				r.Get("/{id}", h.GetGym)
+				r.Put("/{id}", h.UpdateGym)
+				r.Delete("/{id}", h.DeleteGym)
+			})
+
+			r.Route("/workouts", func(r chi.Router) {
+				r.Get("/", h.ListWorkouts)
+				r.Post("/", h.CreateWorkout)
+				r.Post("/repeat-last", h.RepeatLastWorkout)
+				r.Get("/{id}", h.GetWorkout)
+				r.Put("/{id}", h.UpdateWorkout)
+				r.Delete("/{id}", h.DeleteWorkout)
+				r.Patch("/{id}", h.PatchWorkout)
+				r.Put("/{id}/order", h.ReorderWorkoutExercises)
+				r.Post("/{id}/duplicate", h.DuplicateWorkout)
+
+				r.Get("/{id}/exercises", h.ListWorkoutExercises)
+				r.Post("/{id}/exercises", h.CreateWorkoutExercise)
+				r.Get("/{id}/exercises/{weId}", h.GetWorkoutExercise)
+				r.Put("/{id}/exercises/{weId}", h.UpdateWorkoutExercise)
+				r.Delete("/{id}/exercises/{weId}", h.DeleteWorkoutExercise)
+			})
+
+			r.Route("/records", func(r chi.Router) {
+				r.Get("/", h.ListRecords)
+				r.Get("/history", h.ListRecordHistory)
+			})
+
+			r.Route("/stats", func(r chi.Router) {
+				r.Get("/volume", h.VolumeStats)
+				r.Get("/frequency", h.FrequencyStats)
+				r.Get("/relative-strength", h.RelativeStrengthStats)
+				r.Get("/load", h.LoadStats)
+			})
+
+			r.Route("/measurements", func(r chi.Router) {
+				r.Get("/", h.ListMeasurements)
+				r.Post("/", h.CreateMeasurement)
+				r.Get("/trend", h.MeasurementTrend)
+				r.Get("/{id}", h.GetMeasurement)
+				r.Put("/{id}", h.UpdateMeasurement)
+				r.Delete("/{id}", h.DeleteMeasurement)
+			})
+
+			r.Route("/goals", func(r chi.Router) {
+				r.Get("/", h.ListGoals)
+				r.Post("/", h.CreateGoal)
+				r.Get("/{id}", h.GetGoal)
+				r.Put("/{id}", h.UpdateGoal)
+				r.Delete("/{id}", h.DeleteGoal)
+			})
+
+			r.Get("/calendar", h.Calendar)
+
+			r.Get("/inventory", h.GetInventory)
+			r.Put("/inventory", h.UpdateInventory)
+
+			r.Get("/tools/plates", h.PlateCalculator)
+
+			r.Get("/settings", h.GetSettings)
+			r.Put("/settings", h.UpdateSettings)
+		})
+	})
+}
+
+// OpenAPIDocument serves the OpenAPI document describing the API. It is sent
+// as is rather than in a data envelope so tools can load it directly.
+func (h *Handlers) OpenAPIDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Document())
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"repup/internal/data"
+)
+
+// settingsRequest represents the expected request body for updating user settings
+type settingsRequest struct {
+	PreferredUnit string `json:"preferred_unit"`
+	// 1-14 workouts per week. Omitting it keeps the current target and null
+	// clears it.
+	WeeklyTarget optionalInt `json:"weekly_target"`
+	Timezone     string      `json:"timezone"` // IANA timezone name; omitting it keeps the current one
+}
+
+// optionalInt is a nullable integer member that records whether it was sent
+// at all, telling an explicit null apart from an absent member
+type optionalInt struct {
+	Set   bool
+	Value *int
+}
+
+func (o *optionalInt) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	return json.Unmarshal(b, &o.Value)
+}
+
+// ///////////////////////////////////////////////////////////
+// GetSettings handles GET requests for the user's preferences
+func (h *Handlers) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	settings, err := h.models.Users.GetSettings(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, settings)
+}
+
+// /////////////////////////////////////////////////////////////
+// UpdateSettings handles PUT requests to change user preferences
+func (h *Handlers) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req settingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !data.ValidUnit(req.PreferredUnit) {
+		h.respondWithError(w, http.StatusBadRequest, "preferred_unit must be kg or lb")
+		return
+	}
+	if target := req.WeeklyTarget.Value; target != nil && (*target < 1 || *target > 14) {
+		h.respondWithError(w, http.StatusBadRequest, "weekly_target must be between 1 and 14")
+		return
+	}
+	if req.Timezone != "" && !data.ValidTimezone(req.Timezone) {
+		h.respondWithError(w, http.StatusBadRequest, "timezone must be an IANA timezone name")
+		return
+	}
+
+	settings, err := h.models.Users.GetSettings(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	settings.PreferredUnit = req.PreferredUnit
+	if req.WeeklyTarget.Set {
+		settings.WeeklyTarget = req.WeeklyTarget.Value
+	}
+	if req.Timezone != "" {
+		settings.Timezone = req.Timezone
+	}
+
+	err = h.models.Users.UpdateSettings(settings)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "User not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, settings)
+}
+
+// requestLocation returns the timezone a request is evaluated in: the tz query
+// parameter when given, otherwise the user's timezone
+func (h *Handlers) requestLocation(r *http.Request, userID int64) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return h.models.Users.Location(userID), nil
+	}
+	if !data.ValidTimezone(tz) {
+		return nil, errors.New("Invalid tz: must be an IANA timezone name")
+	}
+	return time.LoadLocation(tz)
+}
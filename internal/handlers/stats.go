@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"repup/internal/data"
+)
+
+// defaultStatsRangeDays is the length of the range used when from is omitted
+const defaultStatsRangeDays = 84
+
+// maxStatsRangeDays bounds the range, and so the number of buckets, a single
+// request may cover: ten years of daily buckets
+const maxStatsRangeDays = 3653
+
+// statsResponse wraps a statistics result with the query that produced it
+type statsResponse struct {
+	From     string      `json:"from"`
+	To       string      `json:"to"`
+	Bucket   string      `json:"bucket"`
+	Timezone string      `json:"timezone"`
+	GroupBy  string      `json:"group_by,omitempty"`
+	Results  interface{} `json:"results"`
+}
+
+// /////////////////////////////////////////////////////////////////////////
+// VolumeStats handles GET requests for sets, reps and tonnage over time
+func (h *Handlers) VolumeStats(w http.ResponseWriter, r *http.Request) {
+	q, err := h.parseStatsQuery(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = data.GroupByBodyPart
+	}
+	if !data.ValidGroupBy(groupBy) {
+		h.respondWithError(w, http.StatusBadRequest, "group_by must be body_part, exercise or muscle")
+		return
+	}
+
+	// Body part volume can roll up to a level of the body part tree
+	depth := -1
+	if value := r.URL.Query().Get("depth"); value != "" {
+		if groupBy != data.GroupByBodyPart {
+			h.respondWithError(w, http.StatusBadRequest, "depth only applies when grouping by body_part")
+			return
+		}
+		if err := parseIntParam(value, &depth); err != nil || depth < 0 {
+			h.respondWithError(w, http.StatusBadRequest, "depth must be a whole number of 0 or more")
+			return
+		}
+	}
+
+	series, err := h.models.Stats.Volume(q, groupBy, depth)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, q.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentVolume(series, unit)
+	h.respondWithJSON(w, http.StatusOK, newStatsResponse(q, groupBy, series))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// FrequencyStats handles GET requests for training frequency and session length
+func (h *Handlers) FrequencyStats(w http.ResponseWriter, r *http.Request) {
+	q, err := h.parseStatsQuery(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := h.models.Stats.Frequency(q)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, newStatsResponse(q, "", stats))
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// RelativeStrengthStats handles GET requests for a lift expressed as multiples
+// of body weight, using the body weight logged closest to each session
+func (h *Handlers) RelativeStrengthStats(w http.ResponseWriter, r *http.Request) {
+	q, err := h.parseStatsQuery(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	exerciseIDStr := r.URL.Query().Get("exercise_id")
+	if exerciseIDStr == "" {
+		h.respondWithError(w, http.StatusBadRequest, "exercise_id query parameter is required")
+		return
+	}
+	exerciseID, err := strconv.ParseInt(exerciseIDStr, 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid exercise_id format")
+		return
+	}
+
+	sessions, err := h.models.WorkoutExercises.GetSessions(q.UserID, exerciseID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	var inRange []*data.ExerciseSession
+	for _, session := range sessions {
+		if !session.Date.Before(q.From) && !session.Date.After(q.To) {
+			inRange = append(inRange, session)
+		}
+	}
+
+	// The nearest body weight may fall outside the range, so load them all
+	bodyWeights, err := h.models.Measurements.GetAll(q.UserID, data.MeasurementFilter{Metric: data.MetricBodyWeight})
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	series := data.RelativeStrengthSeries(inRange, bodyWeights)
+	if series == nil {
+		series = []data.RelativeStrength{}
+	}
+	unit, err := h.displayUnit(r, q.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentRelativeStrength(series, unit)
+
+	h.respondWithJSON(w, http.StatusOK, newStatsResponse(q, "", series))
+}
+
+// /////////////////////////////////////////////////////////////////////////////
+// LoadStats handles GET requests for daily training load, the acute:chronic
+// workload ratio, monotony and strain, with a deload recommendation
+func (h *Handlers) LoadStats(w http.ResponseWriter, r *http.Request) {
+	q, err := h.parseStatsQuery(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.Bucket = data.BucketDay
+
+	query := r.URL.Query()
+	thresholds := data.DefaultLoadThresholds()
+	if err := parseFloatParam(query.Get("acwr_max"), &thresholds.ACWRMax); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid acwr_max")
+		return
+	}
+	if err := parseFloatParam(query.Get("acwr_min"), &thresholds.ACWRMin); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid acwr_min")
+		return
+	}
+	if err := parseFloatParam(query.Get("monotony_max"), &thresholds.MonotonyMax); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid monotony_max")
+		return
+	}
+	if err := thresholds.Validate(); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid thresholds: acwr_max must exceed 1, acwr_min must be between 0 and 1 and monotony_max must be positive")
+		return
+	}
+
+	report, err := h.models.Stats.Load(q, thresholds)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, q.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentLoad(report, unit)
+	h.respondWithJSON(w, http.StatusOK, newStatsResponse(q, "", report))
+}
+
+func newStatsResponse(q data.StatsQuery, groupBy string, results interface{}) statsResponse {
+	return statsResponse{
+		From:     q.From.Format(data.DateFormat),
+		To:       q.To.Format(data.DateFormat),
+		Bucket:   q.Bucket,
+		Timezone: q.Location.String(),
+		GroupBy:  groupBy,
+		Results:  results,
+	}
+}
+
+// parseStatsQuery reads the user, date range, bucket and timezone parameters
+// shared by the statistics endpoints
+func (h *Handlers) parseStatsQuery(r *http.Request) (data.StatsQuery, error) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		return data.StatsQuery{}, err
+	}
+
+	query := r.URL.Query()
+	q := data.StatsQuery{
+		UserID: userID,
+		Bucket: query.Get("bucket"),
+	}
+	if q.Bucket == "" {
+		q.Bucket = data.BucketWeek
+	}
+
+	q.Location, err = h.requestLocation(r, userID)
+	if err != nil {
+		return data.StatsQuery{}, err
+	}
+
+	q.To = data.Today(q.Location)
+	if to := query.Get("to"); to != "" {
+		q.To, err = time.Parse(data.DateFormat, to)
+		if err != nil {
+			return data.StatsQuery{}, errors.New("Invalid to date format")
+		}
+	}
+
+	q.From = q.To.AddDate(0, 0, 1-defaultStatsRangeDays)
+	if from := query.Get("from"); from != "" {
+		q.From, err = time.Parse(data.DateFormat, from)
+		if err != nil {
+			return data.StatsQuery{}, errors.New("Invalid from date format")
+		}
+	}
+
+	if err := q.Validate(); err != nil {
+		return data.StatsQuery{}, errors.New("Invalid range or bucket")
+	}
+	if q.To.Sub(q.From) >= maxStatsRangeDays*24*time.Hour {
+		return data.StatsQuery{}, errors.New("Date range must be at most 10 years")
+	}
+
+	return q, nil
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"repup/internal/data"
+)
+
+func TestVolumeStats(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Monday 2024-01-01 and Wednesday 2024-01-10, one week apart
+	bench, squat := 100.0, 140.0
+	for _, workout := range []*data.Workout{
+		{UserID: 1, Name: "A", Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Details: []data.WorkoutExercise{
+			{ExerciseID: 1, Sets: 3, Reps: 5, Weight: &bench},
+			{ExerciseID: 5, Sets: 5, Reps: 5, Weight: &squat},
+		}},
+		{UserID: 1, Name: "B", Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Details: []data.WorkoutExercise{
+			{ExerciseID: 1, Sets: 4, Reps: 5, Weight: &bench},
+			{ExerciseID: 2, Sets: 2, Reps: 20},
+		}},
+	} {
+		if err := h.models.Workouts.Create(workout); err != nil {
+			t.Fatalf("Failed to create workout: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedStatus  int
+		expectedSeries  map[string]float64 // tonnage per series name
+		expectedBuckets int
+	}{
+		{
+			name:            "Weekly by body part",
+			query:           "user_id=1&from=2024-01-01&to=2024-01-14&tz=America/New_York",
+			expectedStatus:  http.StatusOK,
+			expectedSeries:  map[string]float64{"Chest": 3500, "Legs": 3500},
+			expectedBuckets: 2,
+		},
+		{
+			name:            "Monthly by exercise",
+			query:           "user_id=1&from=2024-01-01&to=2024-01-31&bucket=month&group_by=exercise",
+			expectedStatus:  http.StatusOK,
+			expectedSeries:  map[string]float64{"Bench Press": 3500, "Squats": 3500, "Push-ups": 0},
+			expectedBuckets: 1,
+		},
+		{
+			name:            "Range excludes second workout",
+			query:           "user_id=1&from=2024-01-01&to=2024-01-07&bucket=day",
+			expectedStatus:  http.StatusOK,
+			expectedSeries:  map[string]float64{"Chest": 1500, "Legs": 3500},
+			expectedBuckets: 7,
+		},
+		{
+			name:            "Rolled up to regions",
+			query:           "user_id=1&from=2024-01-01&to=2024-01-14&depth=0",
+			expectedStatus:  http.StatusOK,
+			expectedSeries:  map[string]float64{"Upper Body": 3500, "Lower Body": 3500},
+			expectedBuckets: 2,
+		},
+		{
+			name:           "Negative depth",
+			query:          "user_id=1&depth=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Depth without body part grouping",
+			query:          "user_id=1&group_by=exercise&depth=1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid timezone",
+			query:          "user_id=1&tz=Mars/Olympus",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid bucket",
+			query:          "user_id=1&bucket=year",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Range too long",
+			query:          "user_id=1&from=1800-01-01&to=2024-01-01&bucket=day",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/stats/volume?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			h.VolumeStats(rr, req)
+
+			if status := rr.Code; status != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data struct {
+					Results []data.VolumeSeries `json:"results"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+
+			if len(response.Data.Results) != len(tt.expectedSeries) {
+				t.Fatalf("handler returned wrong number of series: got %v want %v",
+					len(response.Data.Results), len(tt.expectedSeries))
+			}
+			for _, series := range response.Data.Results {
+				if series.Totals.Tonnage != tt.expectedSeries[series.Name] {
+					t.Errorf("wrong tonnage for %s: got %v want %v",
+						series.Name, series.Totals.Tonnage, tt.expectedSeries[series.Name])
+				}
+				if len(series.Buckets) != tt.expectedBuckets {
+					t.Errorf("wrong number of buckets for %s: got %v want %v",
+						series.Name, len(series.Buckets), tt.expectedBuckets)
+				}
+			}
+		})
+	}
+}
+
+func TestFrequencyStats(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	for i, minutes := range []int{45, 75} {
+		duration := minutes
+		workout := &data.Workout{
+			UserID:          1,
+			Name:            "Session",
+			Date:            time.Date(2024, 2, 5+i, 0, 0, 0, 0, time.UTC),
+			DurationMinutes: &duration,
+		}
+		if err := h.models.Workouts.Create(workout); err != nil {
+			t.Fatalf("Failed to create workout: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/stats/frequency?user_id=1&from=2024-02-05&to=2024-02-18", nil)
+	rr := httptest.NewRecorder()
+
+	h.FrequencyStats(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response struct {
+		Data struct {
+			Results data.FrequencyStats `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+
+	stats := response.Data.Results
+	if stats.Workouts != 2 || stats.WorkoutsPerWeek != 1 {
+		t.Errorf("wrong frequency: got %v workouts, %v per week", stats.Workouts, stats.WorkoutsPerWeek)
+	}
+	if stats.AverageSessionLength == nil || *stats.AverageSessionLength != 60 {
+		t.Errorf("wrong average session length: got %v want 60", stats.AverageSessionLength)
+	}
+	if len(stats.Buckets) != 2 || stats.Buckets[0].Workouts != 2 || stats.Buckets[1].Workouts != 0 {
+		t.Errorf("wrong buckets: %+v", stats.Buckets)
+	}
+}
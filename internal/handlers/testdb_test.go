@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"repup/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	_ "github.com/mattn/go-sqlite3" // Import SQLite driver for testing
+)
+
+// testFilesURL is where the test blob store's signed links point
+const testFilesURL = "http://example.com/files"
+
+// setupSchemaHandler creates a Handlers instance backed by an in-memory SQLite
+// database with every migration in migrations/ applied and a local blob store
+// in a temporary directory
+func setupSchemaHandler(t *testing.T) *Handlers {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	// Each connection to :memory: is a separate database, so keep just one
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.sql"))
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read migration %s: %v", file, err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("Failed to apply migration %s: %v", file, err)
+		}
+	}
+
+	if _, err := db.Exec(`
+        INSERT INTO users (email, name, oauth_provider, oauth_id)
+        VALUES ('lifter@example.com', 'Lifter', 'google', 'lifter')`); err != nil {
+		t.Fatalf("Failed to insert test user: %v", err)
+	}
+
+	store, err := storage.NewLocalStore(t.TempDir(), testFilesURL, []byte("test-secret"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+
+	return NewHandlers(db, store)
+}
+
+// withURLParams attaches chi URL parameters to a request, given as name/value pairs
+func withURLParams(req *http.Request, params ...string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for i := 0; i+1 < len(params); i += 2 {
+		rctx.URLParams.Add(params[i], params[i+1])
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestWorkoutTimezones(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1",
+		bytes.NewBufferString(`{"preferred_unit": "kg", "timezone": "America/New_York"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1",
+		bytes.NewBufferString(`{"preferred_unit": "kg", "timezone": "Mars/Olympus_Mons"}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid timezone: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// Clients that do not know about timezones keep the stored one
+	rr = httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1",
+		bytes.NewBufferString(`{"preferred_unit": "kg"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if settings, err := h.models.Users.GetSettings(1); err != nil || settings.Timezone != "America/New_York" {
+		t.Fatalf("timezone should be kept when omitted: %+v %v", settings, err)
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedDate     string
+		expectedTimezone string
+		expectedMinutes  int
+	}{
+		{
+			// 22:30 EST on the 9th is already the 10th in UTC
+			name:             "Late session takes the local day",
+			body:             `{"started_at": "2024-03-10T03:30:00Z"}`,
+			expectedStatus:   http.StatusCreated,
+			expectedDate:     "2024-03-09",
+			expectedTimezone: "America/New_York",
+		},
+		{
+			// 01:30 EDT to 01:10 EST is forty minutes, not minus twenty
+			name:             "Session across the end of daylight saving",
+			body:             `{"started_at": "2024-11-03T05:30:00Z", "ended_at": "2024-11-03T06:10:00Z"}`,
+			expectedStatus:   http.StatusCreated,
+			expectedDate:     "2024-11-03",
+			expectedTimezone: "America/New_York",
+			expectedMinutes:  40,
+		},
+		{
+			name:             "Explicit timezone",
+			body:             `{"date": "2024-03-10", "timezone": "Asia/Tokyo", "started_at": "2024-03-09T16:00:00Z"}`,
+			expectedStatus:   http.StatusCreated,
+			expectedDate:     "2024-03-10",
+			expectedTimezone: "Asia/Tokyo",
+		},
+		{
+			name:             "Date only",
+			body:             `{"date": "2024-03-11"}`,
+			expectedStatus:   http.StatusCreated,
+			expectedDate:     "2024-03-11",
+			expectedTimezone: "America/New_York",
+		},
+		{
+			name:           "Date disagrees with the start",
+			body:           `{"date": "2024-03-10", "started_at": "2024-03-10T03:30:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "End before start",
+			body:           `{"started_at": "2024-03-10T03:30:00Z", "ended_at": "2024-03-10T03:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "End without start",
+			body:           `{"date": "2024-03-10", "ended_at": "2024-03-10T03:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown timezone",
+			body:           `{"date": "2024-03-10", "timezone": "Nowhere/Special"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Neither date nor start",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.body), &fields); err != nil {
+				t.Fatalf("invalid test body: %v", err)
+			}
+			fields["user_id"] = 1
+			fields["name"] = "Session"
+			body, _ := json.Marshal(fields)
+
+			rr := httptest.NewRecorder()
+			h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBuffer(body)))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				Data data.Workout `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			workout, err := h.models.Workouts.GetByID(response.Data.ID)
+			if err != nil {
+				t.Fatalf("Failed to load workout: %v", err)
+			}
+			if got := workout.Date.Format(data.DateFormat); got != tt.expectedDate {
+				t.Errorf("wrong date: got %v want %v", got, tt.expectedDate)
+			}
+			if workout.Timezone != tt.expectedTimezone {
+				t.Errorf("wrong timezone: got %v want %v", workout.Timezone, tt.expectedTimezone)
+			}
+			if tt.expectedMinutes != 0 && (workout.DurationMinutes == nil || *workout.DurationMinutes != tt.expectedMinutes) {
+				t.Errorf("wrong duration: got %v want %v", workout.DurationMinutes, tt.expectedMinutes)
+			}
+		})
+	}
+
+	// Statistics default to the user's timezone and count the late session on its local day
+	rr = httptest.NewRecorder()
+	h.FrequencyStats(rr, httptest.NewRequest("GET", "/api/stats/frequency?user_id=1&from=2024-03-09&to=2024-03-09&bucket=day", nil))
+	var stats struct {
+		Data struct {
+			Timezone string `json:"timezone"`
+			Results  struct {
+				Buckets []data.FrequencyBucket `json:"buckets"`
+			} `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if stats.Data.Timezone != "America/New_York" {
+		t.Errorf("wrong stats timezone: got %v", stats.Data.Timezone)
+	}
+	if len(stats.Data.Results.Buckets) != 1 || stats.Data.Results.Buckets[0].Workouts != 1 {
+		t.Errorf("expected one workout on 2024-03-09: %+v", stats.Data.Results.Buckets)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestWorkoutTrackingTypes(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Seeded exercises: 1 Bench Press (weight_reps), 2 Push-ups (bodyweight_reps),
+	// 9 Plank (duration), 11 Running (distance_duration)
+	tests := []struct {
+		name           string
+		detail         string
+		expectedStatus int
+	}{
+		{
+			name:           "Weighted lift",
+			detail:         `{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 100}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Weighted lift without reps",
+			detail:         `{"exercise_id": 1, "sets": 3, "reps": 0, "weight": 100}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Weighted lift with a duration",
+			detail:         `{"exercise_id": 1, "sets": 3, "reps": 5, "duration_seconds": 30}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Bodyweight reps",
+			detail:         `{"exercise_id": 2, "sets": 3, "reps": 20}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Timed hold",
+			detail:         `{"exercise_id": 9, "sets": 3, "duration_seconds": 60}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Timed hold without duration",
+			detail:         `{"exercise_id": 9, "sets": 3, "reps": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Run",
+			detail:         `{"exercise_id": 11, "sets": 1, "duration_seconds": 1500, "distance_meters": 5000}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Run without distance",
+			detail:         `{"exercise_id": 11, "sets": 1, "duration_seconds": 1500}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown exercise",
+			detail:         `{"exercise_id": 999, "sets": 1, "reps": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"user_id": 1, "name": "Mixed", "date": "2024-06-01", "details": [` + tt.detail + `]}`
+			rr := httptest.NewRecorder()
+
+			h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+					rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+
+	// Runs report their pace
+	body := `{"user_id": 1, "name": "Run", "date": "2024-06-02", "details": [
+		{"exercise_id": 11, "sets": 1, "duration_seconds": 1500, "distance_meters": 5000}
+	]}`
+	rr := httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+
+	var response struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if pace := response.Data.Details[0].PaceSecondsPerKm; pace == nil || *pace != 300 {
+		t.Errorf("wrong pace: got %v want 300", pace)
+	}
+}
+
+func TestUpdateExerciseKeepsTrackingType(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	rr := httptest.NewRecorder()
+	h.CreateExercise(rr, httptest.NewRequest("POST", "/api/exercises?user_id=1",
+		bytes.NewBufferString(`{"name": "Wall Sit", "body_part_id": 3, "tracking_type": "duration"}`)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data exerciseResponse `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	id := strconv.FormatInt(created.Data.ID, 10)
+
+	update := func(body string) exerciseResponse {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.UpdateExercise(rr, withURLParams(httptest.NewRequest("PUT", "/api/exercises/"+id+"?user_id=1", bytes.NewBufferString(body)), "id", id))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var updated struct {
+			Data exerciseResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&updated); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		return updated.Data
+	}
+
+	// A client that predates tracking types leaves it out
+	if updated := update(`{"name": "Wall Sit", "body_part_id": 3, "description": "Back flat"}`); updated.TrackingType != data.TrackingDuration {
+		t.Errorf("tracking type changed to %q on update without one", updated.TrackingType)
+	}
+	if updated := update(`{"name": "Weighted Wall Sit", "body_part_id": 3, "tracking_type": "weight_duration"}`); updated.TrackingType != data.TrackingWeightDuration {
+		t.Errorf("tracking type not updated: %q", updated.TrackingType)
+	}
+}
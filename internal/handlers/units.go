@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"repup/internal/data"
+)
+
+// preferredUnit returns the weight unit a user prefers, falling back to
+// kilograms when it cannot be determined
+func (h *Handlers) preferredUnit(userID int64) string {
+	settings, err := h.models.Users.GetSettings(userID)
+	if err != nil {
+		return data.UnitKilograms
+	}
+	return settings.PreferredUnit
+}
+
+// displayUnit returns the unit weights in a response should use: the unit
+// query parameter when given, otherwise the user's preferred unit
+func (h *Handlers) displayUnit(r *http.Request, userID int64) (string, error) {
+	unit := r.URL.Query().Get("unit")
+	if unit == "" {
+		return h.preferredUnit(userID), nil
+	}
+	if !data.ValidUnit(unit) {
+		return "", errors.New("Invalid unit: must be kg or lb")
+	}
+	return unit, nil
+}
+
+// entryUnit returns the unit weights in a request were entered in: the unit
+// given in the request, otherwise the user's preferred unit
+func (h *Handlers) entryUnit(requested string, userID int64) (string, error) {
+	if requested == "" {
+		return h.preferredUnit(userID), nil
+	}
+	if !data.ValidUnit(requested) {
+		return "", errors.New("Invalid unit: must be kg or lb")
+	}
+	return requested, nil
+}
+
+// presentEntry expresses the weight of a workout exercise in unit
+func presentEntry(we *data.WorkoutExercise, unit string) {
+	we.Weight = we.WeightIn(unit)
+	we.Unit = unit
+}
+
+// presentWorkout expresses every weight of a workout in unit
+func presentWorkout(workout *data.Workout, unit string) {
+	for i := range workout.Details {
+		presentEntry(&workout.Details[i], unit)
+	}
+}
+
+// presentRecords expresses the weights and weight-based values of personal records in unit
+func presentRecords(records []*data.PersonalRecord, unit string) {
+	for _, record := range records {
+		if record.Weight != nil {
+			weight := data.RoundToPlate(data.FromKilograms(*record.Weight, unit), unit)
+			record.Weight = &weight
+		}
+		if record.RecordType == data.RecordRepsAtWeight {
+			continue
+		}
+		record.Value = data.ConvertMeasure(record.Value, unit)
+		if record.PreviousValue != nil {
+			previous := data.ConvertMeasure(*record.PreviousValue, unit)
+			record.PreviousValue = &previous
+		}
+	}
+}
+
+// presentSessions expresses the weights, volume and 1RM estimates of exercise sessions in unit
+func presentSessions(sessions []*data.ExerciseSession, unit string) {
+	for _, session := range sessions {
+		if session.BestSet != nil {
+			presentEntry(session.BestSet, unit)
+		}
+		session.Volume = data.ConvertMeasure(session.Volume, unit)
+		session.EstimatedOneRepMax = data.ConvertMeasure(session.EstimatedOneRepMax, unit)
+		if session.Trend != nil {
+			trend := data.ConvertMeasure(*session.Trend, unit)
+			session.Trend = &trend
+		}
+	}
+}
+
+// presentPerformances expresses the weights of a history in unit
+func presentPerformances(history []*data.ExercisePerformance, unit string) {
+	for _, p := range history {
+		presentEntry(&p.WorkoutExercise, unit)
+	}
+}
+
+// presentVolume expresses the tonnage of volume statistics in unit
+func presentVolume(series []*data.VolumeSeries, unit string) {
+	for _, s := range series {
+		s.Totals.Tonnage = data.ConvertMeasure(s.Totals.Tonnage, unit)
+		for i := range s.Buckets {
+			s.Buckets[i].Tonnage = data.ConvertMeasure(s.Buckets[i].Tonnage, unit)
+		}
+	}
+}
+
+// presentMeasurement expresses a body weight measurement in unit. Other
+// metrics keep their canonical unit.
+func presentMeasurement(measurement *data.Measurement, unit string) {
+	if measurement.Metric != data.MetricBodyWeight {
+		return
+	}
+	measurement.Value = data.ConvertMeasure(measurement.Value, unit)
+	measurement.Unit = unit
+}
+
+// presentRelativeStrength expresses the loads and body weights of a relative
+// strength series in unit
+func presentRelativeStrength(series []data.RelativeStrength, unit string) {
+	for i := range series {
+		series[i].BestWeight = data.ConvertMeasure(series[i].BestWeight, unit)
+		series[i].EstimatedOneRepMax = data.ConvertMeasure(series[i].EstimatedOneRepMax, unit)
+		series[i].BodyWeight = data.ConvertMeasure(series[i].BodyWeight, unit)
+	}
+}
+
+// presentGoal expresses the target and progress of a weight goal in unit
+func presentGoal(goal *data.Goal, unit string) {
+	if !data.GoalIsWeight(goal.GoalType) {
+		return
+	}
+	goal.TargetValue = data.ConvertMeasure(goal.TargetValue, unit)
+	if goal.Progress != nil {
+		goal.Progress.Current = data.ConvertMeasure(goal.Progress.Current, unit)
+	}
+	goal.Unit = unit
+}
+
+// presentLoad expresses the tonnage-based loads of a load report in unit
+func presentLoad(report *data.LoadReport, unit string) {
+	for i := range report.Days {
+		day := &report.Days[i]
+		day.Load = data.ConvertMeasure(day.Load, unit)
+		day.Acute = data.ConvertMeasure(day.Acute, unit)
+		day.Chronic = data.ConvertMeasure(day.Chronic, unit)
+		if day.Strain != nil {
+			strain := data.ConvertMeasure(*day.Strain, unit)
+			day.Strain = &strain
+		}
+	}
+}
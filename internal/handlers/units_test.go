@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestWeightUnits(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	getWorkout := func(id int64, unit string) data.WorkoutExercise {
+		t.Helper()
+		url := fmt.Sprintf("/api/workouts/%d", id)
+		if unit != "" {
+			url += "?unit=" + unit
+		}
+		rr := httptest.NewRecorder()
+		h.GetWorkout(rr, withURLParams(httptest.NewRequest("GET", url, nil), "id", fmt.Sprint(id)))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		var response struct {
+			Data data.Workout `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		return response.Data.Details[0]
+	}
+
+	// Enter 225 lb explicitly
+	body := `{"user_id": 1, "name": "Bench", "date": "2024-06-01", "unit": "lb", "details": [
+		{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 225}
+	]}`
+	rr := httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	id := created.Data.ID
+
+	// Reading back in pounds returns exactly what was entered
+	if we := getWorkout(id, "lb"); we.Weight == nil || *we.Weight != 225 || we.Unit != "lb" {
+		t.Errorf("wrong weight in lb: got %v %s want 225 lb", we.Weight, we.Unit)
+	}
+
+	// Kilograms are rounded to the nearest half kilo: 225 lb = 102.06 kg
+	if we := getWorkout(id, ""); we.Weight == nil || *we.Weight != 102 || we.Unit != "kg" {
+		t.Errorf("wrong weight in kg: got %v %s want 102 kg", we.Weight, we.Unit)
+	}
+
+	// Changing the preferred unit changes the default for display and entry
+	rr = httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1", bytes.NewBufferString(`{"preferred_unit": "lb"}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if we := getWorkout(id, ""); we.Weight == nil || *we.Weight != 225 || we.Unit != "lb" {
+		t.Errorf("wrong weight with lb preference: got %v %s want 225 lb", we.Weight, we.Unit)
+	}
+
+	body = `{"user_id": 1, "name": "Bench", "date": "2024-06-03", "details": [
+		{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 135}
+	]}`
+	rr = httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if we := getWorkout(created.Data.ID, "kg"); we.Weight == nil || *we.Weight != 61 {
+		t.Errorf("wrong weight entered with lb preference: got %v want 61 kg", we.Weight)
+	}
+
+	// Invalid units are rejected
+	rr = httptest.NewRecorder()
+	h.UpdateSettings(rr, httptest.NewRequest("PUT", "/api/settings?user_id=1", bytes.NewBufferString(`{"preferred_unit": "stone"}`)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	rr = httptest.NewRecorder()
+	body = `{"user_id": 1, "name": "Bench", "date": "2024-06-04", "unit": "st", "details": [
+		{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 10}
+	]}`
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	// So are invalid display units, before anything is saved
+	rr = httptest.NewRecorder()
+	h.GetWorkout(rr, withURLParams(httptest.NewRequest("GET", fmt.Sprintf("/api/workouts/%d?unit=st", id), nil), "id", fmt.Sprint(id)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	var before int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM workouts").Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	body = `{"user_id": 1, "name": "Bench", "date": "2024-06-05", "details": [
+		{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 60}
+	]}`
+	rr = httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts?unit=st", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	var after int
+	if err := h.db.QueryRow("SELECT COUNT(*) FROM workouts").Scan(&after); err != nil || after != before {
+		t.Errorf("a rejected request should not save a workout: %d workouts before, %d after, %v", before, after, err)
+	}
+}
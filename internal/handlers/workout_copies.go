@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"repup/internal/data"
+)
+
+// copyRequest is the optional body of a duplicate request
+type copyRequest struct {
+	Date   string `json:"date"`   // Format: "2006-01-02"; defaults to today in the user's timezone
+	Status string `json:"status"` // planned (default) or completed
+	GymID  int64  `json:"gym_id"` // Optional gym whose equipment the copy must fit
+}
+
+// ///////////////////////////////////////////////////////////////////////////
+// DuplicateWorkout handles POST requests to copy a workout and all of its
+// exercises to a new date. Copies are planned unless the body says otherwise.
+// With a gym_id, exercises the gym lacks equipment for are swapped.
+func (h *Handlers) DuplicateWorkout(w http.ResponseWriter, r *http.Request) {
+	workout, ok := h.ownedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req copyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	location, err := h.requestLocation(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	date, err := copyDate(req.Date, location)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status := req.Status
+	if status == "" {
+		status = data.WorkoutPlanned
+	}
+	if !data.ValidWorkoutStatus(status) {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid status: must be completed or planned")
+		return
+	}
+
+	h.createCopy(w, r, workout.Clone(date, status), req.GymID)
+}
+
+// ///////////////////////////////////////////////////////////////////////////
+// RepeatLastWorkout handles POST requests to plan the user's most recent
+// completed workout again. The name query parameter limits the search to
+// workouts with that name, and the gym parameter swaps exercises for ones
+// performable in that gym.
+func (h *Handlers) RepeatLastWorkout(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	location, err := h.requestLocation(r, userID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	date, err := copyDate(query.Get("date"), location)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var gymID int64
+	if err := parseIDParam(query.Get("gym"), &gymID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid gym format")
+		return
+	}
+
+	workout, err := h.models.Workouts.GetLatest(userID, query.Get("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "No completed workout to repeat")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	h.createCopy(w, r, workout.Clone(date, data.WorkoutPlanned), gymID)
+}
+
+// createCopy saves a cloned workout and responds with it, first adapting it
+// to the equipment of a gym of the user when gymID is set
+func (h *Handlers) createCopy(w http.ResponseWriter, r *http.Request, workout *data.Workout, gymID int64) {
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if gymID != 0 {
+		if _, ok := h.ownedGym(w, gymID, workout.UserID); !ok {
+			return
+		}
+		if err := h.models.Gyms.Adapt(gymID, workout); err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+
+	err = h.models.Workouts.Create(workout)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidGrouping):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid exercise grouping")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	presentWorkout(workout, unit)
+	h.respondWithJSON(w, http.StatusCreated, workout)
+}
+
+// copyDate parses the date of a copy, defaulting to today in location
+func copyDate(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return data.Today(location), nil
+	}
+
+	date, err := time.Parse(data.DateFormat, value)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid date format")
+	}
+	return date, nil
+}
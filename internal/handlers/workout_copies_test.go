@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestDuplicateWorkout(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	body := `{
+		"user_id": 1, "name": "Push", "date": "2024-06-04", "unit": "lb", "session_rpe": 8,
+		"details": [
+			{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 225, "group_id": 1, "group_type": "superset"},
+			{"exercise_id": 4, "sets": 3, "reps": 10, "group_id": 1, "group_type": "superset"}
+		]
+	}`
+	rr := httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	id := strconv.FormatInt(created.Data.ID, 10)
+
+	duplicate := func(body string) (*httptest.ResponseRecorder, data.Workout) {
+		req := httptest.NewRequest("POST", "/api/workouts/"+id+"/duplicate?user_id=1&unit=lb", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		h.DuplicateWorkout(rr, withURLParams(req, "id", id))
+
+		var response struct {
+			Data data.Workout `json:"data"`
+		}
+		if rr.Code == http.StatusCreated {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+		}
+		return rr, response.Data
+	}
+
+	// The copy keeps every entry as entered but is only planned
+	rr, copied := duplicate(`{"date": "2024-06-11"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if copied.ID == created.Data.ID || copied.Date.Format(data.DateFormat) != "2024-06-11" {
+		t.Errorf("wrong copy: %+v", copied)
+	}
+	if copied.Status != data.WorkoutPlanned || copied.SessionRPE != nil {
+		t.Errorf("copy should be a plan without a session RPE: %+v", copied)
+	}
+	if len(copied.Details) != 2 || *copied.Details[0].Weight != 225 || copied.Details[1].GroupType != "superset" {
+		t.Errorf("details not copied: %+v", copied.Details)
+	}
+	if len(copied.Details[0].Records) != 0 {
+		t.Error("a planned copy should not set records")
+	}
+
+	// An empty body copies to today
+	rr, copied = duplicate("")
+	if rr.Code != http.StatusCreated || copied.Date.IsZero() {
+		t.Errorf("copy without a body failed: %v %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Completed copy", `{"date": "2024-06-05", "status": "completed"}`, http.StatusCreated},
+		{"Invalid date", `{"date": "next week"}`, http.StatusBadRequest},
+		{"Invalid status", `{"status": "skipped"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, _ := duplicate(tt.body)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRepeatLastWorkout(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	createTestWorkout(t, h, 1, 1, 3, 5, 100)
+	latest := createTestWorkout(t, h, 8, 1, 3, 5, 105)
+	createTestWorkout(t, h, 9, 4, 3, 10, 20)
+	if _, err := h.models.Workouts.DB.Exec("UPDATE workouts SET name = 'Legs' WHERE id = ?", latest.ID+1); err != nil {
+		t.Fatalf("Failed to rename workout: %v", err)
+	}
+	// Plans are never repeated
+	plan := &data.Workout{UserID: 1, Name: "Session", Date: latest.Date.AddDate(0, 0, 7), Status: data.WorkoutPlanned}
+	if err := h.models.Workouts.Create(plan); err != nil {
+		t.Fatalf("Failed to create workout: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedWeight float64
+	}{
+		{"Latest by name", "?user_id=1&name=session&date=2024-01-15", http.StatusCreated, 105},
+		{"Latest of any name", "?user_id=1", http.StatusCreated, 20},
+		{"No matching workout", "?user_id=1&name=Pull", http.StatusNotFound, 0},
+		{"Invalid date", "?user_id=1&date=soon", http.StatusBadRequest, 0},
+		{"Missing user", "", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.RepeatLastWorkout(rr, httptest.NewRequest("POST", "/api/workouts/repeat-last"+tt.query, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var response struct {
+				Data data.Workout `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			if response.Data.Status != data.WorkoutPlanned || len(response.Data.Details) != 1 {
+				t.Fatalf("expected a planned copy: %+v", response.Data)
+			}
+			if got := *response.Data.Details[0].Weight; got != tt.expectedWeight {
+				t.Errorf("repeated the wrong workout: weight %v want %v", got, tt.expectedWeight)
+			}
+		})
+	}
+}
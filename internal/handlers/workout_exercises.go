@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"repup/internal/data"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ///////////////////////////////////////////////////////////////////////////
+// ListWorkoutExercises handles GET requests for the exercises of a workout
+func (h *Handlers) ListWorkoutExercises(w http.ResponseWriter, r *http.Request) {
+	workout, ok := h.ownedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.models.WorkoutExercises.GetByWorkoutID(workout.ID)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, we := range entries {
+		presentEntry(we, unit)
+	}
+
+	h.respondWithJSON(w, http.StatusOK, entries)
+}
+
+// //////////////////////////////////////////////////////////////////////
+// GetWorkoutExercise handles GET requests for a single exercise of a workout
+func (h *Handlers) GetWorkoutExercise(w http.ResponseWriter, r *http.Request) {
+	workout, we, ok := h.ownedWorkoutExercise(w, r)
+	if !ok {
+		return
+	}
+
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentEntry(we, unit)
+	h.respondWithJSON(w, http.StatusOK, we)
+}
+
+// ///////////////////////////////////////////////////////////////////////
+// CreateWorkoutExercise handles POST requests to add an exercise to a workout
+func (h *Handlers) CreateWorkoutExercise(w http.ResponseWriter, r *http.Request) {
+	workout, ok := h.ownedWorkout(w, r)
+	if !ok {
+		return
+	}
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req workoutExerciseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	we, err := req.toDetail(h.preferredUnit(workout.UserID))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	we.WorkoutID = workout.ID
+
+	err = h.models.WorkoutExercises.Create(&we)
+	if err != nil {
+		h.respondWithWorkoutExerciseError(w, err)
+		return
+	}
+
+	h.respondWithWorkoutExercise(w, we.ID, unit, http.StatusCreated)
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// UpdateWorkoutExercise handles PUT requests to change what was logged for an
+// exercise of a workout. The exercise itself cannot be changed.
+func (h *Handlers) UpdateWorkoutExercise(w http.ResponseWriter, r *http.Request) {
+	workout, existing, ok := h.ownedWorkoutExercise(w, r)
+	if !ok {
+		return
+	}
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req workoutExerciseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ExerciseID != 0 && req.ExerciseID != existing.ExerciseID {
+		h.respondWithError(w, http.StatusBadRequest, "The exercise of an entry cannot be changed; delete it and add a new one")
+		return
+	}
+
+	we, err := req.toDetail(h.preferredUnit(workout.UserID))
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	we.ID = existing.ID
+
+	err = h.models.WorkoutExercises.Update(&we)
+	if err != nil {
+		h.respondWithWorkoutExerciseError(w, err)
+		return
+	}
+
+	h.respondWithWorkoutExercise(w, we.ID, unit, http.StatusOK)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// DeleteWorkoutExercise handles DELETE requests to remove an exercise from a workout
+func (h *Handlers) DeleteWorkoutExercise(w http.ResponseWriter, r *http.Request) {
+	_, we, ok := h.ownedWorkoutExercise(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.models.WorkoutExercises.Delete(we.ID)
+	if err != nil {
+		h.respondWithWorkoutExerciseError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedWorkoutExercise loads the workout and exercise entry named in the URL,
+// responding with an error and returning false when either is missing, the
+// entry is not part of the workout or the workout belongs to another user
+func (h *Handlers) ownedWorkoutExercise(w http.ResponseWriter, r *http.Request) (*data.Workout, *data.WorkoutExercise, bool) {
+	workout, ok := h.ownedWorkout(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "weId"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid workout exercise ID format")
+		return nil, nil, false
+	}
+
+	we, err := h.models.WorkoutExercises.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Workout exercise not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, nil, false
+	}
+	if we.WorkoutID != workout.ID {
+		h.respondWithError(w, http.StatusNotFound, "Workout exercise not found")
+		return nil, nil, false
+	}
+
+	return workout, we, true
+}
+
+// respondWithWorkoutExercise reloads a saved entry and sends it in unit
+func (h *Handlers) respondWithWorkoutExercise(w http.ResponseWriter, id int64, unit string, status int) {
+	we, err := h.models.WorkoutExercises.GetByID(id)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	presentEntry(we, unit)
+	h.respondWithJSON(w, status, we)
+}
+
+func (h *Handlers) respondWithWorkoutExerciseError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Workout exercise not found")
+	case errors.Is(err, data.ErrInvalidGrouping):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid exercise grouping")
+	case errors.Is(err, data.ErrInvalidInput):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+	default:
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+	}
+}
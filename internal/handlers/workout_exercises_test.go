@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestNestedWorkoutExercises(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	workout := createTestWorkout(t, h, 1, 1, 3, 5, 100)
+	workoutID := strconv.FormatInt(workout.ID, 10)
+	base := "/api/workouts/" + workoutID + "/exercises"
+	query := "?user_id=1"
+
+	// Adding an exercise appends it to the workout
+	req := httptest.NewRequest("POST", base+query, bytes.NewBufferString(`{"exercise_id": 4, "sets": 3, "reps": 10, "weight": 135, "unit": "lb"}`))
+	req = withURLParams(req, "id", workoutID)
+	rr := httptest.NewRecorder()
+	h.CreateWorkoutExercise(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	var created struct {
+		Data data.WorkoutExercise `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if created.Data.WorkoutID != workout.ID || created.Data.Position != 1 {
+		t.Errorf("wrong entry created: %+v", created.Data)
+	}
+	entryID := strconv.FormatInt(created.Data.ID, 10)
+
+	// Listing returns both entries in order
+	rr = httptest.NewRecorder()
+	h.ListWorkoutExercises(rr, withURLParams(httptest.NewRequest("GET", base+query, nil), "id", workoutID))
+	var list struct {
+		Data []data.WorkoutExercise `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if len(list.Data) != 2 || list.Data[1].ID != created.Data.ID {
+		t.Fatalf("wrong entries listed: %+v", list.Data)
+	}
+
+	// Updating changes what was logged but not the exercise
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Changing the logged sets", `{"sets": 4, "reps": 8, "weight": 140, "unit": "lb"}`, http.StatusOK},
+		{"Changing the exercise", `{"exercise_id": 1, "sets": 4, "reps": 8, "weight": 140}`, http.StatusBadRequest},
+		{"Invalid unit", `{"sets": 4, "reps": 8, "weight": 140, "unit": "st"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", base+"/"+entryID+query, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			h.UpdateWorkoutExercise(rr, withURLParams(req, "id", workoutID, "weId", entryID))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+
+	rr = httptest.NewRecorder()
+	h.GetWorkoutExercise(rr, withURLParams(httptest.NewRequest("GET", base+"/"+entryID+query, nil), "id", workoutID, "weId", entryID))
+	var fetched struct {
+		Data data.WorkoutExercise `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&fetched); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if fetched.Data.Sets != 4 || fetched.Data.ExerciseID != 4 {
+		t.Errorf("entry not updated: %+v", fetched.Data)
+	}
+
+	// Entries of another workout, or of another user's workout, are not found
+	other := createTestWorkout(t, h, 2, 1, 3, 5, 100)
+	otherID := strconv.FormatInt(other.ID, 10)
+	rr = httptest.NewRecorder()
+	h.GetWorkoutExercise(rr, withURLParams(httptest.NewRequest("GET", "/"+query, nil), "id", otherID, "weId", entryID))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("entry of another workout: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	if _, err := h.models.Workouts.DB.Exec(`
+		INSERT INTO users (email, name, oauth_provider, oauth_id)
+		VALUES ('other@example.com', 'Other', 'google', 'other')`); err != nil {
+		t.Fatalf("Failed to insert user: %v", err)
+	}
+	rr = httptest.NewRecorder()
+	h.ListWorkoutExercises(rr, withURLParams(httptest.NewRequest("GET", base+"?user_id=2", nil), "id", workoutID))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("another user's workout: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+
+	// Deleting removes the entry
+	rr = httptest.NewRecorder()
+	h.DeleteWorkoutExercise(rr, withURLParams(httptest.NewRequest("DELETE", base+"/"+entryID+query, nil), "id", workoutID, "weId", entryID))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	entries, err := h.models.WorkoutExercises.GetByWorkoutID(workout.ID)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected one entry left: %v, %v", entries, err)
+	}
+}
+
+func TestPatchWorkout(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	workout := createTestWorkout(t, h, 1, 1, 3, 5, 100)
+	workoutID := strconv.FormatInt(workout.ID, 10)
+	entryID := workout.Details[0].ID
+
+	patch := func(contentType, body string) (*httptest.ResponseRecorder, data.Workout) {
+		req := httptest.NewRequest("PATCH", "/api/workouts/"+workoutID+"?user_id=1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		h.PatchWorkout(rr, withURLParams(req, "id", workoutID))
+
+		var response struct {
+			Data data.Workout `json:"data"`
+		}
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+		}
+		return rr, response.Data
+	}
+
+	// Patching summary fields leaves the entries untouched
+	rr, patched := patch(mergePatchContentType, `{"name": "Heavy day", "notes": "Felt strong", "session_rpe": 8}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if patched.Name != "Heavy day" || patched.Notes != "Felt strong" || patched.SessionRPE == nil {
+		t.Errorf("fields not patched: %+v", patched)
+	}
+	if len(patched.Details) != 1 || patched.Details[0].ID != entryID {
+		t.Errorf("entries should be kept: %+v", patched.Details)
+	}
+
+	// Null removes a member
+	_, patched = patch(mergePatchContentType, `{"session_rpe": null, "notes": null}`)
+	if patched.SessionRPE != nil || patched.Notes != "" || patched.Name != "Heavy day" {
+		t.Errorf("null members not removed: %+v", patched)
+	}
+
+	// Patching details replaces the entries
+	_, patched = patch("application/json", `{"details": [{"exercise_id": 4, "sets": 2, "reps": 12}]}`)
+	if len(patched.Details) != 1 || patched.Details[0].ExerciseID != 4 {
+		t.Errorf("details not replaced: %+v", patched.Details)
+	}
+
+	// The owner cannot be patched away
+	if _, err := h.db.Exec(`
+        INSERT INTO users (email, name, oauth_provider, oauth_id)
+        VALUES ('other@example.com', 'Other', 'google', 'other')`); err != nil {
+		t.Fatalf("Failed to insert second user: %v", err)
+	}
+	rr, patched = patch(mergePatchContentType, `{"user_id": 2, "name": "Stolen"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if patched.UserID != 1 {
+		t.Errorf("patch moved the workout to user %d", patched.UserID)
+	}
+	if stored, err := h.models.Workouts.GetByID(workout.ID); err != nil || stored.UserID != 1 {
+		t.Errorf("stored workout belongs to %+v, %v; want user 1", stored, err)
+	}
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{"Unsupported content type", "text/plain", `{"name": "x"}`, http.StatusUnsupportedMediaType},
+		{"Patch is not an object", mergePatchContentType, `["name"]`, http.StatusBadRequest},
+		{"Patched workout is invalid", mergePatchContentType, `{"date": "yesterday"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, _ := patch(tt.contentType, tt.body)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestWorkoutTagsAndFilters(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	// Volumes: Heavy bench 3x5x100 = 1500, Deload squat 3x5x60 = 900,
+	// Travel pull-ups have no weight
+	workouts := []string{
+		`{"user_id": 1, "name": "Heavy Bench", "date": "2024-03-01", "tags": ["Competition  Prep"],
+			"details": [{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 100}]}`,
+		`{"user_id": 1, "name": "Deload squats", "date": "2024-03-08", "tags": ["deload", "competition prep"],
+			"details": [{"exercise_id": 5, "sets": 3, "reps": 5, "weight": 60}]}`,
+		`{"user_id": 1, "name": "Hotel gym", "date": "2024-03-15", "tags": ["travel", "Travel"],
+			"details": [{"exercise_id": 4, "sets": 3, "reps": 8}]}`,
+	}
+	for _, body := range workouts {
+		rr := httptest.NewRecorder()
+		h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedNames  []string
+	}{
+		{"No filters", "", http.StatusOK, []string{"Hotel gym", "Deload squats", "Heavy Bench"}},
+		{"Tag", "&tag=competition+prep", http.StatusOK, []string{"Deload squats", "Heavy Bench"}},
+		{"Every tag", "&tag=deload&tag=Competition+Prep", http.StatusOK, []string{"Deload squats"}},
+		{"Any tag", "&tag=deload&tag=travel&match=any", http.StatusOK, []string{"Hotel gym", "Deload squats"}},
+		{"Date range", "&from=2024-03-02&to=2024-03-15", http.StatusOK, []string{"Hotel gym", "Deload squats"}},
+		{"Name", "&name=BENCH", http.StatusOK, []string{"Heavy Bench"}},
+		{"Name wildcards are literal", "&name=%25", http.StatusOK, nil},
+		{"Exercise", "&exercise_id=5", http.StatusOK, []string{"Deload squats"}},
+		{"Body part", "&body_part_id=2", http.StatusOK, []string{"Hotel gym"}},
+		{"Minimum volume", "&min_volume=1000", http.StatusOK, []string{"Heavy Bench"}},
+		{"Minimum volume in pounds", "&min_volume=1900&unit=lb", http.StatusOK, []string{"Deload squats", "Heavy Bench"}},
+		{"All conditions", "&tag=deload&body_part_id=1", http.StatusOK, nil},
+		{"Any condition", "&tag=deload&body_part_id=1&match=any", http.StatusOK, []string{"Deload squats", "Heavy Bench"}},
+		{"Invalid match", "&match=some", http.StatusBadRequest, nil},
+		{"Invalid date", "&from=March", http.StatusBadRequest, nil},
+		{"Invalid exercise", "&exercise_id=bench", http.StatusBadRequest, nil},
+		{"Invalid volume", "&min_volume=-1", http.StatusBadRequest, nil},
+		{"Non-finite volume", "&min_volume=NaN", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ListWorkouts(rr, httptest.NewRequest("GET", "/api/workouts?user_id=1"+tt.query, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data []data.Workout `json:"data"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response body: %v", err)
+			}
+			var names []string
+			for _, workout := range response.Data {
+				names = append(names, workout.Name)
+			}
+			if len(names) != len(tt.expectedNames) {
+				t.Fatalf("wrong workouts: got %v want %v", names, tt.expectedNames)
+			}
+			for i := range names {
+				if names[i] != tt.expectedNames[i] {
+					t.Fatalf("wrong workouts: got %v want %v", names, tt.expectedNames)
+				}
+			}
+		})
+	}
+
+	// Tags are normalized and listed with each workout
+	rr := httptest.NewRecorder()
+	h.ListWorkouts(rr, httptest.NewRequest("GET", "/api/workouts?user_id=1&name=hotel", nil))
+	var response struct {
+		Data []data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	if tags := response.Data[0].Tags; len(tags) != 1 || tags[0] != "travel" {
+		t.Errorf("wrong tags: %v", tags)
+	}
+
+	rr = httptest.NewRecorder()
+	body := `{"user_id": 1, "name": "Blank tag", "date": "2024-03-20", "tags": ["  "]}`
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("blank tag: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"repup/internal/data"
+)
+
+func TestWorkoutExerciseOrdering(t *testing.T) {
+	h := setupSchemaHandler(t)
+
+	body := `{
+		"user_id": 1, "name": "Upper", "date": "2024-05-01",
+		"details": [
+			{"exercise_id": 6, "sets": 3, "reps": 8, "weight": 40},
+			{"exercise_id": 1, "sets": 3, "reps": 10, "weight": 60, "group_id": 1, "group_type": "superset"},
+			{"exercise_id": 4, "sets": 3, "reps": 8, "group_id": 1, "group_type": "superset"}
+		]
+	}`
+	rr := httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(body)))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	var created struct {
+		Data data.Workout `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response body: %v", err)
+	}
+	workoutID := strconv.FormatInt(created.Data.ID, 10)
+	ids := make([]int64, len(created.Data.Details))
+	for i, detail := range created.Data.Details {
+		ids[i] = detail.ID
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedOrder  []int64 // exercise IDs
+	}{
+		{
+			name:           "Splitting a superset",
+			body:           `{"workout_exercise_ids": [` + join(ids[1], ids[0], ids[2]) + `]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing an exercise",
+			body:           `{"workout_exercise_ids": [` + join(ids[1], ids[2]) + `]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Moving the superset first",
+			body:           `{"workout_exercise_ids": [` + join(ids[2], ids[1], ids[0]) + `]}`,
+			expectedStatus: http.StatusOK,
+			expectedOrder:  []int64{4, 1, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/workouts/"+workoutID+"/order", bytes.NewBufferString(tt.body))
+			req = withURLParams(req, "id", workoutID)
+			rr := httptest.NewRecorder()
+
+			h.ReorderWorkoutExercises(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+					rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			workout, err := h.models.Workouts.GetByID(created.Data.ID)
+			if err != nil {
+				t.Fatalf("Failed to get workout: %v", err)
+			}
+			for i, detail := range workout.Details {
+				if detail.ExerciseID != tt.expectedOrder[i] || detail.Position != i {
+					t.Errorf("position %d: got exercise %v at position %v, want exercise %v",
+						i, detail.ExerciseID, detail.Position, tt.expectedOrder[i])
+				}
+			}
+			if workout.Details[0].GroupType != data.GroupSuperset || workout.Details[2].GroupID != nil {
+				t.Errorf("grouping not preserved: %+v", workout.Details)
+			}
+		})
+	}
+
+	// Mixed group types within one group are rejected on create
+	invalid := `{"user_id": 1, "name": "Bad", "date": "2024-05-02", "details": [
+		{"exercise_id": 1, "sets": 3, "reps": 10, "group_id": 1, "group_type": "superset"},
+		{"exercise_id": 4, "sets": 3, "reps": 8, "group_id": 1, "group_type": "circuit"}
+	]}`
+	rr = httptest.NewRecorder()
+	h.CreateWorkout(rr, httptest.NewRequest("POST", "/api/workouts", bytes.NewBufferString(invalid)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code for mixed group types: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// join formats IDs as the comma separated contents of a JSON array
+func join(ids ...int64) string {
+	var buf bytes.Buffer
+	for i, id := range ids {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(strconv.FormatInt(id, 10))
+	}
+	return buf.String()
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"mime"
 	"net/http"
 	"strconv"
 	"time"
@@ -14,19 +15,35 @@ import (
 
 // workoutRequest represents the expected request body for creating/updating a workout
 type workoutRequest struct {
-	UserID  int64                    `json:"user_id"`
-	Name    string                   `json:"name"`
-	Date    string                   `json:"date"` // Format: "2006-01-02"
-	Notes   string                   `json:"notes"`
-	Details []workoutExerciseRequest `json:"details"`
+	UserID          int64                    `json:"user_id"`
+	Name            string                   `json:"name"`
+	Date            string                   `json:"date"`     // Format: "2006-01-02"; optional when started_at is given
+	Timezone        string                   `json:"timezone"` // IANA timezone; defaults to the user's
+	StartedAt       *time.Time               `json:"started_at"`
+	EndedAt         *time.Time               `json:"ended_at"`
+	Notes           string                   `json:"notes"`
+	DurationMinutes *int                     `json:"duration_minutes"`
+	Status          string                   `json:"status"` // completed (default) or planned
+	SessionRPE      *float64                 `json:"session_rpe"`
+	Tags            []string                 `json:"tags"`
+	Unit            string                   `json:"unit"` // Unit of the weights in details; defaults to the user's preferred unit
+	Details         []workoutExerciseRequest `json:"details"`
 }
 
 type workoutExerciseRequest struct {
-	ExerciseID int64   `json:"exercise_id"`
-	Sets       int     `json:"sets"`
-	Reps       int     `json:"reps"`
-	Weight     float64 `json:"weight"`
-	Notes      string  `json:"notes"`
+	ExerciseID int64    `json:"exercise_id"`
+	Sets       int      `json:"sets"`
+	Reps       int      `json:"reps"`
+	Weight     float64  `json:"weight"`
+	Unit       string   `json:"unit"` // Overrides the workout's unit for this entry
+	RPE        *float64 `json:"rpe"`
+	Notes      string   `json:"notes"`
+	GroupID    *int64   `json:"group_id"`
+	GroupType  string   `json:"group_type"` // superset, circuit or giant_set
+
+	// Which of these apply depends on the exercise's tracking type
+	DurationSeconds *int     `json:"duration_seconds"`
+	DistanceMeters  *float64 `json:"distance_meters"`
 }
 
 func (h *Handlers) GetWorkout(w http.ResponseWriter, r *http.Request) {
@@ -50,9 +67,17 @@ func (h *Handlers) GetWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	presentWorkout(workout, unit)
 	h.respondWithJSON(w, http.StatusOK, workout)
 }
 
+// ListWorkouts handles GET requests for a user's workouts, paged with limit,
+// sort and cursor
 func (h *Handlers) ListWorkouts(w http.ResponseWriter, r *http.Request) {
 	userIDStr := r.URL.Query().Get("user_id")
 	if userIDStr == "" {
@@ -66,13 +91,104 @@ func (h *Handlers) ListWorkouts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	workouts, err := h.models.Workouts.GetAll(userID)
+	filter, err := h.parseWorkoutFilter(r, userID)
 	if err != nil {
-		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	list, err := parseListQuery(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	workouts, page, err := h.models.Workouts.GetAll(userID, filter, list)
+	if err != nil {
+		h.respondWithListError(w, err, "date or name")
 		return
 	}
 
-	h.respondWithJSON(w, http.StatusOK, workouts)
+	h.respondWithPage(w, workouts, page)
+}
+
+// parseWorkoutFilter reads the workout list filters from the query string.
+// tag, exercise_id and body_part_id may be repeated, and min_volume is in the
+// unit workouts are displayed in.
+func (h *Handlers) parseWorkoutFilter(r *http.Request, userID int64) (data.WorkoutFilter, error) {
+	query := r.URL.Query()
+	filter := data.WorkoutFilter{
+		Tags:   query["tag"],
+		Name:   query.Get("name"),
+		Status: query.Get("status"),
+		Match:  query.Get("match"),
+	}
+	if filter.Match != "" && !data.ValidMatch(filter.Match) {
+		return filter, errors.New("Invalid match: must be all or any")
+	}
+	if filter.Status != "" && !data.ValidWorkoutStatus(filter.Status) {
+		return filter, errors.New("Invalid status: must be completed or planned")
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(data.DateFormat, from); err != nil {
+			return filter, errors.New("Invalid from date format")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(data.DateFormat, to); err != nil {
+			return filter, errors.New("Invalid to date format")
+		}
+	}
+
+	if filter.ExerciseIDs, err = parseIDs(query["exercise_id"]); err != nil {
+		return filter, errors.New("Invalid exercise_id format")
+	}
+	if filter.BodyPartIDs, err = parseIDs(query["body_part_id"]); err != nil {
+		return filter, errors.New("Invalid body_part_id format")
+	}
+
+	if minVolume := query.Get("min_volume"); minVolume != "" {
+		var value float64
+		if err := parseFloatParam(minVolume, &value); err != nil || value < 0 {
+			return filter, errors.New("Invalid min_volume: must be a non-negative number")
+		}
+		unit, err := h.displayUnit(r, userID)
+		if err != nil {
+			return filter, err
+		}
+		kilograms := data.ToKilograms(value, unit)
+		filter.MinVolume = &kilograms
+	}
+
+	return filter, nil
+}
+
+// parseIDs parses a list of positive IDs
+func parseIDs(values []string) ([]int64, error) {
+	var ids []int64
+	for _, value := range values {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			return nil, errors.New("invalid ID")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseIDParam parses an optional positive ID query parameter into dst,
+// leaving it unchanged when the value is empty
+func parseIDParam(value string, dst *int64) error {
+	if value == "" {
+		return nil
+	}
+	ids, err := parseIDs([]string{value})
+	if err != nil {
+		return err
+	}
+	*dst = ids[0]
+	return nil
 }
 
 func (h *Handlers) CreateWorkout(w http.ResponseWriter, r *http.Request) {
@@ -83,39 +199,49 @@ func (h *Handlers) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse date string
-	date, err := time.Parse("2006-01-02", req.Date)
+	date, err := req.date()
 	if err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Invalid date format")
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Create workout object
 	workout := &data.Workout{
-		UserID: req.UserID,
-		Name:   req.Name,
-		Date:   date,
-		Notes:  req.Notes,
+		UserID:          req.UserID,
+		Name:            req.Name,
+		Date:            date,
+		Timezone:        req.Timezone,
+		StartedAt:       req.StartedAt,
+		EndedAt:         req.EndedAt,
+		Notes:           req.Notes,
+		DurationMinutes: req.DurationMinutes,
+		Status:          req.Status,
+		SessionRPE:      req.SessionRPE,
+		Tags:            req.Tags,
 	}
 
 	// Add exercises
-	for _, ex := range req.Details {
-		var weight *float64
-		if ex.Weight != 0 { // Assuming 0 means no weight provided
-			weightVal := ex.Weight
-			weight = &weightVal
-		}
-
-		workout.Details = append(workout.Details, data.WorkoutExercise{
-			ExerciseID: ex.ExerciseID,
-			Sets:       ex.Sets,
-			Reps:       ex.Reps,
-			Weight:     weight,
-			Notes:      ex.Notes,
-		})
+	unit, err := h.entryUnit(req.Unit, req.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	workout.Details, err = req.toDetails(unit)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	displayUnit, err := h.displayUnit(r, req.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+
 	err = h.models.Workouts.Create(workout)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrInvalidGrouping):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid exercise grouping")
 		case errors.Is(err, data.ErrInvalidInput):
 			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
 		default:
@@ -124,6 +250,7 @@ func (h *Handlers) CreateWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	presentWorkout(workout, displayUnit)
 	h.respondWithJSON(w, http.StatusCreated, workout)
 }
 
@@ -140,41 +267,61 @@ func (h *Handlers) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.updateWorkout(w, r, id, req, true)
+}
+
+// updateWorkout saves a workout from a request, replacing its exercises when
+// withDetails is set and leaving them untouched otherwise
+func (h *Handlers) updateWorkout(w http.ResponseWriter, r *http.Request, id int64, req workoutRequest, withDetails bool) {
 	// Parse date string
-	date, err := time.Parse("2006-01-02", req.Date)
+	date, err := req.date()
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	displayUnit, err := h.displayUnit(r, req.UserID)
 	if err != nil {
-		h.respondWithError(w, http.StatusBadRequest, "Invalid date format")
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	workout := &data.Workout{
-		ID:     id,
-		UserID: req.UserID,
-		Name:   req.Name,
-		Date:   date,
-		Notes:  req.Notes,
+		ID:              id,
+		UserID:          req.UserID,
+		Name:            req.Name,
+		Date:            date,
+		Timezone:        req.Timezone,
+		StartedAt:       req.StartedAt,
+		EndedAt:         req.EndedAt,
+		Notes:           req.Notes,
+		DurationMinutes: req.DurationMinutes,
+		Status:          req.Status,
+		SessionRPE:      req.SessionRPE,
+		Tags:            req.Tags,
 	}
 
-	for _, ex := range req.Details {
-		var weight *float64
-		if ex.Weight != 0 { // Assuming 0 means no weight provided
-			weightVal := ex.Weight
-			weight = &weightVal
+	if withDetails {
+		unit, err := h.entryUnit(req.Unit, req.UserID)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, err.Error())
+			return
 		}
-
-		workout.Details = append(workout.Details, data.WorkoutExercise{
-			ExerciseID: ex.ExerciseID,
-			Sets:       ex.Sets,
-			Reps:       ex.Reps,
-			Weight:     weight,
-			Notes:      ex.Notes, // If you have this in your request
-		})
+		workout.Details, err = req.toDetails(unit)
+		if err != nil {
+			h.respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		err = h.models.Workouts.Update(workout)
+	} else {
+		err = h.models.Workouts.UpdateSummary(workout)
 	}
-	err = h.models.Workouts.Update(workout)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			h.respondWithError(w, http.StatusNotFound, "Workout not found")
+		case errors.Is(err, data.ErrInvalidGrouping):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid exercise grouping")
 		case errors.Is(err, data.ErrInvalidInput):
 			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
 		default:
@@ -183,6 +330,14 @@ func (h *Handlers) UpdateWorkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !withDetails {
+		if workout, err = h.models.Workouts.GetByID(id); err != nil {
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+
+	presentWorkout(workout, displayUnit)
 	h.respondWithJSON(w, http.StatusOK, workout)
 }
 
@@ -208,3 +363,256 @@ func (h *Handlers) DeleteWorkout(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// date parses the local date of the requested workout. It may be left out
+// when the workout has a start time, in which case the data layer takes the
+// date from the start in the workout's timezone.
+func (req workoutRequest) date() (time.Time, error) {
+	if req.Date == "" && req.StartedAt != nil {
+		return time.Time{}, nil
+	}
+
+	date, err := time.Parse(data.DateFormat, req.Date)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid date format")
+	}
+	return date, nil
+}
+
+// toDetails converts the requested entries into workout exercises, recording
+// weights in the entry's unit or defaultUnit
+func (req workoutRequest) toDetails(defaultUnit string) ([]data.WorkoutExercise, error) {
+	var details []data.WorkoutExercise
+
+	for _, ex := range req.Details {
+		detail, err := ex.toDetail(defaultUnit)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+
+	return details, nil
+}
+
+// toDetail converts a requested entry into a workout exercise, recording its
+// weight in the entry's unit or defaultUnit
+func (ex workoutExerciseRequest) toDetail(defaultUnit string) (data.WorkoutExercise, error) {
+	detail := data.WorkoutExercise{
+		ExerciseID: ex.ExerciseID,
+		Sets:       ex.Sets,
+		Reps:       ex.Reps,
+		RPE:        ex.RPE,
+		Notes:      ex.Notes,
+		GroupID:    ex.GroupID,
+		GroupType:  ex.GroupType,
+
+		DurationSeconds: ex.DurationSeconds,
+		DistanceMeters:  ex.DistanceMeters,
+	}
+
+	if ex.Weight != 0 { // Assuming 0 means no weight provided
+		unit := defaultUnit
+		if ex.Unit != "" {
+			unit = ex.Unit
+		}
+		if !data.ValidUnit(unit) {
+			return detail, errors.New("Invalid unit: must be kg or lb")
+		}
+		detail.SetWeight(ex.Weight, unit)
+	}
+
+	return detail, nil
+}
+
+// reorderRequest lists the IDs of a workout's exercises in their new order
+type reorderRequest struct {
+	WorkoutExerciseIDs []int64 `json:"workout_exercise_ids"`
+}
+
+func (h *Handlers) ReorderWorkoutExercises(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var req reorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// The owner decides the unit of the response, so look it up before changing anything
+	workout, err := h.models.Workouts.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound), errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusNotFound, "Workout not found")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	unit, err := h.displayUnit(r, workout.UserID)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.models.Workouts.Reorder(id, req.WorkoutExerciseIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Workout not found")
+		case errors.Is(err, data.ErrInvalidGrouping):
+			h.respondWithError(w, http.StatusBadRequest, "Reordering would split a superset, circuit or giant set")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "workout_exercise_ids must list every exercise in the workout once")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	workout, err = h.models.Workouts.GetByID(id)
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	presentWorkout(workout, unit)
+	h.respondWithJSON(w, http.StatusOK, workout)
+}
+
+// ////////////////////////////////////////////////////////////////////////////
+// PatchWorkout handles PATCH requests that change a workout with a JSON merge
+// patch. The patch applies to the workout as it is sent to PUT; exercises are
+// only replaced when the patch includes details.
+func (h *Handlers) PatchWorkout(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		(mediaType != mergePatchContentType && mediaType != "application/json") {
+		h.respondWithError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+		return
+	}
+
+	workout, ok := h.ownedWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var patch interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if _, ok := patch.(map[string]interface{}); !ok {
+		h.respondWithError(w, http.StatusBadRequest, "Merge patch must be a JSON object")
+		return
+	}
+
+	// Round trip the current workout through its request representation
+	document, err := json.Marshal(workoutToRequest(workout))
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	patched, err := json.Marshal(applyMergePatch(target, patch))
+	if err != nil {
+		h.respondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	var req workoutRequest
+	if err := json.Unmarshal(patched, &req); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Patched workout is invalid: "+err.Error())
+		return
+	}
+
+	// A patch changes the workout, not who it belongs to
+	req.UserID = workout.UserID
+
+	_, withDetails := patch.(map[string]interface{})["details"]
+	h.updateWorkout(w, r, workout.ID, req, withDetails)
+}
+
+// workoutToRequest expresses a stored workout as the request that would
+// recreate it, with every weight in the unit it was entered in
+func workoutToRequest(workout *data.Workout) workoutRequest {
+	req := workoutRequest{
+		UserID:          workout.UserID,
+		Name:            workout.Name,
+		Date:            workout.Date.Format(data.DateFormat),
+		Timezone:        workout.Timezone,
+		StartedAt:       workout.StartedAt,
+		EndedAt:         workout.EndedAt,
+		Notes:           workout.Notes,
+		DurationMinutes: workout.DurationMinutes,
+		Status:          workout.Status,
+		SessionRPE:      workout.SessionRPE,
+		Tags:            workout.Tags,
+		Details:         []workoutExerciseRequest{},
+	}
+
+	for _, detail := range workout.Details {
+		ex := workoutExerciseRequest{
+			ExerciseID: detail.ExerciseID,
+			Sets:       detail.Sets,
+			Reps:       detail.Reps,
+			RPE:        detail.RPE,
+			Notes:      detail.Notes,
+			GroupID:    detail.GroupID,
+			GroupType:  detail.GroupType,
+
+			DurationSeconds: detail.DurationSeconds,
+			DistanceMeters:  detail.DistanceMeters,
+		}
+		if detail.EnteredWeight != nil {
+			ex.Weight = *detail.EnteredWeight
+			ex.Unit = detail.EnteredUnit
+		}
+		req.Details = append(req.Details, ex)
+	}
+
+	return req
+}
+
+// ownedWorkout loads the workout named in the URL, responding with an error
+// and returning false when it is missing or belongs to another user
+func (h *Handlers) ownedWorkout(w http.ResponseWriter, r *http.Request) (*data.Workout, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return nil, false
+	}
+
+	userID, err := h.currentUserID(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	workout, err := h.models.Workouts.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Workout not found")
+		case errors.Is(err, data.ErrInvalidInput):
+			h.respondWithError(w, http.StatusBadRequest, "Invalid input")
+		default:
+			h.respondWithError(w, http.StatusInternalServerError, "Database error")
+		}
+		return nil, false
+	}
+	if workout.UserID != userID {
+		h.respondWithError(w, http.StatusNotFound, "Workout not found")
+		return nil, false
+	}
+
+	return workout, true
+}
@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Options configures the validation middleware
+type Options struct {
+	// ValidateResponses checks every response against the document as well.
+	// Responses are buffered to do so, so it is meant for tests and
+	// development rather than production.
+	ValidateResponses bool
+
+	// ResponseError is called for each response that does not match the
+	// document; the response is still sent. It defaults to logging.
+	ResponseError func(r *http.Request, err error)
+}
+
+// Middleware rejects requests that do not match the document with 400, 415
+// for a body in a media type the operation does not accept or 413 for a JSON
+// body over MaxBodyBytes
+func (s *Spec) Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.ResponseError == nil {
+		opts.ResponseError = func(r *http.Request, err error) {
+			log.Error().Err(err).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("Response does not match the OpenAPI document")
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := s.ValidateRequest(r); err != nil {
+				status := http.StatusBadRequest
+				switch {
+				case errors.Is(err, ErrUnsupportedMediaType):
+					status = http.StatusUnsupportedMediaType
+				case errors.Is(err, ErrRequestTooLarge):
+					status = http.StatusRequestEntityTooLarge
+				}
+				respondWithError(w, status, "Invalid request: "+err.Error())
+				return
+			}
+
+			if !opts.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if err := s.ValidateResponse(r.Method, r.URL.Path, recorder.status, recorder.header, recorder.body.Bytes()); err != nil {
+				opts.ResponseError(r, err)
+			}
+
+			for name, values := range recorder.header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(recorder.status)
+			w.Write(recorder.body.Bytes())
+		})
+	}
+}
+
+// respondWithError sends an error in the envelope the handlers use
+func respondWithError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// responseRecorder buffers a response so it can be checked before it is sent
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) Header() http.Header {
+	return rr.header
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.wroteHeader {
+		return
+	}
+	rr.status = status
+	rr.wroteHeader = true
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	return rr.body.Write(b)
+}
@@ -0,0 +1,278 @@
+// Package openapi serves the OpenAPI 3.1 document describing the API and
+// validates requests and responses against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the OpenAPI document as JSON
+func Document() []byte {
+	return document
+}
+
+// Spec is a parsed OpenAPI document, indexed for matching requests to the
+// operations it describes
+type Spec struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+		Responses  map[string]*response  `json:"responses"`
+	} `json:"components"`
+
+	routes []*route
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path or query
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref     string                `json:"$ref"`
+	Content map[string]*mediaType `json:"content"` // Empty for responses without a body
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+// route is one operation with its path template split into segments
+type route struct {
+	method    string
+	template  string
+	segments  []string
+	operation *operation
+}
+
+// Load parses the embedded document, resolving the parameters and responses
+// operations share and checking that every schema reference resolves
+func Load() (*Spec, error) {
+	return parse(document)
+}
+
+func parse(doc []byte) (*Spec, error) {
+	var s Spec
+	if err := json.Unmarshal(doc, &s); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+
+	for template, methods := range s.Paths {
+		for method, op := range methods {
+			for i, p := range op.Parameters {
+				if p.Ref == "" {
+					continue
+				}
+				shared, ok := s.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+				if !ok {
+					return nil, fmt.Errorf("openapi: %s %s: unknown parameter %s", method, template, p.Ref)
+				}
+				op.Parameters[i] = shared
+			}
+			for status, resp := range op.Responses {
+				if resp.Ref == "" {
+					continue
+				}
+				shared, ok := s.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+				if !ok {
+					return nil, fmt.Errorf("openapi: %s %s: unknown response %s", method, template, resp.Ref)
+				}
+				op.Responses[status] = shared
+			}
+
+			s.routes = append(s.routes, &route{
+				method:    strings.ToUpper(method),
+				template:  template,
+				segments:  splitPath(template),
+				operation: op,
+			})
+		}
+	}
+	sort.Slice(s.routes, func(i, j int) bool {
+		return s.routes[i].key() < s.routes[j].key()
+	})
+
+	if err := s.checkRefs(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *route) key() string {
+	return r.method + " " + r.template
+}
+
+// Operations lists every operation in the document as "METHOD /path"
+func (s *Spec) Operations() []string {
+	keys := make([]string, len(s.routes))
+	for i, r := range s.routes {
+		keys[i] = r.key()
+	}
+	return keys
+}
+
+// Match finds the operation serving a request path, returning it as
+// "METHOD /path template"
+func (s *Spec) Match(method, path string) (string, bool) {
+	r, _ := s.match(method, path)
+	if r == nil {
+		return "", false
+	}
+	return r.key(), true
+}
+
+// match finds the route for a request with its path parameters. Like the
+// router, a literal segment wins over a parameter in the same place.
+func (s *Spec) match(method, path string) (*route, map[string]string) {
+	segments := splitPath(path)
+
+	var best *route
+	for _, r := range s.routes {
+		if r.method != method || len(r.segments) != len(segments) {
+			continue
+		}
+		if !r.matches(segments) {
+			continue
+		}
+		if best == nil || r.moreSpecific(best) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	params := map[string]string{}
+	for i, segment := range best.segments {
+		if name, ok := paramName(segment); ok {
+			params[name] = segments[i]
+		}
+	}
+	return best, params
+}
+
+func (r *route) matches(segments []string) bool {
+	for i, segment := range r.segments {
+		if _, ok := paramName(segment); ok {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *route) moreSpecific(other *route) bool {
+	for i, segment := range r.segments {
+		_, param := paramName(segment)
+		_, otherParam := paramName(other.segments[i])
+		if param != otherParam {
+			return !param
+		}
+	}
+	return false
+}
+
+func paramName(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// splitPath splits a path into its segments, ignoring a trailing slash
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func (s *Spec) resolveSchema(ref string) (*schema, error) {
+	target, ok := s.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	if !ok || !strings.HasPrefix(ref, "#/components/schemas/") {
+		return nil, fmt.Errorf("openapi: unknown schema %s", ref)
+	}
+	return target, nil
+}
+
+// checkRefs makes sure every schema reference in the document resolves, so a
+// typo fails when the document is loaded rather than when a request uses it
+func (s *Spec) checkRefs() error {
+	var check func(sch *schema) error
+	check = func(sch *schema) error {
+		if sch == nil {
+			return nil
+		}
+		if sch.Ref != "" {
+			_, err := s.resolveSchema(sch.Ref)
+			return err
+		}
+		children := append(append([]*schema{sch.Items}, sch.AllOf...), sch.AnyOf...)
+		for _, property := range sch.Properties {
+			children = append(children, property)
+		}
+		for _, child := range children {
+			if err := check(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, sch := range s.Components.Schemas {
+		if err := check(sch); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.routes {
+		for _, p := range r.operation.Parameters {
+			if err := check(p.Schema); err != nil {
+				return err
+			}
+		}
+		if r.operation.RequestBody != nil {
+			for _, media := range r.operation.RequestBody.Content {
+				if err := check(media.Schema); err != nil {
+					return err
+				}
+			}
+		}
+		for _, resp := range r.operation.Responses {
+			for _, media := range resp.Content {
+				if err := check(media.Schema); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func loadSpec(t *testing.T) *Spec {
+	t.Helper()
+	spec, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load the document: %v", err)
+	}
+	return spec
+}
+
+func TestDocument(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(Document(), &doc); err != nil {
+		t.Fatalf("Document is not JSON: %v", err)
+	}
+	if doc["openapi"] != "3.1.0" {
+		t.Errorf("wrong OpenAPI version: %v", doc["openapi"])
+	}
+
+	// Every operation needs a unique operationId and documented errors
+	spec := loadSpec(t)
+	seen := map[string]string{}
+	for _, r := range spec.routes {
+		id := r.operation.OperationID
+		if id == "" {
+			t.Errorf("%s has no operationId", r.key())
+		} else if other, ok := seen[id]; ok {
+			t.Errorf("%s and %s share operationId %s", r.key(), other, id)
+		}
+		seen[id] = r.key()
+
+		if r.template != "/api/openapi.json" && r.operation.Responses["default"] == nil {
+			t.Errorf("%s does not document its errors", r.key())
+		}
+		for _, segment := range r.segments {
+			name, ok := paramName(segment)
+			if !ok {
+				continue
+			}
+			found := false
+			for _, p := range r.operation.Parameters {
+				found = found || (p.In == "path" && p.Name == name && p.Required)
+			}
+			if !found {
+				t.Errorf("%s does not declare path parameter %s", r.key(), name)
+			}
+		}
+	}
+}
+
+func TestParseRejectsUnknownReferences(t *testing.T) {
+	doc := `{"paths": {"/api/things": {"get": {"responses": {"200": {"description": "ok",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Missing"}}}}}}}}}`
+	if _, err := parse([]byte(doc)); err == nil {
+		t.Error("a reference to a missing schema should fail to load")
+	}
+
+	doc = `{"paths": {"/api/things": {"get": {"parameters": [{"$ref": "#/components/parameters/Missing"}]}}}}`
+	if _, err := parse([]byte(doc)); err == nil {
+		t.Error("a reference to a missing parameter should fail to load")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	spec := loadSpec(t)
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/body-parts/tree", "GET /api/body-parts/tree"},
+		{"GET", "/api/body-parts/7", "GET /api/body-parts/{id}"},
+		{"GET", "/api/body-parts/", "GET /api/body-parts"},
+		{"POST", "/api/workouts/repeat-last", "POST /api/workouts/repeat-last"},
+		{"POST", "/api/workouts/3/duplicate", "POST /api/workouts/{id}/duplicate"},
+		{"DELETE", "/api/workouts/3/exercises/9", "DELETE /api/workouts/{id}/exercises/{weId}"},
+		{"GET", "/api/measurements/trend", "GET /api/measurements/trend"},
+		{"POST", "/api/exercises/2/merge-into/1", "POST /api/exercises/{id}/merge-into/{targetId}"},
+	}
+	for _, tt := range tests {
+		got, ok := spec.Match(tt.method, tt.path)
+		if !ok || got != tt.want {
+			t.Errorf("Match(%s %s) = %q, %v; want %q", tt.method, tt.path, got, ok, tt.want)
+		}
+	}
+
+	for _, unknown := range [][2]string{{"PATCH", "/api/body-parts/1"}, {"GET", "/api/nope"}, {"GET", "/files/a.png"}} {
+		if got, ok := spec.Match(unknown[0], unknown[1]); ok {
+			t.Errorf("Match(%s %s) = %q; want no match", unknown[0], unknown[1], got)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec := loadSpec(t)
+
+	request := func(method, target, contentType, body string) *http.Request {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, target, reader)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return req
+	}
+
+	valid := []*http.Request{
+		request("GET", "/api/workouts?user_id=1&tag=push&tag=heavy&exercise_id=1&exercise_id=3&from=2024-01-01&sort=-name", "", ""),
+		request("GET", "/api/body-parts?top_level=true&limit=10", "", ""),
+		request("GET", "/api/exercises?q=", "", ""),
+		request("POST", "/api/workouts", "application/json",
+			`{"user_id": 1, "name": "Push", "date": "2024-03-01", "unit": "lb",
+              "details": [{"exercise_id": 1, "sets": 3, "reps": 5, "weight": 225, "rpe": 8.5, "group_type": "superset"}]}`),
+		request("PATCH", "/api/workouts/4", "application/merge-patch+json", `{"notes": null, "session_rpe": 7}`),
+		request("POST", "/api/workouts/4/duplicate", "", ""),
+		request("PUT", "/api/settings", "", `{"preferred_unit": "kg", "weekly_target": null}`),
+		request("GET", "/api/unknown?limit=oops", "", ""),
+	}
+	for _, req := range valid {
+		if err := spec.ValidateRequest(req); err != nil {
+			t.Errorf("%s %s: unexpected error %v", req.Method, req.URL, err)
+		}
+	}
+
+	invalidRequests := []struct {
+		req  *http.Request
+		path string
+	}{
+		{request("GET", "/api/workouts", "", ""), "query.user_id"},
+		{request("GET", "/api/workouts?user_id=1&exercise_id=1&exercise_id=x", "", ""), "query.exercise_id[1]"},
+		{request("GET", "/api/workouts?user_id=1&status=skipped", "", ""), "query.status"},
+		{request("GET", "/api/workouts?user_id=1&from=01/02/2024", "", ""), "query.from"},
+		{request("GET", "/api/body-parts/abc", "", ""), "path.id"},
+		{request("GET", "/api/body-parts?limit=500", "", ""), "query.limit"},
+		{request("GET", "/api/stats/relative-strength?user_id=1", "", ""), "query.exercise_id"},
+		{request("POST", "/api/workouts", "application/json", ""), "body"},
+		{request("POST", "/api/workouts", "application/json", `{"user_id": 1`), "body"},
+		{request("POST", "/api/workouts", "application/json", `{"name": "Push"}`), "body.user_id"},
+		{request("POST", "/api/workouts", "application/json", `{"user_id": 1, "name": "Push", "colour": "red"}`), "body.colour"},
+		{request("POST", "/api/workouts", "application/json", `{"user_id": 1, "name": "Push", "details": [{"exercise_id": 1, "sets": 2.5}]}`), "body.details[0].sets"},
+		{request("POST", "/api/workouts", "application/json", `{"user_id": 1, "name": "Push", "session_rpe": 11}`), "body.session_rpe"},
+		{request("PUT", "/api/settings", "application/json", `{"preferred_unit": "stone"}`), "body.preferred_unit"},
+		{request("PUT", "/api/inventory", "application/json", `{"bars": [], "dumbbell_increment": 2}`), "body.bars"},
+	}
+	for _, tt := range invalidRequests {
+		err := spec.ValidateRequest(tt.req)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s %s: got %v, want a validation error", tt.req.Method, tt.req.URL, err)
+			continue
+		}
+		if verr.Path != tt.path {
+			t.Errorf("%s %s: error at %s (%v), want %s", tt.req.Method, tt.req.URL, verr.Path, verr, tt.path)
+		}
+	}
+
+	err := spec.ValidateRequest(request("POST", "/api/workouts", "text/csv", "name\nPush"))
+	if !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("CSV body: got %v want %v", err, ErrUnsupportedMediaType)
+	}
+}
+
+func TestValidateRequestKeepsBody(t *testing.T) {
+	spec := loadSpec(t)
+
+	body := `{"preferred_unit": "lb"}`
+	req := httptest.NewRequest("PUT", "/api/settings", strings.NewReader(body))
+	if err := spec.ValidateRequest(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := io.ReadAll(req.Body)
+	if string(got) != body {
+		t.Errorf("body after validation = %q, want %q", got, body)
+	}
+}
+
+// unreadable fails the test if a body that should be left alone is read
+type unreadable struct{ t *testing.T }
+
+func (u unreadable) Read([]byte) (int, error) {
+	u.t.Error("upload body was read by the validator")
+	return 0, io.EOF
+}
+
+func TestValidateRequestBodyLimits(t *testing.T) {
+	spec := loadSpec(t)
+
+	huge := `{"preferred_unit": "kg", "timezone": "` + strings.Repeat("x", MaxBodyBytes) + `"}`
+	req := httptest.NewRequest("PUT", "/api/settings", strings.NewReader(huge))
+	req.Header.Set("Content-Type", "application/json")
+	if err := spec.ValidateRequest(req); !errors.Is(err, ErrRequestTooLarge) {
+		t.Errorf("oversized JSON body: got %v want %v", err, ErrRequestTooLarge)
+	}
+
+	req = httptest.NewRequest("POST", "/api/exercises/1/media", unreadable{t})
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+	if err := spec.ValidateRequest(req); err != nil {
+		t.Errorf("multipart upload: unexpected error %v", err)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	spec := loadSpec(t)
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+
+	valid := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/api/body-parts/1", 200, `{"data": {"id": 1, "name": "Chest", "parent_id": null}}`},
+		{"GET", "/api/body-parts/99", 404, `{"error": "Body part not found"}`},
+		{"GET", "/api/body-parts", 200, `{"data": [], "next_cursor": null, "has_more": false}`},
+		{"PUT", "/api/settings", 200, `{"data": {"user_id": 1, "preferred_unit": "kg", "weekly_target": 3, "timezone": "UTC"}}`},
+	}
+	for _, tt := range valid {
+		if err := spec.ValidateResponse(tt.method, tt.path, tt.status, jsonHeader, []byte(tt.body)); err != nil {
+			t.Errorf("%s %s %d: unexpected error %v", tt.method, tt.path, tt.status, err)
+		}
+	}
+	if err := spec.ValidateResponse("DELETE", "/api/goals/1", 204, http.Header{}, nil); err != nil {
+		t.Errorf("204 without a body: unexpected error %v", err)
+	}
+
+	invalidResponses := []struct {
+		method, path string
+		status       int
+		header       http.Header
+		body         string
+	}{
+		{"GET", "/api/body-parts/1", 200, jsonHeader, `{"data": {"id": 1, "name": "Chest"}}`},
+		{"GET", "/api/body-parts/1", 200, jsonHeader, `{"data": {"id": "1", "name": "Chest", "parent_id": null}}`},
+		{"GET", "/api/body-parts", 200, jsonHeader, `{"data": []}`},
+		{"GET", "/api/body-parts/1", 500, jsonHeader, `{"message": "oops"}`},
+		{"GET", "/api/body-parts/1", 200, http.Header{"Content-Type": {"text/plain"}}, `ok`},
+		{"DELETE", "/api/goals/1", 204, jsonHeader, `{"data": null}`},
+		{"GET", "/api/openapi.json", 404, jsonHeader, `{"error": "not found"}`},
+	}
+	for _, tt := range invalidResponses {
+		if err := spec.ValidateResponse(tt.method, tt.path, tt.status, tt.header, []byte(tt.body)); err == nil {
+			t.Errorf("%s %s %d %s: expected an error", tt.method, tt.path, tt.status, tt.body)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	spec := loadSpec(t)
+
+	var reported []error
+	handler := spec.Middleware(Options{
+		ValidateResponses: true,
+		ResponseError:     func(r *http.Request, err error) { reported = append(reported, err) },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Handler", "yes")
+		if r.URL.Query().Get("broken") != "" {
+			w.Write([]byte(`{"data": {"preferred_unit": "kg"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": {"user_id": 1, "preferred_unit": "kg", "weekly_target": null, "timezone": "UTC"}}`))
+	}))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(httptest.NewRequest("GET", "/api/settings?user_id=1", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Handler") != "yes" || !strings.Contains(rr.Body.String(), `"timezone"`) {
+		t.Errorf("valid request not passed through: %d %v %s", rr.Code, rr.Header(), rr.Body.String())
+	}
+	if len(reported) != 0 {
+		t.Errorf("valid response reported: %v", reported)
+	}
+
+	rr = serve(httptest.NewRequest("GET", "/api/settings?user_id=0", nil))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "query.user_id") {
+		t.Errorf("invalid request: got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("X-Handler") != "" {
+		t.Error("invalid request reached the handler")
+	}
+
+	req := httptest.NewRequest("PUT", "/api/settings", strings.NewReader("preferred_unit=kg"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if rr = serve(req); rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("form body: got %d want %d", rr.Code, http.StatusUnsupportedMediaType)
+	}
+
+	req = httptest.NewRequest("PUT", "/api/settings", strings.NewReader(`{"timezone": "`+strings.Repeat("x", MaxBodyBytes)+`"}`))
+	if rr = serve(req); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: got %d want %d", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// A response that departs from the document is reported and still sent
+	rr = serve(httptest.NewRequest("GET", "/api/settings?broken=1", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != `{"data": {"preferred_unit": "kg"}}` {
+		t.Errorf("broken response not sent as written: %d %s", rr.Code, rr.Body.String())
+	}
+	if len(reported) != 1 {
+		t.Errorf("broken response: got %d reports, want 1", len(reported))
+	}
+}
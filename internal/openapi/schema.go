@@ -0,0 +1,275 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schema is the subset of JSON Schema the API document uses. Keywords it does
+// not list are accepted in the document and ignored when validating.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaTypes        `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"` // Only false restricts
+	Items                *schema            `json:"items"`
+	AllOf                []*schema          `json:"allOf"`
+	AnyOf                []*schema          `json:"anyOf"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+}
+
+// schemaTypes holds the type keyword, which is a single name or, for
+// nullable values, a list such as ["integer", "null"]
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings: %w", err)
+	}
+	*t = names
+	return nil
+}
+
+// ValidationError describes where a request or response departs from the
+// document
+type ValidationError struct {
+	Path    string // Such as body.details[0].sets or query.limit
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+func invalid(path, format string, args ...interface{}) error {
+	return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// validate checks a decoded JSON value against a schema. Numbers must be
+// decoded as json.Number so integers can be told apart from other numbers.
+func (s *Spec) validate(sch *schema, value interface{}, path string) error {
+	if sch == nil {
+		return nil
+	}
+	if sch.Ref != "" {
+		target, err := s.resolveSchema(sch.Ref)
+		if err != nil {
+			return err
+		}
+		return s.validate(target, value, path)
+	}
+
+	for _, member := range sch.AllOf {
+		if err := s.validate(member, value, path); err != nil {
+			return err
+		}
+	}
+	if len(sch.AnyOf) > 0 {
+		var first error
+		for _, member := range sch.AnyOf {
+			err := s.validate(member, value, path)
+			if err == nil {
+				first = nil
+				break
+			}
+			if first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return first
+		}
+	}
+
+	if len(sch.Type) > 0 && !hasType(sch.Type, value) {
+		return invalid(path, "must be %s, got %s", strings.Join(sch.Type, " or "), typeName(value))
+	}
+	if len(sch.Enum) > 0 && !inEnum(sch.Enum, value) {
+		return invalid(path, "must be one of %s", enumList(sch.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		return validateString(sch, v, path)
+	case json.Number:
+		return validateNumber(sch, v, path)
+	case []interface{}:
+		if sch.MinItems != nil && len(v) < *sch.MinItems {
+			return invalid(path, "must have at least %d items", *sch.MinItems)
+		}
+		if sch.MaxItems != nil && len(v) > *sch.MaxItems {
+			return invalid(path, "must have at most %d items", *sch.MaxItems)
+		}
+		for i, item := range v {
+			if err := s.validate(sch.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range sch.Required {
+			if _, ok := v[name]; !ok {
+				return invalid(path+"."+name, "is required")
+			}
+		}
+		// Visit members in order so the same document reports the same error
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := sch.Properties[name]
+			if !ok {
+				if sch.AdditionalProperties != nil && !*sch.AdditionalProperties {
+					return invalid(path+"."+name, "is not a known field")
+				}
+				continue
+			}
+			if err := s.validate(property, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateString(sch *schema, v, path string) error {
+	if sch.MinLength != nil && len([]rune(v)) < *sch.MinLength {
+		return invalid(path, "must be at least %d characters", *sch.MinLength)
+	}
+	if sch.MaxLength != nil && len([]rune(v)) > *sch.MaxLength {
+		return invalid(path, "must be at most %d characters", *sch.MaxLength)
+	}
+
+	switch sch.Format {
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return invalid(path, "must be a date in the form YYYY-MM-DD")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return invalid(path, "must be an RFC 3339 date and time")
+		}
+	}
+	return nil
+}
+
+func validateNumber(sch *schema, v json.Number, path string) error {
+	n, err := v.Float64()
+	if err != nil {
+		return invalid(path, "must be a number")
+	}
+	if sch.Minimum != nil && n < *sch.Minimum {
+		return invalid(path, "must be at least %g", *sch.Minimum)
+	}
+	if sch.ExclusiveMinimum != nil && n <= *sch.ExclusiveMinimum {
+		return invalid(path, "must be greater than %g", *sch.ExclusiveMinimum)
+	}
+	if sch.Maximum != nil && n > *sch.Maximum {
+		return invalid(path, "must be at most %g", *sch.Maximum)
+	}
+	return nil
+}
+
+func hasType(types schemaTypes, value interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(json.Number); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(json.Number); ok {
+				if _, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+					return true
+				}
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// inEnum compares a value with enum members decoded from the document, where
+// numbers are float64
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, member := range enum {
+		switch m := member.(type) {
+		case float64:
+			if n, ok := value.(json.Number); ok {
+				if f, err := n.Float64(); err == nil && f == m {
+					return true
+				}
+			}
+		default:
+			if member == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	names := make([]string, len(enum))
+	for i, member := range enum {
+		names[i] = fmt.Sprint(member)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,213 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedMediaType is returned for request bodies in a media type the
+// operation does not accept
+var ErrUnsupportedMediaType = errors.New("openapi: unsupported media type")
+
+// ErrRequestTooLarge is returned for JSON request bodies over MaxBodyBytes
+var ErrRequestTooLarge = errors.New("openapi: request body too large")
+
+// MaxBodyBytes is the most of a JSON request body that is read to validate it
+const MaxBodyBytes = 1 << 20
+
+// ValidateRequest checks a request's path and query parameters and its body
+// against the operation serving it. Requests for paths the document does not
+// describe pass, leaving the router to answer them. JSON bodies are read, up
+// to MaxBodyBytes, and replaced so the handler can still decode them.
+func (s *Spec) ValidateRequest(r *http.Request) error {
+	route, pathParams := s.match(r.Method, r.URL.Path)
+	if route == nil {
+		return nil
+	}
+	op := route.operation
+
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			if err := s.validate(p.Schema, s.coerce(p.Schema, pathParams[p.Name]), "path."+p.Name); err != nil {
+				return err
+			}
+		case "query":
+			values, ok := query[p.Name]
+			if !ok || (len(values) == 1 && values[0] == "") {
+				if p.Required {
+					return invalid("query."+p.Name, "is required")
+				}
+				continue
+			}
+			if err := s.validateQuery(p, values); err != nil {
+				return err
+			}
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	return s.validateRequestBody(r, op.RequestBody)
+}
+
+// validateQuery checks the values of a query parameter. Array parameters may
+// be repeated; others use their first value as the handlers do.
+func (s *Spec) validateQuery(p *parameter, values []string) error {
+	sch := p.Schema
+	if sch != nil && sch.Ref != "" {
+		resolved, err := s.resolveSchema(sch.Ref)
+		if err != nil {
+			return err
+		}
+		sch = resolved
+	}
+
+	if sch != nil && sch.Items != nil {
+		items := make([]interface{}, len(values))
+		for i, value := range values {
+			items[i] = s.coerce(sch.Items, value)
+		}
+		return s.validate(sch, items, "query."+p.Name)
+	}
+	return s.validate(sch, s.coerce(sch, values[0]), "query."+p.Name)
+}
+
+func (s *Spec) validateRequestBody(r *http.Request, body *requestBody) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		// The handlers decode JSON whatever the request says it holds
+		mediaType = "application/json"
+	}
+	media, ok := body.Content[mediaType]
+	if ok && !isJSON(mediaType) {
+		// Uploads are left unread for the handler, which limits them itself
+		return nil
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		return invalid("body", "could not be read")
+	}
+	if len(content) > MaxBodyBytes {
+		return ErrRequestTooLarge
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(content))
+
+	if len(bytes.TrimSpace(content)) == 0 {
+		if body.Required {
+			return invalid("body", "is required")
+		}
+		return nil
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+
+	value, err := decodeJSON(content)
+	if err != nil {
+		return invalid("body", "is not valid JSON")
+	}
+	return s.validate(media.Schema, value, "body")
+}
+
+// ValidateResponse checks a response a handler wrote for a request to path
+// against the responses the operation declares
+func (s *Spec) ValidateResponse(method, path string, status int, header http.Header, body []byte) error {
+	route, _ := s.match(method, path)
+	if route == nil {
+		return nil
+	}
+
+	resp := route.operation.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = route.operation.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if resp == nil {
+		resp = route.operation.Responses["default"]
+	}
+	if resp == nil {
+		return invalid("response", "status %d is not documented for %s", status, route.key())
+	}
+
+	if len(resp.Content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return invalid("response", "status %d should not have a body", status)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return invalid("response", "has no Content-Type")
+	}
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return invalid("response", "Content-Type %s is not documented for status %d", mediaType, status)
+	}
+	if !isJSON(mediaType) {
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return invalid("response", "is not valid JSON")
+	}
+	return s.validate(media.Schema, value, "response")
+}
+
+// coerce converts a path or query string to the JSON value its schema
+// describes, leaving it a string when it does not parse so validation
+// reports the mismatch
+func (s *Spec) coerce(sch *schema, value string) interface{} {
+	if sch != nil && sch.Ref != "" {
+		if resolved, err := s.resolveSchema(sch.Ref); err == nil {
+			sch = resolved
+		}
+	}
+	if sch == nil {
+		return value
+	}
+
+	for _, t := range sch.Type {
+		switch t {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return json.Number(value)
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				return b
+			}
+		}
+	}
+	return value
+}
+
+func decodeJSON(content []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return value, nil
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "http://example.com/files", []byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	body := []byte("fake image")
+	if err := store.Put(ctx, "exercises/1/a.png", bytes.NewReader(body), int64(len(body)), "image/png"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put(ctx, "exercises/1/b.png", bytes.NewReader(body), 99, "image/png"); err == nil {
+		t.Error("Put should fail when the size does not match")
+	}
+	if err := store.Put(ctx, "../escape.png", bytes.NewReader(body), int64(len(body)), "image/png"); err != ErrInvalidKey {
+		t.Errorf("Put outside the root: got %v want %v", err, ErrInvalidKey)
+	}
+
+	link, err := store.SignedURL("exercises/1/a.png", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL failed: %v", err)
+	}
+	if !strings.HasPrefix(link, "http://example.com/files/exercises/1/a.png?") {
+		t.Fatalf("wrong link: %s", link)
+	}
+
+	serve := func(link string) *httptest.ResponseRecorder {
+		path := strings.TrimPrefix(link, "http://example.com/files")
+		rr := httptest.NewRecorder()
+		http.StripPrefix("/files", store).ServeHTTP(rr, httptest.NewRequest("GET", "/files"+path, nil))
+		return rr
+	}
+
+	rr := serve(link)
+	if rr.Code != http.StatusOK || rr.Body.String() != "fake image" {
+		t.Errorf("signed link not served: %v %s", rr.Code, rr.Body.String())
+	}
+
+	// Links cannot be reused for other keys or after they expire
+	if rr := serve(strings.Replace(link, "a.png", "b.png", 1)); rr.Code != http.StatusForbidden {
+		t.Errorf("tampered link: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+	now = now.Add(2 * time.Minute)
+	if rr := serve(link); rr.Code != http.StatusForbidden {
+		t.Errorf("expired link: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	if err := store.Delete(ctx, "exercises/1/a.png"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "exercises/1/a.png"); err != ErrNotFound {
+		t.Errorf("Get after delete: got %v want %v", err, ErrNotFound)
+	}
+}
+
+func TestNewWithoutSecret(t *testing.T) {
+	store, err := New(Config{LocalDir: t.TempDir()})
+	if err == nil {
+		t.Fatal("New should fail without a signing secret")
+	}
+	// A nil *LocalStore inside the interface would pass a nil check
+	if store != nil {
+		t.Errorf("New returned %#v with its error, want nil", store)
+	}
+}
@@ -0,0 +1,290 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unsignedPayload lets requests stream bodies without hashing them first
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// maxPresignTTL is the longest S3 accepts for a presigned URL
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// S3Store keeps blobs in a bucket of an S3-compatible service, such as AWS
+// S3 or MinIO, signing requests with Signature Version 4. Requests are
+// path-style: the bucket is the first segment of the path.
+type S3Store struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewS3Store creates a store for bucket at endpoint, such as
+// https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+func NewS3Store(endpoint, region, bucket, accessKeyID, secretAccessKey string) (*S3Store, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("storage: invalid S3 endpoint")
+	}
+	if region == "" || bucket == "" || accessKeyID == "" || secretAccessKey == "" {
+		return nil, errors.New("storage: an S3 store needs a region, bucket and credentials")
+	}
+	return &S3Store{
+		endpoint:        u,
+		region:          region,
+		bucket:          bucket,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		client:          &http.Client{Timeout: 5 * time.Minute},
+		now:             time.Now,
+	}, nil
+}
+
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	u.RawQuery = ""
+	return &u
+}
+
+func (s *S3Store) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	signRequest(req, unsignedPayload, s.accessKeyID, s.secretAccessKey, s.region, s.now())
+	return s.client.Do(req)
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, body, size, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp)
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Error(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return s3Error(resp)
+}
+
+func (s *S3Store) SignedURL(key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	if ttl <= 0 || ttl > maxPresignTTL {
+		return "", errors.New("storage: presigned URLs must expire within 7 days")
+	}
+	u := presignURL(http.MethodGet, s.objectURL(key), s.accessKeyID, s.secretAccessKey, s.region, s.now(), ttl)
+	return u.String(), nil
+}
+
+// s3Error turns an unsuccessful response into an error
+func s3Error(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("storage: S3 responded %s: %s", resp.Status, strings.TrimSpace(string(message)))
+}
+
+// signRequest adds Signature Version 4 headers to req, signing the host,
+// every x-amz-* header and any Content-Type and Range
+func signRequest(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "range" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(headers)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := credentialScope(t, region)
+	signature := signature(secretAccessKey, t, region, stringToSign(amzDate, scope, canonicalRequest))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// presignURL returns u with Signature Version 4 query parameters that allow
+// method on it without credentials until ttl has passed
+func presignURL(method string, u *url.URL, accessKeyID, secretAccessKey, region string, t time.Time, ttl time.Duration) *url.URL {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	scope := credentialScope(t, region)
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", accessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(map[string]string{"host": u.Host})
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI(u),
+		canonicalQuery(query),
+		canonicalHeaders,
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", signature(secretAccessKey, t, region, stringToSign(amzDate, scope, canonicalRequest)))
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(query)
+	return &signed
+}
+
+func credentialScope(t time.Time, region string) string {
+	return t.UTC().Format("20060102") + "/" + region + "/s3/aws4_request"
+}
+
+func stringToSign(amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	return "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+}
+
+func signature(secretAccessKey string, t time.Time, region, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), t.UTC().Format("20060102"))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalizeHeaders returns the signed header list and the canonical
+// header block of lowercase headers
+func canonicalizeHeaders(headers map[string]string) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var block strings.Builder
+	for _, name := range names {
+		block.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return strings.Join(names, ";"), block.String()
+}
+
+// canonicalURI encodes each segment of the path once, as S3 expects
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts and encodes query parameters
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
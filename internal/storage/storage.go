@@ -0,0 +1,103 @@
+// Package storage keeps uploaded files in a blob store and hands out signed,
+// expiring links to download them.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Drivers select the Store built by New
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Store keeps blobs under slash-separated keys
+type Store interface {
+	// Put stores size bytes read from body under key, replacing any blob
+	// already there
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+
+	// Get opens the blob under key, returning ErrNotFound when there is none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// SignedURL returns a link that downloads the blob under key until ttl
+	// has passed
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// Config describes the store to build
+type Config struct {
+	Driver string // DriverLocal (default) or DriverS3
+
+	// Local stores keep blobs under LocalDir and serve them from BaseURL,
+	// signing links with Secret
+	LocalDir string
+	BaseURL  string
+	Secret   string
+
+	// S3-compatible stores use path-style requests to Endpoint
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+}
+
+// New builds the store described by cfg. On error the Store is nil rather
+// than a nil pointer of the driver's type, so callers can test it.
+func New(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		s, err := NewLocalStore(cfg.LocalDir, cfg.BaseURL, []byte(cfg.Secret))
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case DriverS3:
+		s, err := NewS3Store(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKeyID, cfg.S3SecretAccessKey)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, errors.New("storage: unknown driver " + cfg.Driver)
+}
+
+// NewKey returns a fresh random key under prefix ending in ext, such as
+// "exercises/12/3f9a...c1.jpg"
+func NewKey(prefix, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + hex.EncodeToString(b) + ext, nil
+}
+
+// validKey reports whether key is a relative, slash-separated path without
+// empty, "." or ".." segments
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
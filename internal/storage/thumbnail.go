@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// Decoders for the image formats thumbnails can be made from
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxThumbnailSourcePixels bounds the images thumbnails are made from, so a
+// small file cannot decode to an enormous bitmap
+const maxThumbnailSourcePixels = 50_000_000
+
+var ErrImageTooLarge = errors.New("storage: image too large")
+
+// Thumbnail decodes a JPEG, PNG or GIF image and returns a JPEG scaled to fit
+// within maxSide pixels on each side, along with the dimensions of the
+// original. Images already small enough are re-encoded at their own size.
+func Thumbnail(src io.ReadSeeker, maxSide int) ([]byte, image.Point, error) {
+	config, _, err := image.DecodeConfig(src)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	size := image.Pt(config.Width, config.Height)
+	if size.X*size.Y > maxThumbnailSourcePixels {
+		return nil, size, ErrImageTooLarge
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, size, err
+	}
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return nil, size, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(img, maxSide), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, size, err
+	}
+	return buf.Bytes(), size, nil
+}
+
+// scaleDown shrinks img to fit within maxSide, averaging the source pixels
+// under each thumbnail pixel
+func scaleDown(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+
+	tw, th := maxSide, h*maxSide/w
+	if h > w {
+		tw, th = w*maxSide/h, maxSide
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := bounds.Min.Y+ty*h/th, bounds.Min.Y+(ty+1)*h/th
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := bounds.Min.X+tx*w/tw, bounds.Min.X+(tx+1)*w/tw
+
+			var r, g, b, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := img.At(x, y).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			thumb.Set(tx, ty, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return thumb
+}
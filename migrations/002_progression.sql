@@ -0,0 +1,8 @@
+-- migrations/002_progression.sql
+
+-- Optional rate of perceived exertion for each logged entry, used by
+-- RPE-based progression suggestions
+ALTER TABLE workout_exercises ADD COLUMN rpe REAL;
+
+CREATE INDEX idx_workout_exercises_exercise ON workout_exercises(exercise_id);
+CREATE INDEX idx_workouts_user_date ON workouts(user_id, date);
@@ -0,0 +1,26 @@
+-- migrations/003_personal_records.sql
+
+-- Personal records. Every time a record is broken a new row is added, so the
+-- table doubles as the PR history; the current record is the highest value
+-- per (user, exercise, record_type[, weight]).
+CREATE TABLE personal_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    exercise_id INTEGER NOT NULL,
+    record_type TEXT NOT NULL,
+    value REAL NOT NULL,
+    previous_value REAL,
+    weight REAL,
+    reps INTEGER,
+    workout_id INTEGER NOT NULL,
+    workout_exercise_id INTEGER,
+    achieved_on DATE NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (exercise_id) REFERENCES exercises(id),
+    FOREIGN KEY (workout_id) REFERENCES workouts(id),
+    FOREIGN KEY (workout_exercise_id) REFERENCES workout_exercises(id)
+);
+
+CREATE INDEX idx_personal_records_user_exercise ON personal_records(user_id, exercise_id);
+CREATE INDEX idx_personal_records_workout ON personal_records(workout_id);
@@ -0,0 +1,4 @@
+-- migrations/004_training_stats.sql
+
+-- Optional session length, used for average session length statistics
+ALTER TABLE workouts ADD COLUMN duration_minutes INTEGER;
@@ -0,0 +1,18 @@
+-- migrations/005_workout_exercise_order.sql
+
+-- Explicit ordering and grouping (supersets, circuits, giant sets) of the
+-- exercises within a workout
+ALTER TABLE workout_exercises ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE workout_exercises ADD COLUMN group_id INTEGER;
+ALTER TABLE workout_exercises ADD COLUMN group_type TEXT;
+
+-- Existing entries keep the order they were inserted in
+UPDATE workout_exercises
+SET position = (
+    SELECT COUNT(*)
+    FROM workout_exercises earlier
+    WHERE earlier.workout_id = workout_exercises.workout_id
+      AND earlier.id < workout_exercises.id
+);
+
+CREATE INDEX idx_workout_exercises_workout_position ON workout_exercises(workout_id, position);
@@ -0,0 +1,18 @@
+-- migrations/006_tracking_types.sql
+
+-- What is logged for each set of an exercise: weight_reps, bodyweight_reps,
+-- assisted_bodyweight, duration, weight_duration or distance_duration
+ALTER TABLE exercises ADD COLUMN tracking_type TEXT NOT NULL DEFAULT 'weight_reps';
+
+UPDATE exercises SET tracking_type = 'bodyweight_reps'
+WHERE name IN ('Push-ups', 'Pull-ups', 'Crunches');
+
+-- Timed and distance based entries. Reps are 0 for entries that do not count them.
+ALTER TABLE workout_exercises ADD COLUMN duration_seconds INTEGER;
+ALTER TABLE workout_exercises ADD COLUMN distance_meters REAL;
+
+INSERT INTO exercises (name, description, body_part_id, tracking_type) VALUES
+    ('Plank', 'Forearm plank hold', 6, 'duration'),
+    ('Farmer''s Carry', 'Walk holding a heavy dumbbell in each hand', 5, 'weight_duration'),
+    ('Running', 'Outdoor or treadmill run', 3, 'distance_duration'),
+    ('Rowing', 'Rowing machine intervals', 2, 'distance_duration');
@@ -0,0 +1,12 @@
+-- migrations/007_weight_units.sql
+
+-- workout_exercises.weight holds the canonical weight in kilograms. The value
+-- and unit the user entered are kept so history stays exact when they switch
+-- units. Existing weights are assumed to have been entered in kilograms.
+ALTER TABLE workout_exercises ADD COLUMN weight_unit TEXT NOT NULL DEFAULT 'kg';
+ALTER TABLE workout_exercises ADD COLUMN weight_input REAL;
+
+UPDATE workout_exercises SET weight_input = weight;
+
+-- Unit responses are converted to unless a request asks for another
+ALTER TABLE users ADD COLUMN preferred_unit TEXT NOT NULL DEFAULT 'kg';
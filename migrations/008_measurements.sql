@@ -0,0 +1,18 @@
+-- migrations/008_measurements.sql
+
+-- Body measurements logged by a user. Values are stored in a canonical unit
+-- per metric: kg for body weight, percent for body fat and cm for tape
+-- measurements.
+CREATE TABLE measurements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    metric TEXT NOT NULL,
+    value REAL NOT NULL,
+    measured_on DATE NOT NULL,
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_measurements_user_metric_date ON measurements(user_id, metric, measured_on);
@@ -0,0 +1,34 @@
+-- migrations/009_equipment_inventory.sql
+
+-- The equipment a user has available for loading weights. All weights of an
+-- inventory are in its unit, since plates are made in either kg or lb.
+CREATE TABLE equipment_inventories (
+    user_id INTEGER PRIMARY KEY,
+    unit TEXT NOT NULL DEFAULT 'kg',
+    collar_weight REAL NOT NULL DEFAULT 0,
+    dumbbell_increment REAL NOT NULL DEFAULT 2,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE inventory_bars (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    weight REAL NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES equipment_inventories(user_id)
+);
+
+-- Plates are counted in pairs so both sides of a bar can be loaded evenly
+CREATE TABLE inventory_plates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    weight REAL NOT NULL,
+    pairs INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES equipment_inventories(user_id)
+);
+
+CREATE INDEX idx_inventory_bars_user ON inventory_bars(user_id);
+CREATE INDEX idx_inventory_plates_user ON inventory_plates(user_id);
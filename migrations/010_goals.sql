@@ -0,0 +1,23 @@
+-- migrations/010_goals.sql
+
+-- Training goals. Weight targets are stored in kg; frequency targets are
+-- workouts per week and relative strength targets are bodyweight multiples.
+CREATE TABLE goals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    goal_type TEXT NOT NULL,
+    exercise_id INTEGER,
+    target_value REAL NOT NULL,
+    deadline DATE,
+    status TEXT NOT NULL DEFAULT 'active',
+    achieved_on DATE,
+    achieved_workout_id INTEGER,
+    notes TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (exercise_id) REFERENCES exercises(id),
+    FOREIGN KEY (achieved_workout_id) REFERENCES workouts(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_goals_user_status ON goals(user_id, status);
@@ -0,0 +1,8 @@
+-- migrations/011_workout_status.sql
+
+-- Workouts are either completed or planned ahead of time. Only completed
+-- workouts count towards records, statistics and goals.
+ALTER TABLE workouts ADD COLUMN status TEXT NOT NULL DEFAULT 'completed';
+
+-- Workouts per week a user aims for; NULL when they have not set one
+ALTER TABLE users ADD COLUMN weekly_target INTEGER;
@@ -0,0 +1,5 @@
+-- migrations/012_session_rpe.sql
+
+-- Overall effort of a workout on the 1-10 RPE scale, used to weight its
+-- training load
+ALTER TABLE workouts ADD COLUMN session_rpe REAL;
@@ -0,0 +1,13 @@
+-- migrations/013_workout_tags.sql
+
+-- Free-form labels on workouts such as "deload" or "competition prep". Tags are
+-- stored trimmed and lowercased so filters match regardless of how they were
+-- typed.
+CREATE TABLE workout_tags (
+    workout_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (workout_id, tag),
+    FOREIGN KEY (workout_id) REFERENCES workouts(id)
+);
+
+CREATE INDEX idx_workout_tags_tag ON workout_tags(tag);
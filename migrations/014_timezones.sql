@@ -0,0 +1,11 @@
+-- migrations/014_timezones.sql
+
+-- The IANA timezone a user lives in. It decides where "today" falls in
+-- statistics, calendars and goals, and is the default timezone of new workouts.
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+-- Workout dates are local calendar dates in the workout's timezone. The
+-- optional start and end are exact instants stored in UTC.
+ALTER TABLE workouts ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE workouts ADD COLUMN started_at DATETIME;
+ALTER TABLE workouts ADD COLUMN ended_at DATETIME;
@@ -0,0 +1,103 @@
+-- migrations/015_muscles.sql
+
+-- Individual muscles, grouped under the coarse body parts
+CREATE TABLE muscles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    body_part_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (body_part_id) REFERENCES body_parts(id)
+);
+
+-- The muscles an exercise works. Role is primary, secondary or stabilizer;
+-- contribution is the share of each set credited to the muscle, defaulting
+-- by role when NULL.
+CREATE TABLE exercise_muscles (
+    exercise_id INTEGER NOT NULL,
+    muscle_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    contribution REAL,
+    PRIMARY KEY (exercise_id, muscle_id),
+    FOREIGN KEY (exercise_id) REFERENCES exercises(id),
+    FOREIGN KEY (muscle_id) REFERENCES muscles(id)
+);
+
+CREATE INDEX idx_exercise_muscles_muscle ON exercise_muscles(muscle_id);
+
+INSERT INTO muscles (name, body_part_id)
+SELECT m.name, bp.id
+FROM (
+    SELECT 'Chest' AS body_part, 'Pectorals' AS name
+    UNION ALL SELECT 'Back', 'Latissimus Dorsi'
+    UNION ALL SELECT 'Back', 'Trapezius'
+    UNION ALL SELECT 'Back', 'Rhomboids'
+    UNION ALL SELECT 'Back', 'Erector Spinae'
+    UNION ALL SELECT 'Legs', 'Quadriceps'
+    UNION ALL SELECT 'Legs', 'Hamstrings'
+    UNION ALL SELECT 'Legs', 'Glutes'
+    UNION ALL SELECT 'Legs', 'Calves'
+    UNION ALL SELECT 'Shoulders', 'Front Deltoids'
+    UNION ALL SELECT 'Shoulders', 'Side Deltoids'
+    UNION ALL SELECT 'Shoulders', 'Rear Deltoids'
+    UNION ALL SELECT 'Arms', 'Biceps'
+    UNION ALL SELECT 'Arms', 'Triceps'
+    UNION ALL SELECT 'Arms', 'Forearms'
+    UNION ALL SELECT 'Core', 'Abdominals'
+    UNION ALL SELECT 'Core', 'Obliques'
+) m
+JOIN body_parts bp ON bp.name = m.body_part;
+
+-- Body parts added by users get a muscle of the same name
+INSERT INTO muscles (name, body_part_id)
+SELECT name, id FROM body_parts
+WHERE id NOT IN (SELECT body_part_id FROM muscles)
+  AND name NOT IN (SELECT name FROM muscles);
+
+-- Muscles worked by the built-in exercises
+INSERT INTO exercise_muscles (exercise_id, muscle_id, role)
+SELECT e.id, mu.id, m.role
+FROM (
+    SELECT 'Bench Press' AS exercise, 'Pectorals' AS muscle, 'primary' AS role
+    UNION ALL SELECT 'Bench Press', 'Triceps', 'secondary'
+    UNION ALL SELECT 'Bench Press', 'Front Deltoids', 'secondary'
+    UNION ALL SELECT 'Push-ups', 'Pectorals', 'primary'
+    UNION ALL SELECT 'Push-ups', 'Triceps', 'secondary'
+    UNION ALL SELECT 'Push-ups', 'Abdominals', 'stabilizer'
+    UNION ALL SELECT 'Deadlift', 'Erector Spinae', 'primary'
+    UNION ALL SELECT 'Deadlift', 'Glutes', 'primary'
+    UNION ALL SELECT 'Deadlift', 'Hamstrings', 'primary'
+    UNION ALL SELECT 'Deadlift', 'Trapezius', 'secondary'
+    UNION ALL SELECT 'Deadlift', 'Forearms', 'secondary'
+    UNION ALL SELECT 'Pull-ups', 'Latissimus Dorsi', 'primary'
+    UNION ALL SELECT 'Pull-ups', 'Biceps', 'secondary'
+    UNION ALL SELECT 'Pull-ups', 'Rhomboids', 'secondary'
+    UNION ALL SELECT 'Squats', 'Quadriceps', 'primary'
+    UNION ALL SELECT 'Squats', 'Glutes', 'primary'
+    UNION ALL SELECT 'Squats', 'Hamstrings', 'secondary'
+    UNION ALL SELECT 'Squats', 'Erector Spinae', 'stabilizer'
+    UNION ALL SELECT 'Shoulder Press', 'Front Deltoids', 'primary'
+    UNION ALL SELECT 'Shoulder Press', 'Side Deltoids', 'secondary'
+    UNION ALL SELECT 'Shoulder Press', 'Triceps', 'secondary'
+    UNION ALL SELECT 'Bicep Curls', 'Biceps', 'primary'
+    UNION ALL SELECT 'Bicep Curls', 'Forearms', 'secondary'
+    UNION ALL SELECT 'Crunches', 'Abdominals', 'primary'
+    UNION ALL SELECT 'Plank', 'Abdominals', 'primary'
+    UNION ALL SELECT 'Plank', 'Obliques', 'secondary'
+    UNION ALL SELECT 'Farmer''s Carry', 'Forearms', 'primary'
+    UNION ALL SELECT 'Farmer''s Carry', 'Trapezius', 'secondary'
+    UNION ALL SELECT 'Running', 'Quadriceps', 'primary'
+    UNION ALL SELECT 'Running', 'Calves', 'secondary'
+    UNION ALL SELECT 'Rowing', 'Latissimus Dorsi', 'primary'
+    UNION ALL SELECT 'Rowing', 'Quadriceps', 'secondary'
+) m
+JOIN exercises e ON e.name = m.exercise
+JOIN muscles mu ON mu.name = m.muscle;
+
+-- Every other exercise keeps its body part as its primary target, through the
+-- first muscle of that body part
+INSERT INTO exercise_muscles (exercise_id, muscle_id, role)
+SELECT e.id, (SELECT MIN(id) FROM muscles WHERE body_part_id = e.body_part_id), 'primary'
+FROM exercises e
+WHERE e.id NOT IN (SELECT exercise_id FROM exercise_muscles)
+  AND EXISTS (SELECT 1 FROM muscles WHERE body_part_id = e.body_part_id);
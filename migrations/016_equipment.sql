@@ -0,0 +1,66 @@
+-- migrations/016_equipment.sql
+
+-- Equipment an exercise may need. Exercises without equipment need nothing
+-- beyond the athlete's bodyweight.
+CREATE TABLE equipment (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every piece of equipment listed for an exercise is required to perform it
+CREATE TABLE exercise_equipment (
+    exercise_id INTEGER NOT NULL,
+    equipment_id INTEGER NOT NULL,
+    PRIMARY KEY (exercise_id, equipment_id),
+    FOREIGN KEY (exercise_id) REFERENCES exercises(id),
+    FOREIGN KEY (equipment_id) REFERENCES equipment(id)
+);
+
+-- Places a user trains and the equipment available at each
+CREATE TABLE gym_profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE gym_equipment (
+    gym_id INTEGER NOT NULL,
+    equipment_id INTEGER NOT NULL,
+    PRIMARY KEY (gym_id, equipment_id),
+    FOREIGN KEY (gym_id) REFERENCES gym_profiles(id),
+    FOREIGN KEY (equipment_id) REFERENCES equipment(id)
+);
+
+INSERT INTO equipment (name) VALUES
+    ('Barbell'),
+    ('Dumbbell'),
+    ('Kettlebell'),
+    ('Bench'),
+    ('Squat Rack'),
+    ('Pull-up Bar'),
+    ('Cable Machine'),
+    ('Resistance Band'),
+    ('Treadmill'),
+    ('Rowing Machine');
+
+INSERT INTO exercise_equipment (exercise_id, equipment_id)
+SELECT e.id, eq.id
+FROM (
+    SELECT 'Bench Press' AS exercise, 'Barbell' AS equipment
+    UNION ALL SELECT 'Bench Press', 'Bench'
+    UNION ALL SELECT 'Deadlift', 'Barbell'
+    UNION ALL SELECT 'Pull-ups', 'Pull-up Bar'
+    UNION ALL SELECT 'Squats', 'Barbell'
+    UNION ALL SELECT 'Squats', 'Squat Rack'
+    UNION ALL SELECT 'Shoulder Press', 'Barbell'
+    UNION ALL SELECT 'Bicep Curls', 'Dumbbell'
+    UNION ALL SELECT 'Farmer''s Carry', 'Dumbbell'
+    UNION ALL SELECT 'Rowing', 'Rowing Machine'
+) m
+JOIN exercises e ON e.name = m.exercise
+JOIN equipment eq ON eq.name = m.equipment;
@@ -0,0 +1,91 @@
+-- migrations/017_exercise_aliases.sql
+
+-- The movement an exercise trains, used to suggest substitutes:
+-- horizontal_push, vertical_push, horizontal_pull, vertical_pull, squat,
+-- hinge, lunge, carry, core, isolation or locomotion
+ALTER TABLE exercises ADD COLUMN movement_pattern TEXT;
+
+-- The exercise this one is a variation of, such as incline dumbbell press of
+-- bench press
+ALTER TABLE exercises ADD COLUMN variation_of INTEGER REFERENCES exercises(id);
+
+CREATE INDEX idx_exercises_variation_of ON exercises(variation_of);
+
+-- Other names an exercise is searched by
+CREATE TABLE exercise_aliases (
+    exercise_id INTEGER NOT NULL,
+    alias TEXT NOT NULL COLLATE NOCASE,
+    PRIMARY KEY (exercise_id, alias),
+    FOREIGN KEY (exercise_id) REFERENCES exercises(id)
+);
+
+CREATE INDEX idx_exercise_aliases_alias ON exercise_aliases(alias);
+
+UPDATE exercises SET movement_pattern = CASE name
+    WHEN 'Bench Press' THEN 'horizontal_push'
+    WHEN 'Push-ups' THEN 'horizontal_push'
+    WHEN 'Deadlift' THEN 'hinge'
+    WHEN 'Pull-ups' THEN 'vertical_pull'
+    WHEN 'Squats' THEN 'squat'
+    WHEN 'Shoulder Press' THEN 'vertical_push'
+    WHEN 'Bicep Curls' THEN 'isolation'
+    WHEN 'Crunches' THEN 'core'
+    WHEN 'Plank' THEN 'core'
+    WHEN 'Farmer''s Carry' THEN 'carry'
+    WHEN 'Running' THEN 'locomotion'
+    WHEN 'Rowing' THEN 'locomotion'
+END;
+
+-- Common variations of the built-in lifts
+INSERT INTO exercises (name, description, body_part_id, tracking_type, movement_pattern, variation_of)
+SELECT v.name, v.description, e.body_part_id, 'weight_reps', v.pattern, e.id
+FROM (
+    SELECT 'Romanian Deadlift' AS name, 'Stiff-legged barbell hinge from the hips' AS description,
+           'Deadlift' AS parent, 'hinge' AS pattern
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Pressing dumbbells on an incline bench',
+           'Bench Press', 'horizontal_push'
+) v
+JOIN exercises e ON e.name = v.parent;
+
+INSERT INTO exercise_muscles (exercise_id, muscle_id, role)
+SELECT e.id, mu.id, m.role
+FROM (
+    SELECT 'Romanian Deadlift' AS exercise, 'Hamstrings' AS muscle, 'primary' AS role
+    UNION ALL SELECT 'Romanian Deadlift', 'Glutes', 'primary'
+    UNION ALL SELECT 'Romanian Deadlift', 'Erector Spinae', 'secondary'
+    UNION ALL SELECT 'Romanian Deadlift', 'Forearms', 'secondary'
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Pectorals', 'primary'
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Front Deltoids', 'secondary'
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Triceps', 'secondary'
+) m
+JOIN exercises e ON e.name = m.exercise
+JOIN muscles mu ON mu.name = m.muscle;
+
+INSERT INTO exercise_equipment (exercise_id, equipment_id)
+SELECT e.id, eq.id
+FROM (
+    SELECT 'Romanian Deadlift' AS exercise, 'Barbell' AS equipment
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Dumbbell'
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Bench'
+) m
+JOIN exercises e ON e.name = m.exercise
+JOIN equipment eq ON eq.name = m.equipment;
+
+INSERT INTO exercise_aliases (exercise_id, alias)
+SELECT e.id, a.alias
+FROM (
+    SELECT 'Bench Press' AS exercise, 'Flat Bench' AS alias
+    UNION ALL SELECT 'Bench Press', 'Barbell Bench Press'
+    UNION ALL SELECT 'Push-ups', 'Press-up'
+    UNION ALL SELECT 'Deadlift', 'Conventional Deadlift'
+    UNION ALL SELECT 'Pull-ups', 'Chin-up'
+    UNION ALL SELECT 'Squats', 'Back Squat'
+    UNION ALL SELECT 'Shoulder Press', 'Overhead Press'
+    UNION ALL SELECT 'Shoulder Press', 'OHP'
+    UNION ALL SELECT 'Shoulder Press', 'Military Press'
+    UNION ALL SELECT 'Bicep Curls', 'Dumbbell Curl'
+    UNION ALL SELECT 'Romanian Deadlift', 'RDL'
+    UNION ALL SELECT 'Romanian Deadlift', 'Stiff-Leg Deadlift'
+    UNION ALL SELECT 'Incline Dumbbell Press', 'Incline DB Press'
+) a
+JOIN exercises e ON e.name = a.exercise;
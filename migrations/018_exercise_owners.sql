@@ -0,0 +1,9 @@
+-- migrations/018_exercise_owners.sql
+
+-- Exercises without an owner make up the built-in catalog. Users own the
+-- exercises they create, which are private unless made global, in which case
+-- everyone sees them but only the owner can change them.
+ALTER TABLE exercises ADD COLUMN owner_user_id INTEGER REFERENCES users(id);
+ALTER TABLE exercises ADD COLUMN visibility TEXT NOT NULL DEFAULT 'global';
+
+CREATE INDEX idx_exercises_owner ON exercises(owner_user_id);
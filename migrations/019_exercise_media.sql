@@ -0,0 +1,21 @@
+-- migrations/019_exercise_media.sql
+
+-- Demo images and form videos attached to exercises. The files live in the
+-- blob store under storage_key; images also get a JPEG thumbnail.
+CREATE TABLE exercise_media (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    exercise_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    storage_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (exercise_id) REFERENCES exercises(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX idx_exercise_media_exercise ON exercise_media(exercise_id);
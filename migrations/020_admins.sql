@@ -0,0 +1,5 @@
+-- migrations/020_admins.sql
+
+-- Admins curate the shared exercise catalog, such as merging duplicates.
+-- There is no endpoint to grant the flag; set it in the database.
+ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- migrations/021_body_part_tree.sql
+
+-- Body parts form a tree: regions such as Upper Body contain groups such as
+-- Chest, which may contain sub-groups such as Upper Chest. Top-level body
+-- parts have no parent.
+ALTER TABLE body_parts ADD COLUMN parent_id INTEGER REFERENCES body_parts(id);
+
+CREATE INDEX idx_body_parts_parent ON body_parts(parent_id);
+
+INSERT INTO body_parts (name)
+SELECT region FROM (SELECT 'Upper Body' AS region UNION ALL SELECT 'Lower Body')
+WHERE region NOT IN (SELECT name FROM body_parts);
+
+UPDATE body_parts
+SET parent_id = (SELECT id FROM body_parts WHERE name = 'Upper Body')
+WHERE name IN ('Chest', 'Back', 'Shoulders', 'Arms');
+
+UPDATE body_parts
+SET parent_id = (SELECT id FROM body_parts WHERE name = 'Lower Body')
+WHERE name = 'Legs';
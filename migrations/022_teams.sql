@@ -0,0 +1,20 @@
+-- migrations/022_teams.sql
+
+-- Teams group users, such as a coach and their athletes. Exercises with team
+-- visibility are seen by everyone who shares a team with their owner. There
+-- is no endpoint to manage teams yet; set them up in the database.
+CREATE TABLE teams (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE team_members (
+    team_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (team_id, user_id),
+    FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_team_members_user ON team_members(user_id);
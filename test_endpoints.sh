@@ -44,6 +44,7 @@ make_request "POST" "/body-parts" \
 echo -e "\n${GREEN}Testing READ operations${NC}"
 make_request "GET" "/body-parts" "" "Get all body parts"
 make_request "GET" "/body-parts/1" "" "Get specific body part"
+make_request "GET" "/openapi.json" "" "Get the OpenAPI document"
 
 # Test UPDATE
 echo -e "\n${GREEN}Testing UPDATE operations${NC}"